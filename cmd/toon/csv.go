@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+func runCSV(c *cli, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "import":
+			return runCSVImport(c, args[1:])
+		case "export":
+			return runCSVExport(c, args[1:])
+		}
+	}
+	fs := c.flags("csv", "import|export [flags] [file]")
+	if len(args) == 0 {
+		return c.usageError(fs, "missing subcommand")
+	}
+	return c.usageError(fs, "unknown subcommand %q", args[0])
+}
+
+func runCSVImport(c *cli, args []string) int {
+	fs := c.flags("csv import", "[-name key] [-comma c] [-strings] [-delimiter d] [-o file] [file.csv]")
+	name := fs.String("name", "rows", "`key` to write the array under; empty writes a root array")
+	comma := fs.String("comma", ",", "field separator of the CSV input: one character or \"tab\"")
+	asStrings := fs.Bool("strings", false, "keep every cell a string instead of inferring column types")
+	delimiter := fs.String("delimiter", ",", "delimiter of the tabular array: \",\", \"tab\" or \"|\"")
+	output := fs.String("o", "", "write to `file` instead of standard output")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+
+	opts := toon.CSVOptions{Name: *name, Strings: *asStrings}
+	if *comma == "tab" {
+		*comma = "\t"
+	}
+	r, size := utf8.DecodeRuneInString(*comma)
+	if size == 0 || size != len(*comma) || r == utf8.RuneError {
+		return c.usageError(fs, "comma must be a single character, got %q", *comma)
+	}
+	opts.Comma = r
+	var err error
+	if opts.Delimiter, err = parseDelimiter(*delimiter); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+
+	file, data, err := c.readInput(fs.Args())
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	out, err := toon.FromCSV(bytes.NewReader(data), opts)
+	if err != nil {
+		return c.fail(inputError(file, err))
+	}
+	if err := c.writeOutput(*output, out); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+func runCSVExport(c *cli, args []string) int {
+	fs := c.flags("csv export", "[-path p] [-o file] [file.toon]")
+	path := fs.String("path", "rows", "dotted `path` of the tabular array, such as data.rows; empty selects a root array")
+	output := fs.String("o", "", "write to `file` instead of standard output")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+
+	file, data, err := c.readInput(fs.Args())
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	var out bytes.Buffer
+	if err := toon.ToCSV(&out, data, *path); err != nil {
+		return c.fail(inputError(file, err))
+	}
+	if err := c.writeOutput(*output, out.Bytes()); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+func runDecode(c *cli, args []string) int {
+	fs := c.flags("decode", "[-indent n] [-strict=false] [-expand] [-o file] [file.toon]")
+	indent := fs.Int("indent", 2, "spaces per JSON indentation level; 0 writes compact JSON")
+	strict := fs.Bool("strict", true, "reject malformed input; with -strict=false, repair it and report the fixes")
+	expand := fs.Bool("expand", false, "expand dotted keys written with key folding into nested objects")
+	output := fs.String("o", "", "write to `file` instead of standard output")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+	if *indent < 0 {
+		return c.usageError(fs, "indent must not be negative, got %d", *indent)
+	}
+
+	name, data, err := c.readInput(fs.Args())
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if !*strict {
+		repaired, fixes, err := decoder.Repair(data)
+		for _, fix := range fixes {
+			fmt.Fprintf(c.stderr, "toon: %s:%d: fixed %s: %s\n", name, fix.Line, fix.Kind, fix.Detail)
+		}
+		if err != nil {
+			return c.fail(inputError(name, err))
+		}
+		data = repaired
+	}
+
+	// Converting the text keeps the order of keys and the digits of numbers.
+	out, err := toon.ToJSONWithOptions(data, decoder.Options{ExpandPaths: *expand})
+	if err != nil {
+		return c.fail(inputError(name, err))
+	}
+	if *indent > 0 {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, out, "", strings.Repeat(" ", *indent)); err != nil {
+			return c.fail(err)
+		}
+		out = buf.Bytes()
+	}
+	if err := c.writeOutput(*output, append(out, '\n')); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
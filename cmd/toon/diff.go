@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+// ANSI escapes used by diff -color.
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// Exit statuses of diff, which follows diff(1).
+const (
+	exitDiffer  = 1
+	exitTrouble = 2
+)
+
+func runDiff(c *cli, args []string) int {
+	fs := c.flags("diff", "[-key field] [-color auto|always|never] old new")
+	key := fs.String("key", "", "match the elements of arrays of objects by `field`, such as id, instead of by position")
+	color := fs.String("color", "auto", "color the output: auto (when writing to a terminal), always or never")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+	if fs.NArg() != 2 {
+		return c.usageError(fs, "expected two files, got %d", fs.NArg())
+	}
+	var colored bool
+	switch *color {
+	case "auto":
+		colored = isTerminal(c.stdout) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		colored = true
+	case "never":
+	default:
+		return c.usageError(fs, "unknown color mode %q", *color)
+	}
+
+	var values [2]interface{}
+	for i, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			c.fail(err)
+			return exitTrouble
+		}
+		if values[i], err = readAny(data); err != nil {
+			c.fail(inputError(name, err))
+			return exitTrouble
+		}
+	}
+
+	changes := toon.DiffValues(values[0], values[1], toon.DiffOptions{Key: *key})
+	if len(changes) == 0 {
+		return exitOK
+	}
+	p := &diffPrinter{w: c.stdout, color: colored}
+	p.line(colorBold, "--- "+fs.Arg(0))
+	p.line(colorBold, "+++ "+fs.Arg(1))
+	for _, change := range changes {
+		if err := p.change(change); err != nil {
+			c.fail(err)
+			return exitTrouble
+		}
+	}
+	return exitDiffer
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// diffPrinter writes changes in the style of a unified diff: a hunk header
+// naming the path, then the old value on lines starting with "-" and the
+// new value on lines starting with "+", both written as TOON.
+type diffPrinter struct {
+	w     io.Writer
+	color bool
+}
+
+func (p *diffPrinter) line(color, text string) {
+	if p.color {
+		fmt.Fprintf(p.w, "%s%s%s\n", color, text, colorReset)
+		return
+	}
+	fmt.Fprintln(p.w, text)
+}
+
+func (p *diffPrinter) change(change toon.Change) error {
+	p.line(colorCyan, fmt.Sprintf("@@ %s (%s) @@", change.Path, change.Kind))
+	if change.Kind != toon.Added {
+		if err := p.value(colorRed, "-", change.Old); err != nil {
+			return err
+		}
+	}
+	if change.Kind != toon.Removed {
+		return p.value(colorGreen, "+", change.New)
+	}
+	return nil
+}
+
+func (p *diffPrinter) value(color, prefix string, v interface{}) error {
+	out, err := encoder.Append(nil, v, nil)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	if text == "" {
+		// Empty objects encode to nothing.
+		text = "{}"
+	}
+	for _, line := range strings.Split(text, "\n") {
+		p.line(color, prefix+line)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+func runEncode(c *cli, args []string) int {
+	fs := c.flags("encode", "[-indent n] [-delimiter d] [-fold] [-o file] [file.json]")
+	indent := fs.Int("indent", 2, "spaces per indentation level")
+	delimiter := fs.String("delimiter", ",", "delimiter of inline and tabular arrays: \",\", \"tab\" or \"|\"")
+	fold := fs.Bool("fold", false, "fold chains of single-key objects into dotted keys")
+	output := fs.String("o", "", "write to `file` instead of standard output")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+
+	opts := encoder.DefaultOptions()
+	var err error
+	if opts.Indent, err = indentString(*indent); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if opts.Delimiter, err = parseDelimiter(*delimiter); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	opts.KeyFolding = *fold
+
+	name, data, err := c.readInput(fs.Args())
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+
+	out, err := toon.FromJSONWithOptions(data, &opts)
+	if err != nil {
+		return c.fail(inputError(name, err))
+	}
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	if err := c.writeOutput(*output, out); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// parseDelimiter accepts a delimiter by character or by name.
+func parseDelimiter(s string) (byte, error) {
+	switch s {
+	case ",", "comma":
+		return encoder.Comma, nil
+	case "\t", "tab":
+		return encoder.Tab, nil
+	case "|", "pipe":
+		return encoder.Pipe, nil
+	}
+	return 0, fmt.Errorf("unknown delimiter %q", s)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+func runFormat(c *cli, args []string) int {
+	flags := c.flags("fmt", "[-w] [-l] [-check] [-indent n] [-sort] [path ...]")
+	write := flags.Bool("w", false, "write the result back to each file instead of to standard output")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	check := flags.Bool("check", false, "report files whose formatting differs and exit with status 1, writing nothing")
+	indent := flags.Int("indent", 2, "spaces per indentation level")
+	sortKeys := flags.Bool("sort", false, "sort the keys of every object")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+
+	opts := decoder.FormatOptions{SortKeys: *sortKeys}
+	var err error
+	if opts.Indent, err = indentString(*indent); err != nil {
+		return c.usageError(flags, "%v", err)
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			return c.usageError(flags, "cannot use -w with standard input")
+		}
+		data, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.formatFile(stdinName, data, opts, *list, *check, false)
+	}
+
+	status := exitOK
+	for _, path := range flags.Args() {
+		err := walkTOON(path, func(name string, data []byte) {
+			if s := c.formatFile(name, data, opts, *list, *check, *write); s != exitOK {
+				status = s
+			}
+		})
+		if err != nil {
+			status = c.fail(err)
+		}
+	}
+	return status
+}
+
+// formatFile formats the contents of one file and reports, writes or
+// prints the result as the flags ask.
+func (c *cli) formatFile(name string, data []byte, opts decoder.FormatOptions, list, check, write bool) int {
+	out, err := decoder.Format(data, opts)
+	if err != nil {
+		return c.fail(inputError(name, err))
+	}
+	changed := !bytes.Equal(data, out)
+
+	switch {
+	case check:
+		if changed {
+			fmt.Fprintf(c.stderr, "toon: %s is not formatted\n", name)
+			return exitError
+		}
+	case list || write:
+		if changed && list {
+			fmt.Fprintln(c.stdout, name)
+		}
+		if changed && write {
+			info, err := os.Stat(name)
+			if err != nil {
+				return c.fail(err)
+			}
+			if err := os.WriteFile(name, out, info.Mode().Perm()); err != nil {
+				return c.fail(err)
+			}
+		}
+	default:
+		if _, err := c.stdout.Write(out); err != nil {
+			return c.fail(err)
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+func runJSONL(c *cli, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "import":
+			return runJSONLImport(c, args[1:])
+		case "export":
+			return runJSONLExport(c, args[1:])
+		}
+	}
+	fs := c.flags("jsonl", "import|export [flags] [file]")
+	if len(args) == 0 {
+		return c.usageError(fs, "missing subcommand")
+	}
+	return c.usageError(fs, "unknown subcommand %q", args[0])
+}
+
+func runJSONLImport(c *cli, args []string) int {
+	fs := c.flags("jsonl import", "[-name key] [-threshold f] [-delimiter d] [-o file] [file.jsonl]")
+	name := fs.String("name", "rows", "`key` to write the array under; empty writes a root array")
+	threshold := fs.Float64("threshold", 0, "write records with differing keys as a table when at least this fraction of the cells hold a value")
+	delimiter := fs.String("delimiter", ",", "delimiter of tabular and inline arrays: \",\", \"tab\" or \"|\"")
+	output := fs.String("o", "", "write to `file` instead of standard output")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+	if *threshold < 0 || *threshold > 1 {
+		return c.usageError(fs, "threshold must be between 0 and 1, got %g", *threshold)
+	}
+	opts := toon.JSONLOptions{Name: *name, TabularThreshold: *threshold}
+	var err error
+	if opts.Delimiter, err = parseDelimiter(*delimiter); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if fs.NArg() > 1 {
+		return c.usageError(fs, "%v", errTooManyFiles)
+	}
+
+	// The input is streamed rather than read whole: a file is read twice,
+	// and standard input is spooled to disk by FromJSONL.
+	file, in := stdinName, c.stdin
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return c.fail(err)
+		}
+		defer f.Close()
+		file, in = f.Name(), f
+	}
+	out, closeOut, err := c.createOutput(*output)
+	if err != nil {
+		return c.fail(err)
+	}
+	err = toon.FromJSONL(out, in, opts)
+	if cerr := closeOut(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return c.fail(inputError(file, err))
+	}
+	return exitOK
+}
+
+func runJSONLExport(c *cli, args []string) int {
+	fs := c.flags("jsonl export", "[-path p] [-o file] [file.toon]")
+	path := fs.String("path", "rows", "dotted `path` of the array, such as data.rows; empty selects a root array")
+	output := fs.String("o", "", "write to `file` instead of standard output")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+
+	file, data, err := c.readInput(fs.Args())
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	var out bytes.Buffer
+	if err := toon.ToJSONL(&out, data, *path); err != nil {
+		return c.fail(inputError(file, err))
+	}
+	if err := c.writeOutput(*output, out.Bytes()); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// createOutput opens the file named by output for writing, or returns
+// standard output when it is empty. The returned function closes the file.
+func (c *cli) createOutput(output string) (io.Writer, func() error, error) {
+	if output == "" {
+		return c.stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
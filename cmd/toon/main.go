@@ -1,73 +1,219 @@
+// Command toon converts between JSON and TOON.
+//
+// Usage:
+//
+//	toon <command> [flags] [file]
+//
+// The commands are:
+//
+//	encode    convert JSON to TOON
+//	decode    convert TOON to JSON
+//	fmt       rewrite TOON files in canonical form
+//	validate  report every error in TOON files
+//	stats     compare the size and token count of encodings
+//	query     select values by path
+//	diff      compare two documents structurally
+//	csv       convert between CSV and tabular arrays
+//	jsonl     convert between JSON Lines and arrays
+//
+// Commands read the named file, or standard input when the file is omitted
+// or "-", and write to standard output unless -o is given. fmt and validate
+// take any number of files and directories, which are searched for .toon
+// files. csv and jsonl take a subcommand: "toon csv import" converts CSV
+// to a tabular array and "toon csv export" converts one back, and likewise
+// for JSON Lines. Run "toon <command> -h" for the flags of a command.
+//
+// The exit status is 0 on success, 1 when the input is invalid or cannot be
+// read or written, and 2 for usage errors. diff follows diff(1) instead: 1
+// when the documents differ and 2 when they cannot be compared. Errors in
+// the input are reported as file:line:column: message.
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/devalexandre/toon-go/pkg/toon"
+	"github.com/devalexandre/toon-go/pkg/decoder"
 )
 
+// Exit statuses.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// stdinName names standard input in error messages.
+const stdinName = "<stdin>"
+
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) int
+}
+
+var commands = []command{
+	{"encode", "convert JSON to TOON", runEncode},
+	{"decode", "convert TOON to JSON", runDecode},
+	{"fmt", "rewrite TOON files in canonical form", runFormat},
+	{"validate", "report every error in TOON files", runValidate},
+	{"stats", "compare the size and token count of encodings", runStats},
+	{"query", "select values by path", runQuery},
+	{"diff", "compare two documents structurally", runDiff},
+	{"csv", "convert between CSV and tabular arrays", runCSV},
+	{"jsonl", "convert between JSON Lines and arrays", runJSONL},
+}
+
 func main() {
-	fmt.Println("=== TOON Format Example ===")
-	fmt.Println()
-
-	fmt.Println("1. Simple key-value pairs:")
-	simpleData := map[string]interface{}{
-		"name":    "John Doe",
-		"age":     30,
-		"active":  true,
-		"balance": 1234.56,
-		"tags":    []string{"developer", "golang", "toon"},
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		c.usage()
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(c, args[1:])
+		}
+	}
+	fmt.Fprintf(stderr, "toon: unknown command %q\n", args[0])
+	c.usage()
+	return exitUsage
+}
+
+// cli holds the streams of one invocation.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (c *cli) usage() {
+	fmt.Fprintf(c.stderr, "Usage: toon <command> [flags] [file]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-8s  %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(c.stderr, "\nRun \"toon <command> -h\" for the flags of a command.\n")
+}
+
+// flags returns the flag set of a command whose usage line is usage.
+func (c *cli) flags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: toon %s %s\n", name, usage)
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	toonData1, err := toon.Marshal(simpleData)
-	if err != nil {
-		log.Fatal(err)
+// parse parses the flags of a command, returning the exit status to stop
+// with when ok is false.
+func (c *cli) parse(fs *flag.FlagSet, args []string) (status int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
 	}
-	fmt.Println(string(toonData1))
-	fmt.Println()
-
-	fmt.Println("2. Nested objects:")
-	nestedData := map[string]interface{}{
-		"user": map[string]interface{}{
-			"id":    123,
-			"login": "johndoe",
-			"profile": map[string]interface{}{
-				"firstName": "John",
-				"lastName":  "Doe",
-				"settings": map[string]interface{}{
-					"theme":    "dark",
-					"language": "en",
-				},
-			},
-		},
-		"timestamp": "2024-01-15T10:30:00Z",
+	return exitOK, true
+}
+
+// usageError reports a misuse of a command and returns exitUsage.
+func (c *cli) usageError(fs *flag.FlagSet, format string, args ...interface{}) int {
+	fmt.Fprintf(c.stderr, "toon %s: %s\n", fs.Name(), fmt.Sprintf(format, args...))
+	fs.Usage()
+	return exitUsage
+}
+
+// fail reports err and returns exitError.
+func (c *cli) fail(err error) int {
+	fmt.Fprintf(c.stderr, "toon: %v\n", err)
+	return exitError
+}
+
+// readInput reads the single file argument of a command, or standard input
+// when there is none or it is "-". It returns the name to report errors
+// under.
+func (c *cli) readInput(args []string) (string, []byte, error) {
+	switch len(args) {
+	case 0:
+	case 1:
+		if name := args[0]; name != "-" {
+			data, err := os.ReadFile(name)
+			return name, data, err
+		}
+	default:
+		return "", nil, errTooManyFiles
 	}
+	data, err := io.ReadAll(c.stdin)
+	return stdinName, data, err
+}
+
+var errTooManyFiles = errors.New("too many files")
 
-	toonData2, err := toon.Marshal(nestedData)
-	if err != nil {
-		log.Fatal(err)
+// writeOutput writes data to the file named by output, or to standard
+// output when it is empty.
+func (c *cli) writeOutput(output string, data []byte) error {
+	if output == "" {
+		_, err := c.stdout.Write(data)
+		return err
 	}
-	fmt.Println(string(toonData2))
-	fmt.Println()
-
-	fmt.Println("3. Parsing TOON data:")
-	toonString := `name: "John Doe"
-age: 30
-active: true
-address:
-  street: "123 Main St"
-  city: "New York"`
-
-	fmt.Println("Parsing TOON string:")
-	fmt.Println(toonString)
-	fmt.Println()
-
-	var parsedData map[string]interface{}
-	err = toon.Unmarshal([]byte(toonString), &parsedData)
-	if err != nil {
-		log.Fatal(err)
+	return os.WriteFile(output, data, 0o644)
+}
+
+// walkTOON calls fn with the contents of path, or of every .toon file
+// below path when it is a directory. Files named on the command line are
+// read whatever their extension.
+func walkTOON(path string, fn func(name string, data []byte)) error {
+	return filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || name != path && filepath.Ext(name) != ".toon" {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		fn(name, data)
+		return nil
+	})
+}
+
+// inputError formats an error found in the input name, prefixing the line
+// and column of syntax errors in the style of compilers.
+func inputError(name string, err error) error {
+	var serr *decoder.SyntaxError
+	if errors.As(err, &serr) {
+		if serr.Column > 0 {
+			return fmt.Errorf("%s:%d:%d: %s", name, serr.Line, serr.Column, serr.Msg)
+		}
+		return fmt.Errorf("%s:%d: %s", name, serr.Line, serr.Msg)
 	}
+	// Library errors carry their own "toon: " prefix, which fail adds.
+	return fmt.Errorf("%s: %s", name, strings.TrimPrefix(err.Error(), "toon: "))
+}
 
-	fmt.Printf("Parsed data: %+v\n", parsedData)
+// indentString returns n spaces, rejecting widths TOON cannot use.
+func indentString(n int) (string, error) {
+	if n < 1 || n > 8 {
+		return "", fmt.Errorf("indent must be between 1 and 8, got %d", n)
+	}
+	return strings.Repeat(" ", n), nil
 }
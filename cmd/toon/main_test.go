@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runCLI runs the command line args with stdin as standard input.
+func runCLI(t *testing.T, stdin string, args ...string) (status int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	status = run(args, strings.NewReader(stdin), &out, &errOut)
+	return status, out.String(), errOut.String()
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{
+			name:  "key order and tabular arrays",
+			args:  []string{"encode"},
+			input: `{"name":"Ada","id":7,"roles":[{"id":1,"label":"admin"},{"id":2,"label":"dev"}]}`,
+			want: `name: "Ada"
+id: 7
+roles[2]{id,label}:
+  1,"admin"
+  2,"dev"
+`,
+		},
+		{
+			name:  "flags",
+			args:  []string{"encode", "-indent", "4", "-delimiter", "tab", "-fold"},
+			input: `{"a":{"b":{"c":[1,2]}},"rows":[{"x":1.5},{"x":2}]}`,
+			want:  "a.b.c[2\t]: 1\t2\nrows[2\t]{x}:\n    1.5\n    2\n",
+		},
+		{
+			name:  "numbers keep their text",
+			args:  []string{"encode"},
+			input: `{"id":12345678901234567890,"price":1.50}`,
+			want:  "id: 12345678901234567890\nprice: 1.50\n",
+		},
+		{
+			name:  "root primitive",
+			args:  []string{"encode"},
+			input: `"hi"`,
+			want:  "\"hi\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, stdout, stderr := runCLI(t, tt.input, tt.args...)
+			if status != exitOK {
+				t.Fatalf("status %d, stderr: %s", status, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, stdout)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	input := "user:\n  name: \"Ada\"\n  tags[2]: \"a\",\"b\"\n"
+
+	status, stdout, stderr := runCLI(t, input, "decode", "-indent", "0")
+	if status != exitOK {
+		t.Fatalf("status %d, stderr: %s", status, stderr)
+	}
+	if want := `{"user":{"name":"Ada","tags":["a","b"]}}` + "\n"; stdout != want {
+		t.Errorf("Expected %q, got %q", want, stdout)
+	}
+
+	_, stdout, _ = runCLI(t, input, "decode")
+	if !strings.Contains(stdout, "\n  \"user\": {\n    \"name\": \"Ada\",") {
+		t.Errorf("Expected indented JSON, got:\n%s", stdout)
+	}
+
+	status, stdout, stderr = runCLI(t, "a.b: 1\n", "decode", "-expand", "-indent", "0")
+	if status != exitOK || stdout != `{"a":{"b":1}}`+"\n" {
+		t.Errorf("Expected expanded paths, got status %d, %q, %s", status, stdout, stderr)
+	}
+
+	status, stdout, stderr = runCLI(t, "z: 1\nbig: 12345678901234567890123\na: 1.50\n", "decode", "-indent", "0")
+	if want := `{"z":1,"big":12345678901234567890123,"a":1.50}` + "\n"; status != exitOK || stdout != want {
+		t.Errorf("Expected key order and number text to be kept, got status %d, %q, %s", status, stdout, stderr)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	input := "id: 1\ntags[3]: \"a\",\"b\"\n"
+
+	status, _, stderr := runCLI(t, input, "decode")
+	if status != exitError {
+		t.Errorf("Expected status %d, got %d", exitError, status)
+	}
+	if want := "toon: <stdin>:2:1: array count mismatch: declared 3, found 2\n"; stderr != want {
+		t.Errorf("Expected %q, got %q", want, stderr)
+	}
+
+	status, stdout, stderr := runCLI(t, input, "decode", "-strict=false", "-indent", "0")
+	if status != exitOK || stdout != `{"id":1,"tags":["a","b"]}`+"\n" {
+		t.Errorf("Expected repaired output, got status %d, %q", status, stdout)
+	}
+	if !strings.Contains(stderr, "<stdin>:2: fixed count") {
+		t.Errorf("Expected the fix to be reported, got %q", stderr)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		status int
+		want   string
+	}{
+		{"syntax", "{\"a\": 1,\n  \"b\": x}", exitError, "toon: <stdin>:2:8: invalid character 'x' looking for beginning of value\n"},
+		{"truncated", `{"a": [1`, exitError, "toon: <stdin>:1:9: unexpected end of JSON input\n"},
+		{"trailing data", "{}\n{}", exitError, "toon: <stdin>:2:1: invalid character after top-level value\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, stderr := runCLI(t, tt.input, "encode")
+			if status != tt.status || stderr != tt.want {
+				t.Errorf("Expected status %d and %q, got %d and %q", tt.status, tt.want, status, stderr)
+			}
+		})
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"frobnicate"},
+		{"encode", "-delimiter", ";"},
+		{"encode", "-indent", "0"},
+		{"decode", "-bogus"},
+		{"decode", "a.toon", "b.toon"},
+	}
+	for _, args := range tests {
+		if status, _, _ := runCLI(t, "", args...); status != exitUsage {
+			t.Errorf("%q: expected status %d, got %d", args, exitUsage, status)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.json")
+	out := filepath.Join(dir, "out.toon")
+	if err := os.WriteFile(in, []byte(`{"a": [1, 2]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _, stderr := runCLI(t, "", "encode", "-o", out, in); status != exitOK {
+		t.Fatalf("status %d, stderr: %s", status, stderr)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a[2]: 1,2\n" {
+		t.Errorf("Expected the encoded file, got %q", got)
+	}
+
+	status, _, stderr := runCLI(t, "", "decode", filepath.Join(dir, "missing.toon"))
+	if status != exitError || !strings.Contains(stderr, "missing.toon") {
+		t.Errorf("Expected a read error, got status %d, %q", status, stderr)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	status, stdout, stderr := runCLI(t, "# config\nname:   Ada\ntags[5]: a,b\n", "fmt")
+	if status != exitOK {
+		t.Fatalf("status %d, stderr: %s", status, stderr)
+	}
+	if want := "# config\nname: \"Ada\"\ntags[2]: \"a\",\"b\"\n"; stdout != want {
+		t.Errorf("Expected %q, got %q", want, stdout)
+	}
+
+	status, _, stderr = runCLI(t, "tags[5]: a,b\n", "fmt", "-check")
+	if status != exitError || stderr != "toon: <stdin> is not formatted\n" {
+		t.Errorf("Expected -check to report a wrong count, got status %d, %q", status, stderr)
+	}
+
+	status, _, stderr = runCLI(t, "a: 1\n    b: 2\n", "fmt")
+	if status != exitError || stderr != "toon: <stdin>:2:5: unexpected indentation\n" {
+		t.Errorf("Expected a positioned error, got status %d, %q", status, stderr)
+	}
+}
+
+func TestFormatFiles(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.toon")
+	messy := filepath.Join(dir, "sub", "messy.toon")
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.MkdirAll(filepath.Dir(messy), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		clean: "a: 1\n",
+		messy: "b:    2\n",
+		other: "not:   toon\n",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, stdout, _ := runCLI(t, "", "fmt", "-l", dir)
+	if status != exitOK || stdout != messy+"\n" {
+		t.Errorf("Expected -l to list %s, got status %d, %q", messy, status, stdout)
+	}
+
+	status, stdout, stderr := runCLI(t, "", "fmt", "-check", dir)
+	if status != exitError || stdout != "" || !strings.Contains(stderr, messy+" is not formatted") {
+		t.Errorf("Expected -check to fail on %s, got status %d, %q", messy, status, stderr)
+	}
+
+	if status, _, stderr := runCLI(t, "", "fmt", "-w", dir); status != exitOK {
+		t.Fatalf("status %d, stderr: %s", status, stderr)
+	}
+	got, err := os.ReadFile(messy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "b: 2\n" {
+		t.Errorf("Expected -w to rewrite the file, got %q", got)
+	}
+	if got, _ := os.ReadFile(other); string(got) != "not:   toon\n" {
+		t.Errorf("Expected files without the .toon extension to be skipped, got %q", got)
+	}
+
+	if status, _, _ := runCLI(t, "", "fmt", "-check", dir); status != exitOK {
+		t.Errorf("Expected -check to pass after -w, got status %d", status)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	input := "a: 1\na: 2\nrows[3]{id,name}:\n  1,\"Ada\"\n  2\n"
+
+	status, stdout, _ := runCLI(t, input, "validate")
+	if status != exitError {
+		t.Errorf("Expected status %d, got %d", exitError, status)
+	}
+	want := `<stdin>:2:1: duplicate key "a"
+  2 | a: 2
+    | ^
+<stdin>:3:1: array count mismatch: declared 3, found 2
+  3 | rows[3]{id,name}:
+    | ^
+<stdin>:5:3: row has 1 fields, header has 2
+  5 |   2
+    |   ^
+`
+	if stdout != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	if status, stdout, _ := runCLI(t, "a: 1\n", "validate"); status != exitOK || stdout != "" {
+		t.Errorf("Expected a valid document to pass silently, got status %d, %q", status, stdout)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.json")
+	good := filepath.Join(dir, "good.toon")
+	bad := filepath.Join(dir, "sub", "bad.toon")
+	if err := os.MkdirAll(filepath.Dir(bad), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		schema: `{"properties": {"users": {"items": {"properties": {"id": {"type": "integer"}}}}}}`,
+		good:   "users[1]{id}:\n  1\n",
+		bad:    "users[2]{id}:\n  1\n  \"two\"\n",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, stdout, stderr := runCLI(t, "", "validate", "-schema", schema, "-format", "json", dir)
+	if status != exitError {
+		t.Fatalf("Expected status %d, got %d: %s", exitError, status, stderr)
+	}
+	var problems []problem
+	if err := json.Unmarshal([]byte(stdout), &problems); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", stdout, err)
+	}
+	want := []problem{{File: bad, Line: 3, Column: 3, Path: "users[1].id", Message: "expected integer, got string"}}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("Expected %+v, got %+v", want, problems)
+	}
+
+	if status, stdout, _ := runCLI(t, "", "validate", "-schema", schema, "-format", "json", good); status != exitOK || stdout != "[]\n" {
+		t.Errorf("Expected an empty array, got status %d, %q", status, stdout)
+	}
+	if status, _, _ := runCLI(t, "", "validate", "-format", "xml"); status != exitUsage {
+		t.Errorf("Expected status %d for an unknown format, got %d", exitUsage, status)
+	}
+}
+
+func TestStats(t *testing.T) {
+	input := `{"users":[{"id":1,"name":"Ada"},{"id":2,"name":"Linus"}],"meta":{"page":1,"next":null}}`
+
+	status, stdout, stderr := runCLI(t, input, "stats", "-depth", "2", "-keys", "2")
+	if status != exitOK {
+		t.Fatalf("status %d, stderr: %s", status, stderr)
+	}
+	lines := strings.Split(stdout, "\n")
+	if fields := strings.Fields(lines[0]); !reflect.DeepEqual(fields, []string{"format", "bytes", "lines", "toon_base", "vs", "json"}) {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	for i, name := range []string{"json", "json-indent", "toon", "toon-tab", "toon-pipe", "toon-fold", "toon-auto"} {
+		if fields := strings.Fields(lines[i+1]); len(fields) == 0 || fields[0] != name {
+			t.Errorf("Expected row %d to be %s, got %q", i+1, name, lines[i+1])
+		}
+	}
+	if fields := strings.Fields(lines[1]); fields[1] != fmt.Sprint(len(input)) || fields[2] != "1" {
+		t.Errorf("Expected the size of compact JSON, got %q", lines[1])
+	}
+	if !strings.Contains(stdout, "tokens by key (toon_base):") || !strings.Contains(stdout, "(1 more)") {
+		t.Errorf("Expected a key breakdown limited to 2 keys, got:\n%s", stdout)
+	}
+	for _, key := range []string{"users", "meta.next"} {
+		if !strings.Contains(stdout, " "+key+" ") {
+			t.Errorf("Expected the breakdown to list %s, got:\n%s", key, stdout)
+		}
+	}
+
+	// TOON input measures the same value.
+	toon := "meta:\n  next: null\n  page: 1\nusers[2]{id,name}:\n  1,\"Ada\"\n  2,\"Linus\"\n"
+	_, fromTOON, _ := runCLI(t, toon, "stats", "-depth", "2", "-keys", "2")
+	if fromTOON != stdout {
+		t.Errorf("Expected TOON input to give the same stats, got:\n%s", fromTOON)
+	}
+
+	if status, _, stderr := runCLI(t, input, "stats", "-tokenizer", "missing.tiktoken"); status != exitError || !strings.Contains(stderr, "tokenizer missing.tiktoken") {
+		t.Errorf("Expected a tokenizer error, got status %d, %q", status, stderr)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	input := "users[3]{id,name,active}:\n  1,\"Ada\",true\n  2,\"Linus\",false\n  3,\"Grace\",true\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"query", "users[0].name"}, "\"Ada\"\n"},
+		{[]string{"query", "users[?active].id"}, "[2]: 1,3\n"},
+		{[]string{"query", "users[5:]"}, "[0]:\n"},
+		{[]string{"query", "-format", "json", "users[-1]"}, "{\n  \"id\": 3,\n  \"name\": \"Grace\",\n  \"active\": true\n}\n"},
+	}
+	for _, tt := range tests {
+		status, stdout, stderr := runCLI(t, input, tt.args...)
+		if status != exitOK || stdout != tt.want {
+			t.Errorf("%q: expected %q, got status %d, %q, %s", tt.args, tt.want, status, stdout, stderr)
+		}
+	}
+
+	// JSON input keeps its key order.
+	status, stdout, _ := runCLI(t, `{"rows":[{"z":1,"a":2},{"z":3,"a":4}]}`, "query", "rows")
+	if want := "[2]{z,a}:\n  1,2\n  3,4\n"; status != exitOK || stdout != want {
+		t.Errorf("Expected %q, got status %d, %q", want, status, stdout)
+	}
+
+	status, _, stderr := runCLI(t, input, "query", "users[9].name")
+	if status != exitError || stderr != "toon: <stdin>: no value at users[9].name\n" {
+		t.Errorf("Expected a missing value error, got status %d, %q", status, stderr)
+	}
+	if status, _, _ := runCLI(t, input, "query", "users[?"); status != exitUsage {
+		t.Errorf("Expected status %d for an invalid path, got %d", exitUsage, status)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.toon")
+	new := filepath.Join(dir, "new.json")
+	for name, content := range map[string]string{
+		old: "users[2]{id,name}:\n  1,\"Ada\"\n  2,\"Linus\"\n",
+		new: `{"users": [{"id": 2, "name": "Linus"}, {"id": 1, "name": "Ada L."}]}`,
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, stdout, stderr := runCLI(t, "", "diff", "-key", "id", old, new)
+	if status != exitDiffer {
+		t.Fatalf("Expected status %d, got %d: %s", exitDiffer, status, stderr)
+	}
+	want := "--- " + old + "\n+++ " + new + "\n@@ users[id=1].name (changed) @@\n-\"Ada\"\n+\"Ada L.\"\n"
+	if stdout != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	_, stdout, _ = runCLI(t, "", "diff", "-color", "always", "-key", "id", old, new)
+	if !strings.Contains(stdout, colorRed+"-\"Ada\""+colorReset+"\n") || !strings.Contains(stdout, colorGreen+"+\"Ada L.\""+colorReset+"\n") {
+		t.Errorf("Expected colored lines, got %q", stdout)
+	}
+
+	if status, stdout, _ := runCLI(t, "", "diff", old, old); status != exitOK || stdout != "" {
+		t.Errorf("Expected equal documents to produce no output, got status %d, %q", status, stdout)
+	}
+	if status, _, _ := runCLI(t, "", "diff", old, filepath.Join(dir, "missing.toon")); status != exitTrouble {
+		t.Errorf("Expected status %d for a missing file, got %d", exitTrouble, status)
+	}
+	if status, _, _ := runCLI(t, "", "diff", old); status != exitUsage {
+		t.Errorf("Expected status %d for one file, got %d", exitUsage, status)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	input := "id;name;zip\n1;Ada;02139\n2;Linus;\n"
+	status, stdout, stderr := runCLI(t, input, "csv", "import", "-name", "users", "-comma", ";")
+	if status != exitOK {
+		t.Fatalf("Expected status %d, got %d: %s", exitOK, status, stderr)
+	}
+	want := "users[2]{id,name,zip}:\n  1,\"Ada\",\"02139\"\n  2,\"Linus\",\"\"\n"
+	if stdout != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	nested := "meta:\n  page: 1\ndata:\n  users[2]{id,name,zip}:\n    1,\"Ada\",\"02139\"\n    2,\"Linus\",\"\"\n"
+	status, stdout, stderr = runCLI(t, nested, "csv", "export", "-path", "data.users")
+	if status != exitOK {
+		t.Fatalf("Expected status %d, got %d: %s", exitOK, status, stderr)
+	}
+	if want := "id,name,zip\n1,Ada,02139\n2,Linus,\n"; stdout != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	_, stdout, _ = runCLI(t, "x\n1\n", "csv", "import")
+	status, stdout, stderr = runCLI(t, stdout, "csv", "export")
+	if status != exitOK || stdout != "x\n1\n" {
+		t.Errorf("Expected import and export to agree on the default path, got status %d, %q: %s", status, stdout, stderr)
+	}
+
+	if status, _, stderr := runCLI(t, "a,a\n", "csv", "import"); status != exitError || !strings.Contains(stderr, `duplicate column "a"`) {
+		t.Errorf("Expected a duplicate column error, got status %d: %s", status, stderr)
+	}
+	for _, args := range [][]string{
+		{"csv"},
+		{"csv", "convert"},
+		{"csv", "import", "-comma", ";;"},
+		{"csv", "import", "-delimiter", "x"},
+	} {
+		if status, _, _ := runCLI(t, "", args...); status != exitUsage {
+			t.Errorf("%q: expected status %d, got %d", args, exitUsage, status)
+		}
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	input := "{\"id\": 1, \"msg\": \"start\"}\n{\"id\": 2, \"msg\": \"stop\"}\n"
+	status, stdout, stderr := runCLI(t, input, "jsonl", "import", "-name", "events", "-delimiter", "tab")
+	if status != exitOK {
+		t.Fatalf("Expected status %d, got %d: %s", exitOK, status, stderr)
+	}
+	want := "events[2\t]{id\tmsg}:\n  1\t\"start\"\n  2\t\"stop\"\n"
+	if stdout != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "events.jsonl")
+	out := filepath.Join(dir, "events.toon")
+	if err := os.WriteFile(in, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, stderr := runCLI(t, "", "jsonl", "import", "-o", out, in); status != exitOK {
+		t.Fatalf("Expected status %d, got %d: %s", exitOK, status, stderr)
+	}
+	// Both commands default to the rows key.
+	status, stdout, stderr = runCLI(t, "", "jsonl", "export", out)
+	if status != exitOK {
+		t.Fatalf("Expected status %d, got %d: %s", exitOK, status, stderr)
+	}
+	if want := "{\"id\":1,\"msg\":\"start\"}\n{\"id\":2,\"msg\":\"stop\"}\n"; stdout != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, stdout)
+	}
+
+	if status, _, stderr := runCLI(t, "{\"id\": 1}\n{\n", "jsonl", "import"); status != exitError || !strings.Contains(stderr, "line 2:") {
+		t.Errorf("Expected an error on line 2, got status %d: %s", status, stderr)
+	}
+	for _, args := range [][]string{
+		{"jsonl"},
+		{"jsonl", "import", "-threshold", "2"},
+		{"jsonl", "import", "a.jsonl", "b.jsonl"},
+	} {
+		if status, _, _ := runCLI(t, "", args...); status != exitUsage {
+			t.Errorf("%q: expected status %d, got %d", args, exitUsage, status)
+		}
+	}
+}
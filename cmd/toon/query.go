@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+func runQuery(c *cli, args []string) int {
+	fs := c.flags("query", "[-format toon|json] path [file]")
+	format := fs.String("format", "toon", "output format: toon or json")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+	if *format != "toon" && *format != "json" {
+		return c.usageError(fs, "unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return c.usageError(fs, "missing path")
+	}
+	path, err := toon.ParsePath(fs.Arg(0))
+	if err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+
+	name, data, err := c.readInput(fs.Args()[1:])
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	v, err := readAny(data)
+	if err != nil {
+		return c.fail(inputError(name, err))
+	}
+
+	// A definite path selects a single value, which is written on its own;
+	// other paths write the array of their matches.
+	var result interface{}
+	matches := path.Select(v)
+	switch {
+	case !path.Definite():
+		if matches == nil {
+			matches = []interface{}{}
+		}
+		result = matches
+	case len(matches) == 0:
+		return c.fail(fmt.Errorf("%s: no value at %s", name, path))
+	default:
+		result = matches[0]
+	}
+
+	var out []byte
+	if *format == "json" {
+		out, err = marshalJSON(result, "  ")
+	} else {
+		out, err = encoder.Append(nil, result, nil)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	if len(out) == 0 || out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	if _, err := c.stdout.Write(out); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
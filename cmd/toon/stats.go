@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/tokens"
+	"github.com/devalexandre/toon-go/pkg/toon"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// layout is one encoding measured by stats.
+type layout struct {
+	name   string
+	encode func(v interface{}) ([]byte, error)
+}
+
+var layouts = []layout{
+	{"json", func(v interface{}) ([]byte, error) { return marshalJSON(v, "") }},
+	{"json-indent", func(v interface{}) ([]byte, error) { return marshalJSON(v, "  ") }},
+	{"toon", toonLayout(func(*encoder.Options) {})},
+	{"toon-tab", toonLayout(func(o *encoder.Options) { o.Delimiter = encoder.Tab })},
+	{"toon-pipe", toonLayout(func(o *encoder.Options) { o.Delimiter = encoder.Pipe })},
+	{"toon-fold", toonLayout(func(o *encoder.Options) { o.KeyFolding = true })},
+	{"toon-auto", toonLayout(func(o *encoder.Options) { o.AutoLayout = true })},
+}
+
+func toonLayout(set func(*encoder.Options)) func(v interface{}) ([]byte, error) {
+	opts := encoder.DefaultOptions()
+	set(&opts)
+	return func(v interface{}) ([]byte, error) {
+		return encoder.Append(nil, v, &opts)
+	}
+}
+
+// marshalJSON encodes v as JSON without escaping HTML characters, indented
+// by indent unless it is empty.
+func marshalJSON(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+func runStats(c *cli, args []string) int {
+	fs := c.flags("stats", "[-tokenizer list] [-depth n] [-keys n] [file]")
+	tokenizers := fs.String("tokenizer", "toon_base", "comma-separated `list` of tokenizers: toon_base, or the path of a tiktoken rank file such as cl100k_base.tiktoken")
+	depth := fs.Int("depth", 1, "levels of nested objects to break down by key")
+	keys := fs.Int("keys", 10, "number of keys to list, largest first; 0 lists all")
+	if status, ok := c.parse(fs, args); !ok {
+		return status
+	}
+	if *depth < 1 || *keys < 0 {
+		return c.usageError(fs, "-depth must be positive and -keys must not be negative")
+	}
+
+	toks, err := loadTokenizers(*tokenizers)
+	if err != nil {
+		return c.fail(err)
+	}
+	name, data, err := c.readInput(fs.Args())
+	if errors.Is(err, errTooManyFiles) {
+		return c.usageError(fs, "%v", err)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	v, err := readAny(data)
+	if err != nil {
+		return c.fail(inputError(name, err))
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "format\tbytes\tlines")
+	for _, tok := range toks {
+		fmt.Fprintf(tw, "\t%s", tok.Name())
+	}
+	fmt.Fprint(tw, "\tvs json\t\n")
+	// Savings are measured against compact JSON with the first tokenizer.
+	var base int
+	for i, l := range layouts {
+		out, err := l.encode(v)
+		if err != nil {
+			return c.fail(inputError(name, err))
+		}
+		text := string(out)
+		fmt.Fprintf(tw, "%s\t%d\t%d", l.name, len(out), countLines(text))
+		for _, tok := range toks {
+			fmt.Fprintf(tw, "\t%d", tok.Count(text))
+		}
+		n := toks[0].Count(text)
+		switch {
+		case i == 0:
+			base = n
+			fmt.Fprint(tw, "\t\t\n")
+		case base == 0:
+			fmt.Fprint(tw, "\t\t\n")
+		default:
+			fmt.Fprintf(tw, "\t%.1f%%\t\n", 100*(1-float64(n)/float64(base)))
+		}
+	}
+	tw.Flush()
+
+	costs, err := keyCosts(v, *depth, toks[0])
+	if err != nil {
+		return c.fail(inputError(name, err))
+	}
+	if len(costs) == 0 {
+		return exitOK
+	}
+	total := 0
+	for _, kc := range costs {
+		total += kc.toon
+	}
+	fmt.Fprintf(c.stdout, "\ntokens by key (%s):\n", toks[0].Name())
+	tw = tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "key\tjson\ttoon\tshare\tsaved\t\n")
+	for i, kc := range costs {
+		if *keys > 0 && i == *keys {
+			fmt.Fprintf(tw, "(%d more)\t\t\t\t\t\n", len(costs)-i)
+			break
+		}
+		saved := 0.0
+		if kc.json > 0 {
+			saved = 1 - float64(kc.toon)/float64(kc.json)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%.1f%%\t\n", kc.path, kc.json, kc.toon,
+			100*float64(kc.toon)/float64(total), 100*saved)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+// loadTokenizers resolves a comma-separated list of tokenizer names and
+// rank file paths.
+func loadTokenizers(list string) ([]tokens.Tokenizer, error) {
+	var toks []tokens.Tokenizer
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+			continue
+		case "toon_base":
+			toks = append(toks, tokens.Default())
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s: %w", name, err)
+		}
+		bpe, err := tokens.LoadTiktoken(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)), f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s: %w", name, err)
+		}
+		toks = append(toks, bpe)
+	}
+	if len(toks) == 0 {
+		return nil, errors.New("no tokenizer given")
+	}
+	return toks, nil
+}
+
+// readAny decodes data as JSON, or as TOON when it is not JSON, keeping
+// key order either way. Errors are reported for TOON unless data looks
+// like a JSON object.
+func readAny(data []byte) (interface{}, error) {
+	v, jerr := toon.DecodeJSON(data)
+	if jerr == nil {
+		return v, nil
+	}
+	out, err := toon.ToJSON(data)
+	if err != nil {
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			return nil, jerr
+		}
+		return nil, err
+	}
+	return toon.DecodeJSON(out)
+}
+
+// countLines returns the number of lines of text, counting a final line
+// without a newline.
+func countLines(text string) int {
+	n := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		n++
+	}
+	return n
+}
+
+// keyCost is the number of tokens spent on one field.
+type keyCost struct {
+	path       string
+	json, toon int
+}
+
+// keyCosts measures each field of v encoded on its own, descending into
+// objects up to depth levels, and returns them largest first.
+func keyCosts(v interface{}, depth int, tok tokens.Tokenizer) ([]keyCost, error) {
+	var costs []keyCost
+	opts := encoder.DefaultOptions()
+	var walk func(v interface{}, prefix string, level int) error
+	walk = func(v interface{}, prefix string, level int) error {
+		for _, m := range members(v) {
+			path := prefix + m.Key
+			if level < depth && len(members(m.Value)) > 0 {
+				if err := walk(m.Value, path+".", level+1); err != nil {
+					return err
+				}
+				continue
+			}
+			field := types.Object{m}
+			js, err := marshalJSON(field, "")
+			if err != nil {
+				return err
+			}
+			tn, err := encoder.Append(nil, field, &opts)
+			if err != nil {
+				return err
+			}
+			// Leave out the braces that wrap the field in JSON.
+			costs = append(costs, keyCost{path, tok.Count(string(js[1 : len(js)-1])), tok.Count(string(tn))})
+		}
+		return nil
+	}
+	if err := walk(v, "", 1); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(costs, func(i, j int) bool { return costs[i].toon > costs[j].toon })
+	return costs, nil
+}
+
+// members returns the fields of an object in order, with the keys of maps
+// sorted, or nil when v is not an object.
+func members(v interface{}) types.Object {
+	switch v := v.(type) {
+	case types.Object:
+		return v
+	case map[string]interface{}:
+		obj := make(types.Object, 0, len(v))
+		for key, value := range v {
+			obj = append(obj, types.Member{Key: key, Value: value})
+		}
+		sort.Slice(obj, func(i, j int) bool { return obj[i].Key < obj[j].Key })
+		return obj
+	}
+	return nil
+}
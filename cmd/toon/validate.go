@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+// problem is one error found by validate, as written by -format json.
+type problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func runValidate(c *cli, args []string) int {
+	flags := c.flags("validate", "[-schema file] [-format text|json] [path ...]")
+	schemaFile := flags.String("schema", "", "also check values against the JSON Schema in `file`, written as JSON or TOON")
+	format := flags.String("format", "text", "output format: text, or json for an array of errors")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if *format != "text" && *format != "json" {
+		return c.usageError(flags, "unknown format %q", *format)
+	}
+
+	var schema *decoder.Schema
+	if *schemaFile != "" {
+		data, err := os.ReadFile(*schemaFile)
+		if err != nil {
+			return c.fail(err)
+		}
+		if schema, err = decoder.ParseSchema(data); err != nil {
+			return c.fail(fmt.Errorf("%s: %v", *schemaFile, err))
+		}
+	}
+
+	problems := []problem{}
+	check := func(name string, data []byte) {
+		lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		for _, err := range decoder.Validate(data, schema) {
+			p := newProblem(name, err)
+			problems = append(problems, p)
+			if *format == "text" {
+				c.printProblem(p, lines)
+			}
+		}
+	}
+
+	status := exitOK
+	if flags.NArg() == 0 {
+		data, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return c.fail(err)
+		}
+		check(stdinName, data)
+	}
+	for _, path := range flags.Args() {
+		if err := walkTOON(path, check); err != nil {
+			status = c.fail(err)
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(problems); err != nil {
+			return c.fail(err)
+		}
+	}
+	if len(problems) > 0 {
+		return exitError
+	}
+	return status
+}
+
+func newProblem(name string, err error) problem {
+	p := problem{File: name, Message: err.Error()}
+	var serr *decoder.SyntaxError
+	var scerr *decoder.SchemaError
+	switch {
+	case errors.As(err, &serr):
+		p.Line, p.Column, p.Message = serr.Line, serr.Column, serr.Msg
+	case errors.As(err, &scerr):
+		p.Line, p.Column, p.Path, p.Message = scerr.Line, scerr.Column, scerr.Path, scerr.Msg
+	}
+	return p
+}
+
+// printProblem writes p as file:line:column: message, followed by the
+// offending line with a caret under the column.
+func (c *cli) printProblem(p problem, lines []string) {
+	msg := p.Message
+	if p.Path != "" {
+		msg = p.Path + ": " + msg
+	}
+	switch {
+	case p.Column > 0:
+		fmt.Fprintf(c.stdout, "%s:%d:%d: %s\n", p.File, p.Line, p.Column, msg)
+	case p.Line > 0:
+		fmt.Fprintf(c.stdout, "%s:%d: %s\n", p.File, p.Line, msg)
+	default:
+		fmt.Fprintf(c.stdout, "%s: %s\n", p.File, msg)
+	}
+	if p.Line < 1 || p.Line > len(lines) {
+		return
+	}
+
+	text := strings.TrimRight(lines[p.Line-1], "\r")
+	number := fmt.Sprint(p.Line)
+	fmt.Fprintf(c.stdout, "  %s | %s\n", number, text)
+	if p.Column > 0 {
+		// Tabs are kept so that the caret lines up in a terminal.
+		pad := []byte(text[:min(p.Column-1, len(text))])
+		for i, b := range pad {
+			if b != '\t' {
+				pad[i] = ' '
+			}
+		}
+		fmt.Fprintf(c.stdout, "  %s | %s^\n", strings.Repeat(" ", len(number)), pad)
+	}
+}
@@ -0,0 +1,731 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	toontypes "github.com/devalexandre/toon-go/pkg/types"
+)
+
+const generatedMarker = "// Code generated by toongen. DO NOT EDIT."
+
+type primKind int
+
+const (
+	primString primKind = iota
+	primBool
+	primInt
+	primUint
+	primFloat32
+	primFloat64
+)
+
+type fieldMode int
+
+const (
+	modePrimitive fieldMode = iota
+	modePrimitivePtr
+	modePrimitiveSlice
+	modeTabular
+	modeReflect
+)
+
+// fieldPlan describes how one struct field is generated.
+type fieldPlan struct {
+	goPath    string
+	key       string
+	omitEmpty bool
+	typ       types.Type
+	mode      fieldMode
+	prim      primKind
+	elem      types.Type // element type of slices and pointers
+	isArray   bool       // fixed-size array rather than slice
+	columns   []fieldPlan
+	ptrs      []embeddedPtr // embedded pointers the field is promoted through
+}
+
+// embeddedPtr is an embedded struct pointer on the path to a promoted field.
+type embeddedPtr struct {
+	goPath   string
+	elem     types.Type
+	exported bool
+}
+
+type generator struct {
+	pkg     *types.Package
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+// Generate type-checks the package in dir and returns the formatted source
+// of the methods for the named struct types.
+func Generate(dir string, names []string) ([]byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]bool{}}
+
+	var body bytes.Buffer
+	for _, name := range names {
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil, fmt.Errorf("type %s not found in package %s", name, pkg.Name())
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a named type", name)
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a struct type", name)
+		}
+
+		plans, err := g.planStruct(name, st)
+		if err != nil {
+			return nil, err
+		}
+
+		g.buf.Reset()
+		g.genMarshal(name, plans)
+		g.genUnmarshal(name, plans)
+		body.Write(g.buf.Bytes())
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\n", generatedMarker, pkg.Name())
+	out.WriteString("import (\n")
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		si, sj := isStdlib(imports[i]), isStdlib(imports[j])
+		if si != sj {
+			return si
+		}
+		return imports[i] < imports[j]
+	})
+	for i, path := range imports {
+		if i > 0 && isStdlib(imports[i-1]) && !isStdlib(path) {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+// isStdlib reports whether path looks like a standard library import.
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// loadPackage parses and type-checks the non-test files in dir, skipping
+// earlier toongen output. Type errors are tolerated because other files may
+// refer to the methods that are about to be generated.
+func loadPackage(dir string) (*types.Package, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(src, []byte(generatedMarker)) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	return pkg, nil
+}
+
+// structFields flattens st into its encoded fields, promoting untagged
+// embedded structs and resolving name conflicts like the runtime encoder.
+func (g *generator) structFields(owner string, st *types.Struct) ([]fieldPlan, error) {
+	type candidate struct {
+		plan   fieldPlan
+		depth  int
+		tagged bool
+		order  int
+	}
+
+	var candidates []candidate
+	var walk func(st *types.Struct, prefix string, depth int, ptrs []embeddedPtr) error
+	walk = func(st *types.Struct, prefix string, depth int, ptrs []embeddedPtr) error {
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			tag := reflect.StructTag(st.Tag(i))
+
+			raw, hasTag := tag.Lookup("toon")
+			if !hasTag {
+				raw, hasTag = tag.Lookup("json")
+			}
+			if raw == "-" {
+				continue
+			}
+			name, opts := toontypes.ParseTag(raw)
+
+			if field.Embedded() && name == "" {
+				if embedded, ok := field.Type().Underlying().(*types.Struct); ok {
+					if err := walk(embedded, prefix+field.Name()+".", depth+1, ptrs); err != nil {
+						return err
+					}
+					continue
+				}
+				if ptr, ok := field.Type().(*types.Pointer); ok {
+					if embedded, ok := ptr.Elem().Underlying().(*types.Struct); ok {
+						next := append(ptrs[:len(ptrs):len(ptrs)], embeddedPtr{
+							goPath:   prefix + field.Name(),
+							elem:     ptr.Elem(),
+							exported: field.Exported(),
+						})
+						if err := walk(embedded, prefix+field.Name()+".", depth+1, next); err != nil {
+							return err
+						}
+						continue
+					}
+				}
+			}
+			if !field.Exported() {
+				continue
+			}
+
+			plan := fieldPlan{
+				goPath: prefix + field.Name(),
+				key:    name,
+				typ:    field.Type(),
+				ptrs:   ptrs,
+			}
+			if plan.key == "" {
+				plan.key = field.Name()
+			}
+			for _, opt := range opts {
+				if opt == "omitempty" {
+					plan.omitEmpty = true
+				}
+			}
+			candidates = append(candidates, candidate{plan: plan, depth: depth, tagged: hasTag && name != "", order: len(candidates)})
+		}
+		return nil
+	}
+	if err := walk(st, "", 0, nil); err != nil {
+		return nil, err
+	}
+
+	byKey := map[string][]candidate{}
+	for _, c := range candidates {
+		byKey[c.plan.key] = append(byKey[c.plan.key], c)
+	}
+
+	var kept []candidate
+	for _, group := range byKey {
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].depth != group[j].depth {
+				return group[i].depth < group[j].depth
+			}
+			return group[i].tagged && !group[j].tagged
+		})
+		if len(group) == 1 || group[1].depth > group[0].depth || group[0].tagged && !group[1].tagged {
+			kept = append(kept, group[0])
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].order < kept[j].order })
+
+	plans := make([]fieldPlan, len(kept))
+	for i, c := range kept {
+		plans[i] = c.plan
+	}
+	return plans, nil
+}
+
+func (g *generator) planStruct(owner string, st *types.Struct) ([]fieldPlan, error) {
+	plans, err := g.structFields(owner, st)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		if err := g.planField(owner, &plans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+func primitiveKind(t types.Type) (primKind, bool) {
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return 0, false
+	}
+	info := basic.Info()
+	switch {
+	case info&types.IsString != 0:
+		return primString, true
+	case info&types.IsBoolean != 0:
+		return primBool, true
+	case info&types.IsInteger != 0 && info&types.IsUnsigned != 0:
+		return primUint, true
+	case info&types.IsInteger != 0:
+		return primInt, true
+	case basic.Kind() == types.Float32:
+		return primFloat32, true
+	case info&types.IsFloat != 0:
+		return primFloat64, true
+	default:
+		return 0, false
+	}
+}
+
+// checkSupported rejects types that have no TOON representation.
+func checkSupported(t types.Type, seen map[types.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Info()&types.IsComplex != 0 || u.Kind() == types.UnsafePointer {
+			return fmt.Errorf("type %s is not supported", t)
+		}
+	case *types.Chan, *types.Signature:
+		return fmt.Errorf("type %s is not supported", t)
+	case *types.Pointer:
+		return checkSupported(u.Elem(), seen)
+	case *types.Slice:
+		return checkSupported(u.Elem(), seen)
+	case *types.Array:
+		return checkSupported(u.Elem(), seen)
+	case *types.Map:
+		if _, ok := primitiveKind(u.Key()); !ok {
+			return fmt.Errorf("map key type %s is not supported", u.Key())
+		}
+		return checkSupported(u.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if u.Field(i).Exported() || u.Field(i).Embedded() {
+				if err := checkSupported(u.Field(i).Type(), seen); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) planField(owner string, plan *fieldPlan) error {
+	if err := checkSupported(plan.typ, map[types.Type]bool{}); err != nil {
+		return fmt.Errorf("%s.%s: %v", owner, plan.goPath, err)
+	}
+
+	if hasMarshaler(plan.typ) {
+		plan.mode = modeReflect
+		return nil
+	}
+
+	if prim, ok := primitiveKind(plan.typ); ok {
+		plan.mode = modePrimitive
+		plan.prim = prim
+		return nil
+	}
+
+	switch u := plan.typ.Underlying().(type) {
+	case *types.Pointer:
+		if prim, ok := primitiveKind(u.Elem()); ok {
+			plan.mode = modePrimitivePtr
+			plan.prim = prim
+			plan.elem = u.Elem()
+			return nil
+		}
+
+	case *types.Slice, *types.Array:
+		var elem types.Type
+		if s, ok := u.(*types.Slice); ok {
+			elem = s.Elem()
+		} else {
+			elem = u.(*types.Array).Elem()
+			plan.isArray = true
+		}
+		plan.elem = elem
+
+		if prim, ok := primitiveKind(elem); ok {
+			plan.mode = modePrimitiveSlice
+			plan.prim = prim
+			return nil
+		}
+
+		if st, ok := elem.Underlying().(*types.Struct); ok && !plan.isArray && !hasMarshaler(elem) {
+			if columns, ok := g.tabularColumns(owner, st); ok {
+				plan.mode = modeTabular
+				plan.columns = columns
+				return nil
+			}
+		}
+	}
+
+	plan.mode = modeReflect
+	return nil
+}
+
+// hasMarshaler reports whether t or *t has a MarshalTOON method, in which
+// case the field is left to the runtime encoder so the method is honoured.
+func hasMarshaler(t types.Type) bool {
+	if _, ok := t.(*types.Pointer); !ok {
+		t = types.NewPointer(t)
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "MarshalTOON")
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// tabularColumns returns the column plans for a struct whose fields are all
+// primitives or pointers to primitives, none promoted through an embedded
+// pointer.
+func (g *generator) tabularColumns(owner string, st *types.Struct) ([]fieldPlan, bool) {
+	columns, err := g.structFields(owner, st)
+	if err != nil || len(columns) == 0 {
+		return nil, false
+	}
+	for i := range columns {
+		if err := g.planField(owner, &columns[i]); err != nil {
+			return nil, false
+		}
+		if columns[i].mode != modePrimitive && columns[i].mode != modePrimitivePtr || len(columns[i].ptrs) > 0 {
+			return nil, false
+		}
+	}
+	return columns, true
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = true
+		return p.Name()
+	})
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// convert returns expr converted to the basic type named to, leaving it
+// untouched when t already is that type.
+func convert(t types.Type, to, expr string) string {
+	if basic, ok := t.(*types.Basic); ok && basic.Name() == to {
+		return expr
+	}
+	return to + "(" + expr + ")"
+}
+
+// appendExpr returns the expression appending expr, of primitive type t, to dst.
+func (g *generator) appendExpr(prim primKind, t types.Type, expr string) string {
+	switch prim {
+	case primString:
+		g.imports["github.com/devalexandre/toon-go/pkg/encoder"] = true
+		return fmt.Sprintf("encoder.AppendString(dst, %s)", convert(t, "string", expr))
+	case primBool:
+		g.imports["strconv"] = true
+		return fmt.Sprintf("strconv.AppendBool(dst, %s)", convert(t, "bool", expr))
+	case primInt:
+		g.imports["strconv"] = true
+		return fmt.Sprintf("strconv.AppendInt(dst, %s, 10)", convert(t, "int64", expr))
+	case primUint:
+		g.imports["strconv"] = true
+		return fmt.Sprintf("strconv.AppendUint(dst, %s, 10)", convert(t, "uint64", expr))
+	case primFloat32:
+		g.imports["github.com/devalexandre/toon-go/pkg/encoder"] = true
+		return fmt.Sprintf("encoder.AppendFloat(dst, %s, 32)", convert(t, "float64", expr))
+	default:
+		g.imports["github.com/devalexandre/toon-go/pkg/encoder"] = true
+		return fmt.Sprintf("encoder.AppendFloat(dst, %s, 64)", convert(t, "float64", expr))
+	}
+}
+
+// emptyCond returns the condition under which an omitempty field is written.
+func emptyCond(plan fieldPlan, expr string) string {
+	switch u := plan.typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return expr + ` != ""`
+		case u.Info()&types.IsBoolean != 0:
+			return expr
+		default:
+			return expr + " != 0"
+		}
+	case *types.Pointer, *types.Interface:
+		return expr + " != nil"
+	case *types.Slice, *types.Map, *types.Array:
+		return "len(" + expr + ") != 0"
+	default:
+		return ""
+	}
+}
+
+func quotedKey(key string) string {
+	return string(encoder.AppendKey(nil, key))
+}
+
+func (g *generator) genMarshal(name string, plans []fieldPlan) {
+	g.printf("\n// MarshalTOON implements toon.Marshaler.\n")
+	g.printf("func (v %s) MarshalTOON() ([]byte, error) {\n", name)
+	g.printf("var dst []byte\n")
+
+	needsErr := false
+	for _, plan := range plans {
+		if plan.mode == modeReflect {
+			needsErr = true
+		}
+	}
+	if needsErr {
+		g.printf("var err error\n")
+	}
+
+	for _, plan := range plans {
+		expr := "v." + plan.goPath
+		var conds []string
+		for _, ptr := range plan.ptrs {
+			conds = append(conds, "v."+ptr.goPath+" != nil")
+		}
+		if plan.omitEmpty {
+			if empty := emptyCond(plan, expr); empty != "" {
+				conds = append(conds, empty)
+			}
+		}
+		cond := strings.Join(conds, " && ")
+		if cond != "" {
+			g.printf("if %s {\n", cond)
+		}
+		g.genMarshalField(plan, expr)
+		if cond != "" {
+			g.printf("}\n")
+		}
+	}
+
+	g.printf("return dst, nil\n}\n")
+}
+
+func (g *generator) genMarshalField(plan fieldPlan, expr string) {
+	key := quotedKey(plan.key)
+
+	switch plan.mode {
+	case modePrimitive:
+		g.printf("dst = append(dst, %q...)\n", key+": ")
+		g.printf("dst = %s\n", g.appendExpr(plan.prim, plan.typ, expr))
+		g.printf("dst = append(dst, '\\n')\n")
+
+	case modePrimitivePtr:
+		g.printf("dst = append(dst, %q...)\n", key+": ")
+		g.printf("if %s == nil {\ndst = append(dst, \"null\"...)\n} else {\n", expr)
+		g.printf("dst = %s\n}\n", g.appendExpr(plan.prim, plan.elem, "*"+expr))
+		g.printf("dst = append(dst, '\\n')\n")
+
+	case modePrimitiveSlice:
+		g.imports["strconv"] = true
+		g.printf("dst = append(dst, %q...)\n", key+"[")
+		g.printf("dst = strconv.AppendInt(dst, int64(len(%s)), 10)\n", expr)
+		g.printf("if len(%s) == 0 {\ndst = append(dst, \"]:\\n\"...)\n} else {\n", expr)
+		g.printf("dst = append(dst, \"]: \"...)\n")
+		g.printf("for i, x := range %s {\nif i > 0 {\ndst = append(dst, ',')\n}\n", expr)
+		g.printf("dst = %s\n}\n", g.appendExpr(plan.prim, plan.elem, "x"))
+		g.printf("dst = append(dst, '\\n')\n}\n")
+
+	case modeTabular:
+		g.imports["strconv"] = true
+		header := make([]string, len(plan.columns))
+		for i, column := range plan.columns {
+			header[i] = quotedKey(column.key)
+		}
+		g.printf("dst = append(dst, %q...)\n", key+"[")
+		g.printf("dst = strconv.AppendInt(dst, int64(len(%s)), 10)\n", expr)
+		g.printf("if len(%s) == 0 {\ndst = append(dst, \"]:\\n\"...)\n} else {\n", expr)
+		g.printf("dst = append(dst, %q...)\n", "]{"+strings.Join(header, ",")+"}:\n")
+		g.printf("for i := range %s {\nx := &%s[i]\n", expr, expr)
+		g.printf("dst = append(dst, \"  \"...)\n")
+		for i, column := range plan.columns {
+			if i > 0 {
+				g.printf("dst = append(dst, ',')\n")
+			}
+			columnExpr := "x." + column.goPath
+			if column.mode == modePrimitivePtr {
+				g.printf("if %s == nil {\ndst = append(dst, \"null\"...)\n} else {\n", columnExpr)
+				g.printf("dst = %s\n}\n", g.appendExpr(column.prim, column.elem, "*"+columnExpr))
+			} else {
+				g.printf("dst = %s\n", g.appendExpr(column.prim, column.typ, columnExpr))
+			}
+		}
+		g.printf("dst = append(dst, '\\n')\n}\n}\n")
+
+	default:
+		g.imports["github.com/devalexandre/toon-go/pkg/encoder"] = true
+		g.printf("if dst, err = encoder.AppendField(dst, %q, %s, 0); err != nil {\nreturn nil, err\n}\n", plan.key, expr)
+	}
+}
+
+func (g *generator) genUnmarshal(name string, plans []fieldPlan) {
+	g.imports["github.com/devalexandre/toon-go/pkg/decoder"] = true
+	g.imports["fmt"] = true
+
+	g.printf("\n// UnmarshalTOON implements toon.Unmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalTOON(data []byte) error {\n", name)
+	g.printf("value, err := decoder.ParseBytes(data)\nif err != nil {\nreturn err\n}\n")
+	g.printf("return v.UnmarshalTOONValue(value)\n}\n")
+
+	g.printf("\n// UnmarshalTOONValue implements toon.ValueUnmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalTOONValue(value interface{}) error {\n", name)
+	g.printf("m, err := decoder.AsObject(value)\nif err != nil {\nreturn fmt.Errorf(\"toon: %s: %%w\", err)\n}\n", name)
+
+	for _, plan := range plans {
+		g.printf("if raw, ok := m[%q]; ok {\n", plan.key)
+		// Like the runtime decoder, allocate nil embedded pointers on the
+		// way to the field, and skip the field when one is unexported.
+		guards := 0
+		for _, ptr := range plan.ptrs {
+			if ptr.exported {
+				g.printf("if v.%s == nil {\nv.%s = new(%s)\n}\n", ptr.goPath, ptr.goPath, g.typeString(ptr.elem))
+			} else {
+				g.printf("if v.%s != nil {\n", ptr.goPath)
+				guards++
+			}
+		}
+		g.genUnmarshalField(plan, "v."+plan.goPath, name+"."+plan.goPath)
+		g.printf("%s}\n", strings.Repeat("}\n", guards))
+	}
+
+	g.printf("return nil\n}\n")
+}
+
+// convertFrom returns expr, as returned by the conversion function for prim,
+// converted to the Go type t.
+func (g *generator) convertFrom(prim primKind, t types.Type, expr string) string {
+	natural := map[primKind]string{
+		primString:  "string",
+		primBool:    "bool",
+		primInt:     "int64",
+		primUint:    "uint64",
+		primFloat32: "float64",
+		primFloat64: "float64",
+	}[prim]
+	if basic, ok := t.(*types.Basic); ok && basic.Name() == natural {
+		return expr
+	}
+	return g.typeString(t) + "(" + expr + ")"
+}
+
+// convertFunc returns the decoder conversion function for a primitive kind.
+func convertFunc(prim primKind) string {
+	switch prim {
+	case primString:
+		return "decoder.AsString"
+	case primBool:
+		return "decoder.AsBool"
+	case primInt:
+		return "decoder.AsInt64"
+	case primUint:
+		return "decoder.AsUint64"
+	default:
+		return "decoder.AsFloat64"
+	}
+}
+
+func (g *generator) genDecodePrimitive(prim primKind, src, target string, t types.Type, label string) {
+	g.printf("x, err := %s(%s)\n", convertFunc(prim), src)
+	g.printf("if err != nil {\nreturn fmt.Errorf(\"toon: %s: %%w\", err)\n}\n", label)
+	g.printf("%s = %s\n", target, g.convertFrom(prim, t, "x"))
+}
+
+func (g *generator) genUnmarshalField(plan fieldPlan, target, label string) {
+	switch plan.mode {
+	case modePrimitive:
+		g.genDecodePrimitive(plan.prim, "raw", target, plan.typ, label)
+
+	case modePrimitivePtr:
+		g.printf("if raw == nil {\n%s = nil\n} else {\n", target)
+		g.printf("x, err := %s(raw)\n", convertFunc(plan.prim))
+		g.printf("if err != nil {\nreturn fmt.Errorf(\"toon: %s: %%w\", err)\n}\n", label)
+		if conv := g.convertFrom(plan.prim, plan.elem, "x"); conv == "x" {
+			g.printf("%s = &x\n}\n", target)
+		} else {
+			g.printf("y := %s\n%s = &y\n}\n", conv, target)
+		}
+
+	case modePrimitiveSlice:
+		g.printf("items, err := decoder.AsArray(raw)\n")
+		g.printf("if err != nil {\nreturn fmt.Errorf(\"toon: %s: %%w\", err)\n}\n", label)
+		if plan.isArray {
+			g.printf("for i, item := range items {\nif i >= len(%s) {\nbreak\n}\n", target)
+		} else {
+			g.printf("if items == nil {\n%s = nil\n} else {\n", target)
+			g.printf("%s = make(%s, len(items))\n}\n", target, g.typeString(plan.typ))
+			g.printf("for i, item := range items {\n")
+		}
+		g.printf("x, err := %s(item)\n", convertFunc(plan.prim))
+		g.printf("if err != nil {\nreturn fmt.Errorf(\"toon: %s[%%d]: %%w\", i, err)\n}\n", label)
+		g.printf("%s[i] = %s\n}\n", target, g.convertFrom(plan.prim, plan.elem, "x"))
+
+	case modeTabular:
+		g.printf("items, err := decoder.AsArray(raw)\n")
+		g.printf("if err != nil {\nreturn fmt.Errorf(\"toon: %s: %%w\", err)\n}\n", label)
+		g.printf("if items == nil {\n%s = nil\n} else {\n", target)
+		g.printf("%s = make(%s, len(items))\n}\n", target, g.typeString(plan.typ))
+		g.printf("for i, item := range items {\n")
+		g.printf("row, err := decoder.AsObject(item)\n")
+		g.printf("if err != nil {\nreturn fmt.Errorf(\"toon: %s[%%d]: %%w\", i, err)\n}\n", label)
+		g.printf("elem := &%s[i]\n", target)
+		for _, column := range plan.columns {
+			g.printf("if raw, ok := row[%q]; ok {\n", column.key)
+			g.genUnmarshalField(column, "elem."+column.goPath, label+"[]."+column.goPath)
+			g.printf("}\n")
+		}
+		g.printf("}\n")
+
+	default:
+		g.imports["github.com/devalexandre/toon-go/pkg/toon"] = true
+		g.printf("if err := toon.UnmarshalValue(raw, &%s); err != nil {\n", target)
+		g.printf("return fmt.Errorf(\"toon: %s: %%w\", err)\n}\n", label)
+	}
+}
@@ -0,0 +1,62 @@
+// Command toongen generates reflection-free MarshalTOON and UnmarshalTOON
+// methods for Go struct types.
+//
+// It is meant to be run through go generate:
+//
+//	//go:generate go run github.com/devalexandre/toon-go/cmd/toongen -type Order,Customer
+//
+// The generated methods implement the toon.Marshaler, toon.Unmarshaler and
+// toon.ValueUnmarshaler interfaces. Primitive fields, slices of primitives
+// and slices of primitive-only structs (written as tabular arrays) are
+// encoded without reflection; other nested values fall back to the
+// reflection-based encoder. Field types that TOON cannot represent, such as
+// channels, functions and complex numbers, are reported as errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <first type>_toon.go")
+	dir := flag.String("dir", ".", "package directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: toongen -type T[,T...] [-output file] [-dir dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+
+	if *output == "" {
+		*output = strings.ToLower(names[0]) + "_toon.go"
+	}
+	outPath := *output
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(*dir, outPath)
+	}
+
+	src, err := Generate(*dir, names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "toongen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(outPath, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "toongen: %v\n", err)
+		os.Exit(1)
+	}
+}
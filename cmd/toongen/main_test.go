@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerateGolden regenerates the example package and compares it with
+// the committed output. Run go generate ./example/generated after changing
+// the generator.
+func TestGenerateGolden(t *testing.T) {
+	dir := filepath.Join("..", "..", "example", "generated")
+
+	got, err := Generate(dir, []string{"Order", "Customer"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	want, err := os.ReadFile(filepath.Join(dir, "order_toon.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s; run go generate ./example/generated", filepath.Join(dir, "order_toon.go"))
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		typ  string
+		want string
+	}{
+		{"missing type", "type A struct{}", "B", "type B not found"},
+		{"not a struct", "type A int", "A", "A is not a struct type"},
+		{"channel", "type A struct{ C chan int }", "A", "A.C: type chan int is not supported"},
+		{"func", "type A struct{ F func() }", "A", "A.F: type func() is not supported"},
+		{"complex", "type A struct{ X []complex128 }", "A", "A.X: type complex128 is not supported"},
+		{"map key", "type A struct{ M map[[2]int]string }", "A", "A.M: map key type [2]int is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := "package p\n\n" + tt.src + "\n"
+			if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Generate(dir, []string{tt.typ})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Generate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	src := `package p
+
+type Base struct {
+	ID   int    ` + "`toon:\"id\"`" + `
+	Name string ` + "`toon:\"name\"`" + `
+}
+
+type A struct {
+	Base
+	Name  string ` + "`toon:\"name\"`" + `
+	Score *float32
+	skip  int
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Generate(dir, []string{"A"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	code := string(out)
+	for _, want := range []string{
+		`dst = append(dst, "id: "...)`,
+		`strconv.AppendInt(dst, int64(v.Base.ID), 10)`,
+		`dst = append(dst, "name: "...)`,
+		`encoder.AppendString(dst, v.Name)`,
+		`encoder.AppendFloat(dst, float64(*v.Score), 32)`,
+		`m["Score"]`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "skip") || strings.Contains(code, "v.Base.Name") {
+		t.Errorf("generated code includes hidden or unexported fields:\n%s", code)
+	}
+}
+
+func TestGenerateEmbeddedPointer(t *testing.T) {
+	dir := t.TempDir()
+	src := `package p
+
+type Base struct {
+	ID int ` + "`toon:\"id\"`" + `
+}
+
+type inner struct {
+	Note string
+}
+
+type A struct {
+	*Base
+	*inner
+	Name string
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Generate(dir, []string{"A"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	code := string(out)
+	for _, want := range []string{
+		"if v.Base != nil {",
+		"strconv.AppendInt(dst, int64(v.Base.ID), 10)",
+		"if v.Base == nil {\n\t\t\tv.Base = new(Base)\n\t\t}",
+		"if v.inner != nil {",
+		"v.inner.Note = x",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q:\n%s", want, code)
+		}
+	}
+}
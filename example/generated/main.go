@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+func main() {
+	email := "ada@example.com"
+	order := Order{
+		ID:   1001,
+		Paid: true,
+		Tags: []string{"priority", "gift"},
+		Items: []LineItem{
+			{SKU: "A-1", Quantity: 2, Price: 9.5},
+			{SKU: "B-7", Quantity: 1, Price: 24.99},
+		},
+		Customer: Customer{ID: 7, Name: "Ada", Email: &email},
+		Meta:     map[string]string{"channel": "web"},
+	}
+
+	data, err := toon.Marshal(order)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(string(data))
+
+	var decoded Order
+	if err := toon.Unmarshal(data, &decoded); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\ndecoded %d items for %s\n", len(decoded.Items), decoded.Customer.Name)
+}
@@ -0,0 +1,29 @@
+package main
+
+//go:generate go run github.com/devalexandre/toon-go/cmd/toongen -type Order,Customer
+
+// Customer is encoded with generated methods.
+type Customer struct {
+	ID    int     `toon:"id"`
+	Name  string  `toon:"name"`
+	Email *string `toon:"email"`
+}
+
+// LineItem only holds primitives, so []LineItem is written as a tabular array.
+type LineItem struct {
+	SKU      string  `toon:"sku"`
+	Quantity int     `toon:"qty"`
+	Price    float64 `toon:"price"`
+}
+
+// Order mixes generated fast paths with a reflection fallback for Customer
+// and Meta.
+type Order struct {
+	ID       uint64            `toon:"id"`
+	Paid     bool              `toon:"paid"`
+	Tags     []string          `toon:"tags"`
+	Items    []LineItem        `toon:"items"`
+	Customer Customer          `toon:"customer"`
+	Meta     map[string]string `toon:"meta,omitempty"`
+	Note     string            `toon:"note,omitempty"`
+}
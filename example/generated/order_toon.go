@@ -0,0 +1,237 @@
+// Code generated by toongen. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/toon"
+)
+
+// MarshalTOON implements toon.Marshaler.
+func (v Order) MarshalTOON() ([]byte, error) {
+	var dst []byte
+	var err error
+	dst = append(dst, "id: "...)
+	dst = strconv.AppendUint(dst, v.ID, 10)
+	dst = append(dst, '\n')
+	dst = append(dst, "paid: "...)
+	dst = strconv.AppendBool(dst, v.Paid)
+	dst = append(dst, '\n')
+	dst = append(dst, "tags["...)
+	dst = strconv.AppendInt(dst, int64(len(v.Tags)), 10)
+	if len(v.Tags) == 0 {
+		dst = append(dst, "]:\n"...)
+	} else {
+		dst = append(dst, "]: "...)
+		for i, x := range v.Tags {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = encoder.AppendString(dst, x)
+		}
+		dst = append(dst, '\n')
+	}
+	dst = append(dst, "items["...)
+	dst = strconv.AppendInt(dst, int64(len(v.Items)), 10)
+	if len(v.Items) == 0 {
+		dst = append(dst, "]:\n"...)
+	} else {
+		dst = append(dst, "]{sku,qty,price}:\n"...)
+		for i := range v.Items {
+			x := &v.Items[i]
+			dst = append(dst, "  "...)
+			dst = encoder.AppendString(dst, x.SKU)
+			dst = append(dst, ',')
+			dst = strconv.AppendInt(dst, int64(x.Quantity), 10)
+			dst = append(dst, ',')
+			dst = encoder.AppendFloat(dst, x.Price, 64)
+			dst = append(dst, '\n')
+		}
+	}
+	if dst, err = encoder.AppendField(dst, "customer", v.Customer, 0); err != nil {
+		return nil, err
+	}
+	if len(v.Meta) != 0 {
+		if dst, err = encoder.AppendField(dst, "meta", v.Meta, 0); err != nil {
+			return nil, err
+		}
+	}
+	if v.Note != "" {
+		dst = append(dst, "note: "...)
+		dst = encoder.AppendString(dst, v.Note)
+		dst = append(dst, '\n')
+	}
+	return dst, nil
+}
+
+// UnmarshalTOON implements toon.Unmarshaler.
+func (v *Order) UnmarshalTOON(data []byte) error {
+	value, err := decoder.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalTOONValue(value)
+}
+
+// UnmarshalTOONValue implements toon.ValueUnmarshaler.
+func (v *Order) UnmarshalTOONValue(value interface{}) error {
+	m, err := decoder.AsObject(value)
+	if err != nil {
+		return fmt.Errorf("toon: Order: %w", err)
+	}
+	if raw, ok := m["id"]; ok {
+		x, err := decoder.AsUint64(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Order.ID: %w", err)
+		}
+		v.ID = x
+	}
+	if raw, ok := m["paid"]; ok {
+		x, err := decoder.AsBool(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Order.Paid: %w", err)
+		}
+		v.Paid = x
+	}
+	if raw, ok := m["tags"]; ok {
+		items, err := decoder.AsArray(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Order.Tags: %w", err)
+		}
+		if items == nil {
+			v.Tags = nil
+		} else {
+			v.Tags = make([]string, len(items))
+		}
+		for i, item := range items {
+			x, err := decoder.AsString(item)
+			if err != nil {
+				return fmt.Errorf("toon: Order.Tags[%d]: %w", i, err)
+			}
+			v.Tags[i] = x
+		}
+	}
+	if raw, ok := m["items"]; ok {
+		items, err := decoder.AsArray(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Order.Items: %w", err)
+		}
+		if items == nil {
+			v.Items = nil
+		} else {
+			v.Items = make([]LineItem, len(items))
+		}
+		for i, item := range items {
+			row, err := decoder.AsObject(item)
+			if err != nil {
+				return fmt.Errorf("toon: Order.Items[%d]: %w", i, err)
+			}
+			elem := &v.Items[i]
+			if raw, ok := row["sku"]; ok {
+				x, err := decoder.AsString(raw)
+				if err != nil {
+					return fmt.Errorf("toon: Order.Items[].SKU: %w", err)
+				}
+				elem.SKU = x
+			}
+			if raw, ok := row["qty"]; ok {
+				x, err := decoder.AsInt64(raw)
+				if err != nil {
+					return fmt.Errorf("toon: Order.Items[].Quantity: %w", err)
+				}
+				elem.Quantity = int(x)
+			}
+			if raw, ok := row["price"]; ok {
+				x, err := decoder.AsFloat64(raw)
+				if err != nil {
+					return fmt.Errorf("toon: Order.Items[].Price: %w", err)
+				}
+				elem.Price = x
+			}
+		}
+	}
+	if raw, ok := m["customer"]; ok {
+		if err := toon.UnmarshalValue(raw, &v.Customer); err != nil {
+			return fmt.Errorf("toon: Order.Customer: %w", err)
+		}
+	}
+	if raw, ok := m["meta"]; ok {
+		if err := toon.UnmarshalValue(raw, &v.Meta); err != nil {
+			return fmt.Errorf("toon: Order.Meta: %w", err)
+		}
+	}
+	if raw, ok := m["note"]; ok {
+		x, err := decoder.AsString(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Order.Note: %w", err)
+		}
+		v.Note = x
+	}
+	return nil
+}
+
+// MarshalTOON implements toon.Marshaler.
+func (v Customer) MarshalTOON() ([]byte, error) {
+	var dst []byte
+	dst = append(dst, "id: "...)
+	dst = strconv.AppendInt(dst, int64(v.ID), 10)
+	dst = append(dst, '\n')
+	dst = append(dst, "name: "...)
+	dst = encoder.AppendString(dst, v.Name)
+	dst = append(dst, '\n')
+	dst = append(dst, "email: "...)
+	if v.Email == nil {
+		dst = append(dst, "null"...)
+	} else {
+		dst = encoder.AppendString(dst, *v.Email)
+	}
+	dst = append(dst, '\n')
+	return dst, nil
+}
+
+// UnmarshalTOON implements toon.Unmarshaler.
+func (v *Customer) UnmarshalTOON(data []byte) error {
+	value, err := decoder.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalTOONValue(value)
+}
+
+// UnmarshalTOONValue implements toon.ValueUnmarshaler.
+func (v *Customer) UnmarshalTOONValue(value interface{}) error {
+	m, err := decoder.AsObject(value)
+	if err != nil {
+		return fmt.Errorf("toon: Customer: %w", err)
+	}
+	if raw, ok := m["id"]; ok {
+		x, err := decoder.AsInt64(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Customer.ID: %w", err)
+		}
+		v.ID = int(x)
+	}
+	if raw, ok := m["name"]; ok {
+		x, err := decoder.AsString(raw)
+		if err != nil {
+			return fmt.Errorf("toon: Customer.Name: %w", err)
+		}
+		v.Name = x
+	}
+	if raw, ok := m["email"]; ok {
+		if raw == nil {
+			v.Email = nil
+		} else {
+			x, err := decoder.AsString(raw)
+			if err != nil {
+				return fmt.Errorf("toon: Customer.Email: %w", err)
+			}
+			v.Email = &x
+		}
+	}
+	return nil
+}
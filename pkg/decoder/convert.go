@@ -0,0 +1,115 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// The functions in this file convert the generic values produced by Parse
+// into concrete Go types. They are used by code generated by cmd/toongen;
+// nil converts to the zero value of each type.
+
+// ParseBytes parses a complete TOON document held in memory.
+func ParseBytes(data []byte) (interface{}, error) {
+	return NewParser(bytes.NewReader(data)).Parse()
+}
+
+// AsString converts a decoded value to a string.
+func AsString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	default:
+		return "", fmt.Errorf("cannot decode %T into string", v)
+	}
+}
+
+// AsBool converts a decoded value to a bool.
+func AsBool(v interface{}) (bool, error) {
+	switch val := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return val, nil
+	default:
+		return false, fmt.Errorf("cannot decode %T into bool", v)
+	}
+}
+
+// AsInt64 converts a decoded number without a fractional part to an int64.
+func AsInt64(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return val, nil
+	case float64:
+		if val != math.Trunc(val) || val < math.MinInt64 || val >= math.MaxInt64 {
+			return 0, fmt.Errorf("cannot decode %v into integer", val)
+		}
+		return int64(val), nil
+	default:
+		return 0, fmt.Errorf("cannot decode %T into integer", v)
+	}
+}
+
+// AsUint64 converts a decoded non-negative integer to a uint64.
+func AsUint64(v interface{}) (uint64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		if val < 0 {
+			return 0, fmt.Errorf("cannot decode %d into unsigned integer", val)
+		}
+		return uint64(val), nil
+	case float64:
+		if val != math.Trunc(val) || val < 0 || val >= math.MaxUint64 {
+			return 0, fmt.Errorf("cannot decode %v into unsigned integer", val)
+		}
+		return uint64(val), nil
+	default:
+		return 0, fmt.Errorf("cannot decode %T into unsigned integer", v)
+	}
+}
+
+// AsFloat64 converts a decoded number to a float64.
+func AsFloat64(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	default:
+		return 0, fmt.Errorf("cannot decode %T into float", v)
+	}
+}
+
+// AsArray converts a decoded value to an array.
+func AsArray(v interface{}) ([]interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return val, nil
+	default:
+		return nil, fmt.Errorf("cannot decode %T into array", v)
+	}
+}
+
+// AsObject converts a decoded value to an object.
+func AsObject(v interface{}) (map[string]interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return val, nil
+	default:
+		return nil, fmt.Errorf("cannot decode %T into object", v)
+	}
+}
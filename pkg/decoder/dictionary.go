@@ -0,0 +1,134 @@
+package decoder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// keysDirective opens the key dictionary block written by the encoder's
+// KeyDictionary option.
+const keysDirective = "%keys"
+
+// expandKeys removes the key dictionary block at the top of lines, if any,
+// and replaces every alias in a key position with the key it stands for.
+// Without Options.KeyDictionary a dictionary is an error, so that such
+// documents are never silently decoded with their aliases as keys.
+func (p *Parser) expandKeys(lines []string) ([]string, error) {
+	first := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			first = i
+			break
+		}
+	}
+	if first < 0 || strings.TrimSpace(lines[first]) != keysDirective {
+		return lines, nil
+	}
+	if !p.opts.KeyDictionary {
+		return nil, &SyntaxError{Line: first + 1, Msg: "document uses a key dictionary; decode it with Options.KeyDictionary"}
+	}
+
+	aliases := map[string]string{}
+	end := first + 1
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] != ' ' {
+			break
+		}
+		alias, rest, ok := splitKey(strings.TrimSpace(line))
+		if !ok || !strings.HasPrefix(alias, "@") || !strings.HasPrefix(rest, ":") {
+			return nil, &SyntaxError{Line: end + 1, Msg: "invalid key dictionary entry"}
+		}
+		value := strings.TrimSpace(rest[1:])
+		if len(value) < 2 || value[0] != '"' || closingQuote(value) != len(value)-1 {
+			return nil, &SyntaxError{Line: end + 1, Msg: fmt.Sprintf("key dictionary entry %s must be a quoted string", alias)}
+		}
+		aliases[alias] = unquote(value[1 : len(value)-1])
+	}
+
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if i >= first && i < end {
+			continue
+		}
+		expanded, err := expandLineKeys(line, aliases)
+		if err != nil {
+			return nil, &SyntaxError{Line: i + 1, Msg: err.Error()}
+		}
+		out[i] = expanded
+	}
+	return out, nil
+}
+
+// expandLineKeys replaces the alias in the key position of line and in the
+// field list of a tabular header. Values are left alone.
+func expandLineKeys(line string, aliases map[string]string) (string, error) {
+	content := strings.TrimLeft(line, " ")
+	prefix := line[:len(line)-len(content)]
+	if strings.HasPrefix(content, "- ") {
+		prefix += "- "
+		content = content[2:]
+	}
+	_, rest, ok := splitKey(content)
+	if !ok {
+		return line, nil
+	}
+	// The key as written, quotes included.
+	key := content[:len(content)-len(rest)]
+	if strings.HasPrefix(key, "@") {
+		full, ok := aliases[strings.TrimSpace(key)]
+		if !ok {
+			return "", fmt.Errorf("unknown key alias %q", strings.TrimSpace(key))
+		}
+		key = dictionaryKeyText(full)
+	}
+
+	if strings.HasPrefix(rest, "[") {
+		closing := strings.Index(rest, "]")
+		if closing > 0 && strings.HasPrefix(rest[closing+1:], "{") {
+			delim := byte(',')
+			if c := rest[closing-1]; c == '|' || c == '\t' {
+				delim = c
+			}
+			open := closing + 1
+			fieldsEnd := strings.Index(rest[open:], "}")
+			if fieldsEnd < 0 {
+				return line, nil
+			}
+			fieldsEnd += open
+			fields := rest[open+1 : fieldsEnd]
+
+			var b strings.Builder
+			for i, span := range splitSpans(fields, delim) {
+				if i > 0 {
+					b.WriteByte(delim)
+				}
+				field := strings.TrimSpace(fields[span[0]:span[1]])
+				if strings.HasPrefix(field, "@") {
+					full, ok := aliases[field]
+					if !ok {
+						return "", fmt.Errorf("unknown key alias %q", field)
+					}
+					field = dictionaryKeyText(full)
+				}
+				b.WriteString(field)
+			}
+			rest = rest[:open+1] + b.String() + rest[fieldsEnd:]
+		}
+	}
+	return prefix + key + rest, nil
+}
+
+// dictionaryKeyText returns key as it would appear in the document without
+// the dictionary: bare when possible, quoted otherwise.
+func dictionaryKeyText(key string) string {
+	if isBareKeyText(key) && key[0] != '-' {
+		return key
+	}
+	return string(types.AppendString(nil, key))
+}
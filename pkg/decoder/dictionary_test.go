@@ -0,0 +1,80 @@
+package decoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyDictionary(t *testing.T) {
+	input := `%keys
+  @0: "requestDurationMilliseconds"
+  @1: "status code"
+@1: 200
+@0: 12
+spans[2|]{@0|"@1"}:
+  1|a
+  2|b
+items[1]:
+  - @0: 3`
+
+	if _, err := NewParser(strings.NewReader(input)).Parse(); err == nil || !strings.Contains(err.Error(), "key dictionary") {
+		t.Fatalf("Expected the dictionary to be rejected without the option, got %v", err)
+	}
+
+	result, err := NewParserWithOptions(strings.NewReader(input), Options{KeyDictionary: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"status code":                 int64(200),
+		"requestDurationMilliseconds": int64(12),
+		"spans": []interface{}{
+			map[string]interface{}{"requestDurationMilliseconds": int64(1), "@1": "a"},
+			map[string]interface{}{"requestDurationMilliseconds": int64(2), "@1": "b"},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"requestDurationMilliseconds": int64(3)},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %#v, got %#v", want, result)
+	}
+}
+
+func TestParseKeyDictionaryUnknownAlias(t *testing.T) {
+	input := "%keys\n  @0: \"name\"\n@1: 2"
+	_, err := NewParserWithOptions(strings.NewReader(input), Options{KeyDictionary: true}).Parse()
+	if err == nil || !strings.Contains(err.Error(), "line 3: unknown key alias") {
+		t.Errorf("Expected unknown alias error, got %v", err)
+	}
+}
+
+func TestParseKeyDictionaryQuotedKeys(t *testing.T) {
+	input := `%keys
+  @0: "customer"
+  @1: "status"
+"k:v"[2]{@0,@1}:
+  "a",1
+  "b",2
+items[1]:
+  - "x[y"[1]{@1}:
+      3`
+
+	result, err := NewParserWithOptions(strings.NewReader(input), Options{KeyDictionary: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"k:v": []interface{}{
+			map[string]interface{}{"customer": "a", "status": int64(1)},
+			map[string]interface{}{"customer": "b", "status": int64(2)},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"x[y": []interface{}{map[string]interface{}{"status": int64(3)}}},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %#v, got %#v", want, result)
+	}
+}
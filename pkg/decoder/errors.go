@@ -0,0 +1,36 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SyntaxError reports invalid TOON and where it was found. Line and Column
+// are 1-based; Column is the byte offset of the start of the offending
+// line's content, or 0 when it is not known.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// syntaxError attributes err to line, a 1-based line number, unless it
+// already carries a position from a more deeply nested line.
+func (p *Parser) syntaxError(line int, err error) error {
+	var serr *SyntaxError
+	if errors.As(err, &serr) {
+		return err
+	}
+	column := 0
+	if line >= 1 && line <= len(p.lines) {
+		column = p.indentOf(p.lines[line-1]) + 1
+	}
+	return &SyntaxError{Line: line, Column: column, Msg: err.Error()}
+}
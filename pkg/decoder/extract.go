@@ -0,0 +1,203 @@
+package decoder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// BlockKind tells how a TOON document was found by ExtractBlocks.
+type BlockKind int
+
+const (
+	// FencedBlock is a code fence tagged toon.
+	FencedBlock BlockKind = iota
+	// UntaggedBlock is a code fence without a language whose content parses
+	// as a TOON object or array.
+	UntaggedBlock
+	// BareBlock is a document at the end of the text, outside any fence.
+	BareBlock
+)
+
+func (k BlockKind) String() string {
+	switch k {
+	case FencedBlock:
+		return "fenced"
+	case UntaggedBlock:
+		return "untagged"
+	case BareBlock:
+		return "bare"
+	default:
+		return "BlockKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Block is a TOON document found inside a larger text.
+type Block struct {
+	// Start and End are the byte offsets of the document in the text, so
+	// that text[Start:End] is its source without fences.
+	Start, End int
+	Kind       BlockKind
+	// Value is the decoded document.
+	Value interface{}
+	// Err is the parse error of a block tagged toon that is not valid. Other
+	// blocks are only returned when they parse.
+	Err error
+}
+
+// textLine is a line of a text with the byte offsets of its content,
+// excluding the newline.
+type textLine struct {
+	text       string
+	start, end int
+}
+
+func splitTextLines(text string) []textLine {
+	var lines []textLine
+	start := 0
+	for start <= len(text) {
+		end := strings.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		line := textLine{text: text[start:end], start: start, end: end}
+		if strings.HasSuffix(line.text, "\r") {
+			line.text = line.text[:len(line.text)-1]
+			line.end--
+		}
+		lines = append(lines, line)
+		start = end + 1
+	}
+	return lines
+}
+
+// ExtractBlocks finds the TOON documents in text, such as the response of
+// a language model: code fences tagged toon, code fences without a language
+// whose content parses as a TOON object or array, and a document at the end
+// of the text after any fences. Blocks are returned in order of appearance.
+func ExtractBlocks(text string) []Block {
+	lines := splitTextLines(text)
+	var blocks []Block
+
+	bareStart := 0
+	for i := 0; i < len(lines); i++ {
+		content := strings.TrimSpace(lines[i].text)
+		if !strings.HasPrefix(content, "```") {
+			continue
+		}
+		lang := strings.ToLower(strings.TrimSpace(strings.TrimLeft(content, "`")))
+
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j].text) == "```" {
+				end = j
+				break
+			}
+		}
+		body := lines[i+1 : end]
+		bodyStart := len(text)
+		if i+1 < len(lines) {
+			bodyStart = lines[i+1].start
+		}
+		i, bareStart = end, end+1
+
+		switch lang {
+		case "toon":
+			block := Block{Kind: FencedBlock}
+			block.Start, block.End = lineRange(body, bodyStart)
+			block.Value, block.Err = parseBlock(text[block.Start:block.End])
+			blocks = append(blocks, block)
+		case "":
+			if block, ok := structuredBlock(text, body, UntaggedBlock); ok {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+
+	if bareStart < len(lines) {
+		if block, ok := bareBlock(text, lines[bareStart:]); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// bareBlock finds a document at the end of lines: the longest run of
+// top-level TOON lines, with their indented children, that reaches the end
+// of the text and parses as an object or array.
+func bareBlock(text string, lines []textLine) (Block, bool) {
+	last := len(lines) - 1
+	for last >= 0 && strings.TrimSpace(lines[last].text) == "" {
+		last--
+	}
+
+	var starts []int
+	for i := last; i >= 0; i-- {
+		line := lines[i].text
+		content := strings.TrimSpace(line)
+		if content == "" || content != strings.TrimLeft(line, " \t") {
+			continue
+		}
+		if !isTOONLine(content) {
+			break
+		}
+		starts = append(starts, i)
+	}
+
+	// Prefer the earliest start so the whole document is returned.
+	for k := len(starts) - 1; k >= 0; k-- {
+		if block, ok := structuredBlock(text, lines[starts[k]:last+1], BareBlock); ok {
+			return block, true
+		}
+	}
+	return Block{}, false
+}
+
+// structuredBlock decodes lines as a block of the given kind, accepting it
+// only when the first line looks like TOON and the value is an object or
+// an array, so that plain text is not mistaken for a string document.
+func structuredBlock(text string, lines []textLine, kind BlockKind) (Block, bool) {
+	first := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line.text) != "" {
+			first = i
+			break
+		}
+	}
+	if first < 0 || !isTOONLine(strings.TrimSpace(lines[first].text)) {
+		return Block{}, false
+	}
+
+	block := Block{Kind: kind}
+	block.Start, block.End = lineRange(lines, lines[0].start)
+	value, err := parseBlock(text[block.Start:block.End])
+	if err != nil {
+		return Block{}, false
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		block.Value = value
+		return block, true
+	}
+	return Block{}, false
+}
+
+// lineRange returns the byte range covering lines without surrounding
+// blank lines, or an empty range at offset when all lines are blank.
+func lineRange(lines []textLine, offset int) (int, int) {
+	for len(lines) > 0 && strings.TrimSpace(lines[0].text) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1].text) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return offset, offset
+	}
+	return lines[0].start, lines[len(lines)-1].end
+}
+
+func parseBlock(source string) (interface{}, error) {
+	return NewParser(strings.NewReader(source)).Parse()
+}
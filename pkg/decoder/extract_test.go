@@ -0,0 +1,60 @@
+package decoder
+
+import (
+	"testing"
+)
+
+func TestExtractBlocks(t *testing.T) {
+	text := "Here are the users:\n\n" +
+		"```toon\nusers[2]{id,name}:\n  1,Ada\n  2,Linus\n```\n\n" +
+		"The same as JSON:\n\n```json\n{\"id\": 1}\n```\n\n" +
+		"```\ncount: 2\n```\n\n" +
+		"```\nplain text, not a document\n```\n\n" +
+		"And the summary:\n\ntotal: 2\nmeta:\n  page: 1\n\n"
+
+	blocks := ExtractBlocks(text)
+	if len(blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d: %+v", len(blocks), blocks)
+	}
+
+	wantSources := []string{"users[2]{id,name}:\n  1,Ada\n  2,Linus", "count: 2", "total: 2\nmeta:\n  page: 1"}
+	wantKinds := []BlockKind{FencedBlock, UntaggedBlock, BareBlock}
+	for i, block := range blocks {
+		if block.Err != nil {
+			t.Errorf("Block %d: unexpected error %v", i, block.Err)
+		}
+		if block.Kind != wantKinds[i] {
+			t.Errorf("Block %d: expected kind %v, got %v", i, wantKinds[i], block.Kind)
+		}
+		if source := text[block.Start:block.End]; source != wantSources[i] {
+			t.Errorf("Block %d: expected source %q, got %q", i, wantSources[i], source)
+		}
+	}
+
+	users := blocks[0].Value.(map[string]interface{})["users"].([]interface{})
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users, got %v", users)
+	}
+	if blocks[2].Value.(map[string]interface{})["total"] != int64(2) {
+		t.Errorf("Unexpected bare block value %v", blocks[2].Value)
+	}
+}
+
+func TestExtractBlocksInvalidFence(t *testing.T) {
+	blocks := ExtractBlocks("```toon\nusers[3]{id}:\n  1\n```")
+	if len(blocks) != 1 || blocks[0].Err == nil {
+		t.Fatalf("Expected one block with an error, got %+v", blocks)
+	}
+}
+
+func TestExtractBlocksWholeDocument(t *testing.T) {
+	text := "name: Alice\ntags[2]: a,b"
+	blocks := ExtractBlocks(text)
+	if len(blocks) != 1 || blocks[0].Kind != BareBlock || blocks[0].Start != 0 || blocks[0].End != len(text) {
+		t.Fatalf("Expected the whole text as a bare block, got %+v", blocks)
+	}
+
+	if blocks := ExtractBlocks("Just a sentence, nothing else."); len(blocks) != 0 {
+		t.Errorf("Expected no blocks in prose, got %+v", blocks)
+	}
+}
@@ -0,0 +1,265 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// FormatOptions controls Format.
+type FormatOptions struct {
+	// Indent is the indentation unit. Empty means two spaces.
+	Indent string
+	// SortKeys sorts the keys of every object. Comments and blank lines
+	// move with the key they precede. Tabular columns keep their order.
+	SortKeys bool
+}
+
+// Format rewrites a TOON document in canonical form: one indentation unit
+// per level, keys quoted only when they must be, strings always quoted with
+// the encoder's escapes, numbers written the way the encoder writes them
+// and array counts recomputed from the items present. The layout of every
+// array, the order of keys unless SortKeys is set, and comment lines
+// starting with "#" are kept; runs of blank lines between keys are
+// collapsed to one.
+//
+// Literal keys containing dots stay quoted so that documents decoded with
+// ExpandPaths keep their meaning. Integers too large for an int64 keep their
+// digits. Documents using a key dictionary are not supported.
+func Format(data []byte, opts FormatOptions) ([]byte, error) {
+	p := newTreeParser(data)
+	p.recount = true
+	t, err := p.document()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &formatter{indent: opts.Indent, sort: opts.SortKeys}
+	if f.indent == "" {
+		f.indent = "  "
+	}
+	f.document(t)
+
+	if _, err := NewParser(bytes.NewReader(f.buf)).Parse(); err != nil {
+		return nil, fmt.Errorf("formatted document does not parse: %w", err)
+	}
+	return f.buf, nil
+}
+
+type formatter struct {
+	buf    []byte
+	indent string
+	sort   bool
+}
+
+func (f *formatter) document(t *tree) {
+	f.comments(t.comments, 0)
+	if t.root != nil {
+		switch t.root.kind {
+		case objectNode:
+			f.entries(t.root.entries, 0)
+		case arrayNode:
+			f.value(t.root, 0)
+		default:
+			f.buf = appendValue(f.buf, t.root.raw)
+			f.buf = append(f.buf, '\n')
+		}
+	}
+	f.comments(t.trailing, 0)
+}
+
+func (f *formatter) appendIndent(depth int) {
+	for i := 0; i < depth; i++ {
+		f.buf = append(f.buf, f.indent...)
+	}
+}
+
+func (f *formatter) comments(comments []string, depth int) {
+	for _, comment := range comments {
+		f.appendIndent(depth)
+		f.buf = append(f.buf, comment...)
+		f.buf = append(f.buf, '\n')
+	}
+}
+
+// entries writes the fields of an object at depth.
+func (f *formatter) entries(entries []*entry, depth int) {
+	for i, e := range f.sorted(entries) {
+		if e.blank && i > 0 {
+			f.buf = append(f.buf, '\n')
+		}
+		f.comments(e.comments, depth)
+		f.appendIndent(depth)
+		f.key(e)
+		f.value(e.value, depth)
+	}
+}
+
+func (f *formatter) sorted(entries []*entry) []*entry {
+	if !f.sort {
+		return entries
+	}
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, func(a, b *entry) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return entries
+}
+
+// key writes a key. Quoted keys containing dots stay quoted so that they
+// are not expanded as folded paths.
+func (f *formatter) key(e *entry) {
+	if e.quoted && strings.Contains(e.key, ".") {
+		f.buf = types.AppendString(f.buf, e.key)
+		return
+	}
+	f.buf = types.AppendKey(f.buf, e.key)
+}
+
+// value writes what follows the key of a field at depth.
+func (f *formatter) value(n *node, depth int) {
+	switch n.kind {
+	case primitiveNode:
+		f.buf = append(f.buf, ": "...)
+		f.buf = appendValue(f.buf, n.raw)
+		f.buf = append(f.buf, '\n')
+	case objectNode:
+		f.buf = append(f.buf, ":\n"...)
+		f.entries(n.entries, depth+1)
+	default:
+		f.array(n, depth)
+	}
+}
+
+func (f *formatter) array(n *node, depth int) {
+	count := len(n.values)
+	switch {
+	case n.columns != nil:
+		count = len(n.rows)
+	case n.list:
+		count = len(n.entries)
+	}
+	f.buf = append(f.buf, '[')
+	f.buf = strconv.AppendInt(f.buf, int64(count), 10)
+	if n.delim != ',' {
+		f.buf = append(f.buf, n.delim)
+	}
+	f.buf = append(f.buf, ']')
+
+	switch {
+	case n.columns != nil:
+		f.buf = append(f.buf, '{')
+		for i := range n.columns {
+			if i > 0 {
+				f.buf = append(f.buf, n.delim)
+			}
+			f.key(&n.columns[i])
+		}
+		f.buf = append(f.buf, "}:\n"...)
+		for _, r := range n.rows {
+			f.comments(r.comments, depth)
+			f.appendIndent(depth + 1)
+			f.values(r.cells, n.delim)
+			f.buf = append(f.buf, '\n')
+		}
+	case n.list:
+		f.buf = append(f.buf, ":\n"...)
+		for i, item := range n.entries {
+			if item.blank && i > 0 {
+				f.buf = append(f.buf, '\n')
+			}
+			f.item(item, depth+1)
+		}
+	case len(n.values) > 0:
+		f.buf = append(f.buf, ": "...)
+		f.values(n.values, n.delim)
+		f.buf = append(f.buf, '\n')
+	default:
+		f.buf = append(f.buf, ":\n"...)
+	}
+}
+
+func (f *formatter) values(values []string, delim byte) {
+	for i, value := range values {
+		if i > 0 {
+			f.buf = append(f.buf, delim)
+		}
+		f.buf = appendValue(f.buf, value)
+	}
+}
+
+// item writes a list item at depth the way the encoder does: the first
+// field of an object on the marker line and the rest one level deeper.
+func (f *formatter) item(e *entry, depth int) {
+	n := e.value
+	comments := e.comments
+	var fields []*entry
+	if n.kind == objectNode && len(n.entries) > 0 {
+		fields = f.sorted(n.entries)
+		comments = append(slices.Clone(comments), fields[0].comments...)
+	}
+
+	f.comments(comments, depth)
+	f.appendIndent(depth)
+	switch {
+	case n.kind == objectNode && len(fields) == 0:
+		f.buf = append(f.buf, "-\n"...)
+	case n.kind == objectNode:
+		f.buf = append(f.buf, "- "...)
+		f.key(fields[0])
+		f.value(fields[0].value, depth+1)
+		f.entries(fields[1:], depth+1)
+	case n.kind == arrayNode:
+		f.buf = append(f.buf, "- "...)
+		f.array(n, depth+1)
+	default:
+		f.buf = append(f.buf, "- "...)
+		f.buf = appendValue(f.buf, n.raw)
+		f.buf = append(f.buf, '\n')
+	}
+}
+
+// appendValue appends the canonical form of the primitive written as raw.
+// Empty tabular cells stay empty.
+func appendValue(dst []byte, raw string) []byte {
+	switch {
+	case raw == "", raw == "true", raw == "false", raw == "null":
+		return append(dst, raw...)
+	case raw[0] == '"' && len(raw) >= 2 && closingQuote(raw) == len(raw)-1:
+		return types.AppendString(dst, unquote(raw[1:len(raw)-1]))
+	}
+
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return strconv.AppendInt(dst, n, 10)
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	switch {
+	case err != nil:
+		// Decoders read anything else unquoted as a string.
+		return types.AppendString(dst, raw)
+	case isInteger(raw), math.IsInf(f, 0), math.IsNaN(f):
+		// Keep the digits a float64 cannot hold.
+		return append(dst, raw...)
+	default:
+		return types.AppendFloat(dst, f, 64)
+	}
+}
+
+// isInteger reports whether s is an optionally signed run of digits.
+func isInteger(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package decoder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  FormatOptions
+		input string
+		want  string
+	}{
+		{
+			name: "canonical values and counts",
+			input: `# Service configuration
+name: api server
+"port": 08080
+ratio: 1.50
+big: 123456789012345678901234
+"a.b": "x"
+a.b: 'y'
+tags[5]: red, "green",blue
+
+
+users[1]{id,"full name"}:
+    1,Ada Lovelace
+# the second user
+    2,"Linus"
+`,
+			want: `# Service configuration
+name: "api server"
+port: 8080
+ratio: 1.5
+big: 123456789012345678901234
+"a.b": "x"
+a.b: "'y'"
+tags[3]: "red","green","blue"
+
+users[2]{id,"full name"}:
+  1,"Ada Lovelace"
+# the second user
+  2,"Linus"
+`,
+		},
+		{
+			name: "nested objects and lists",
+			input: `server:
+    host:   "localhost"
+    # retries before giving up
+    limits:
+        retries: 3
+items[9]:
+    - 1
+    -
+    - id: 1
+        # the name
+        name: "a"
+        tags[1|]: x|y
+    - [2]{a,b}:
+            1,2
+            3,4
+# end
+`,
+			want: `server:
+  host: "localhost"
+  # retries before giving up
+  limits:
+    retries: 3
+items[4]:
+  - 1
+  -
+  - id: 1
+    # the name
+    name: "a"
+    tags[2|]: "x"|"y"
+  - [2]{a,b}:
+      1,2
+      3,4
+# end
+`,
+		},
+		{
+			name: "sort keys",
+			opts: FormatOptions{SortKeys: true, Indent: "    "},
+			input: `b: 1
+# about a
+a:
+  z: true
+  y: null
+rows[2]{z,a}:
+  1,2
+  3,4
+`,
+			want: `# about a
+a:
+    y: null
+    z: true
+b: 1
+rows[2]{z,a}:
+    1,2
+    3,4
+`,
+		},
+		{
+			name:  "root array",
+			input: "# numbers\n[3]: 1,2\n",
+			want:  "# numbers\n[2]: 1,2\n",
+		},
+		{
+			name:  "values starting with #",
+			input: "rows[3]{c}:\n  #fff\n  \"x\"\nitems[1]:\n  # a comment\n  - #fff\n",
+			want:  "rows[2]{c}:\n  \"#fff\"\n  \"x\"\nitems[1]:\n  # a comment\n  - \"#fff\"\n",
+		},
+		{
+			name:  "root primitive",
+			input: "\n  hello\n",
+			want:  "\"hello\"\n",
+		},
+		{
+			name:  "empty cells",
+			input: "rows[2\t]{a\tb}:\n  1\t\n  \t2\n",
+			want:  "rows[2\t]{a\tb}:\n  1\t\n  \t2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.input), tt.opts)
+			if err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, got)
+			}
+
+			again, err := Format(got, tt.opts)
+			if err != nil || string(again) != string(got) {
+				t.Errorf("Expected formatting to be idempotent, got:\n%s (%v)", again, err)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  SyntaxError
+	}{
+		{"indentation", "a: 1\n   b: 2", SyntaxError{Line: 2, Column: 4, Msg: "unexpected indentation"}},
+		{"row length", "rows[1]{a,b}:\n  1", SyntaxError{Line: 2, Column: 3, Msg: "row has 1 fields, header has 2"}},
+		{"list item", "items[1]:\n  x: 1", SyntaxError{Line: 2, Column: 3, Msg: `expected list item, got "x: 1"`}},
+		{"missing key", "a: 1\nplain text", SyntaxError{Line: 2, Column: 1, Msg: "expected a key"}},
+		{"key dictionary", "%keys\n  @n: \"name\"\n@n: 1", SyntaxError{Line: 1, Column: 1, Msg: "document uses a key dictionary; decode it with Options.KeyDictionary"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Format([]byte(tt.input), FormatOptions{})
+			var serr *SyntaxError
+			if !errors.As(err, &serr) {
+				t.Fatalf("Expected a *SyntaxError, got %v", err)
+			}
+			if *serr != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *serr)
+			}
+		})
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	input := `# header: ignored
+user:
+  # note: also ignored
+  name: "Ada"
+  tags[2]:
+    # first
+    - "a"
+    - "b"`
+
+	result, err := NewParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	user := result.(map[string]interface{})["user"].(map[string]interface{})
+	if len(user) != 2 || user["name"] != "Ada" || len(user["tags"].([]interface{})) != 2 {
+		t.Errorf("Expected comments to be skipped, got %v", user)
+	}
+}
+
+func TestParseRowsStartingWithHash(t *testing.T) {
+	input := "rows[3]{c,n}:\n  #fff,1\n# not a row\n  \"x\",2\n  #000,3"
+
+	result, err := NewParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	rows := result.(map[string]interface{})["rows"].([]interface{})
+	if len(rows) != 3 || rows[0].(map[string]interface{})["c"] != "#fff" || rows[2].(map[string]interface{})["c"] != "#000" {
+		t.Errorf("Expected rows starting with # to be read as data, got %v", rows)
+	}
+}
@@ -0,0 +1,257 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// ToJSON converts a TOON document to compact JSON, reading the text of the
+// document rather than decoded values: keys keep their order, and numbers
+// keep the text they were written with, so 1.50 stays 1.50 and integers
+// too long for a float64 keep every digit. Other values follow
+// Parser: unquoted text is a string, an empty document is an empty object,
+// folded keys are not expanded, and of duplicate keys the last one wins, in
+// the position of the first. Documents that Parser rejects, including
+// those using a key dictionary, are rejected too.
+func ToJSON(data []byte) ([]byte, error) {
+	return ToJSONWithOptions(data, Options{})
+}
+
+// ToJSONWithOptions is like ToJSON but applies the options of Parser that
+// change the decoded values: ExpandPaths nests folded keys in an object
+// placed where its first key was, and DropAbsent leaves out the fields of
+// empty tabular cells. Other options are ignored; run Repair first to read
+// malformed documents.
+func ToJSONWithOptions(data []byte, opts Options) ([]byte, error) {
+	// The tree rejects what Parser rejects, such as counts that do not
+	// match their arrays, so the document is read once.
+	t := newTreeParser(data)
+	doc, err := t.document()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &jsonWriter{t: t, expand: opts.ExpandPaths, dropAbsent: opts.DropAbsent}
+	w.enc = json.NewEncoder(&w.buf)
+	w.enc.SetEscapeHTML(false)
+	if doc.root == nil {
+		w.buf.WriteString("{}")
+	} else if err := w.value(doc.root); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+type jsonWriter struct {
+	buf        bytes.Buffer
+	enc        *json.Encoder
+	t          *treeParser
+	expand     bool
+	dropAbsent bool
+	// paths holds the folded key that each entry made by expanding one was
+	// read from.
+	paths map[*entry]string
+}
+
+func (w *jsonWriter) value(n *node) error {
+	switch n.kind {
+	case primitiveNode:
+		w.primitive(n.raw)
+		return nil
+	case objectNode:
+		return w.object(n.entries)
+	default:
+		return w.array(n)
+	}
+}
+
+func (w *jsonWriter) object(entries []*entry) error {
+	fields, err := w.fields(entries)
+	if err != nil {
+		return err
+	}
+
+	w.buf.WriteByte('{')
+	for i, e := range fields {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.string(e.key)
+		w.buf.WriteByte(':')
+		if err := w.value(e.value); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
+
+// fields returns the fields of an object in the order they are written.
+// Later duplicates replace the value of the first. With ExpandPaths a
+// folded key becomes a field named by its first segment, holding an object
+// with the rest of the path, and paths with the same first segment share
+// that object; as in Parser, a path through a value that is not an object,
+// or ending on a field another path set, is an error unless both are
+// objects.
+func (w *jsonWriter) fields(entries []*entry) ([]*entry, error) {
+	index := make(map[string]int, len(entries))
+	fields := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		key, rest, folded := e.key, "", false
+		if w.expand && !e.quoted && isFoldedPath(e.key) {
+			key, rest, _ = strings.Cut(e.key, ".")
+			folded = true
+		}
+
+		i, ok := index[key]
+		switch {
+		case !ok:
+			index[key] = len(fields)
+			if folded {
+				e = &entry{line: e.line, key: key, value: &node{
+					kind:    objectNode,
+					line:    e.line,
+					entries: []*entry{w.child(e, rest, w.path(e))},
+				}}
+			}
+			fields = append(fields, e)
+		case folded:
+			if fields[i].value.kind != objectNode {
+				return nil, w.errorAt(e, "path %q conflicts with key %q", w.path(e), key)
+			}
+			fields[i] = w.extend(fields[i], w.child(e, rest, w.path(e)))
+		case w.paths[e] != "":
+			prev := fields[i]
+			if prev.value.kind != objectNode || e.value.kind != objectNode {
+				return nil, w.errorAt(e, "duplicate key %q", w.path(e))
+			}
+			children := make([]*entry, len(e.value.entries))
+			for j, c := range e.value.entries {
+				children[j] = w.child(c, c.key, w.path(e)+"."+c.key)
+			}
+			fields[i] = w.extend(prev, children...)
+		default:
+			fields[i] = e
+		}
+	}
+	return fields, nil
+}
+
+// child returns an entry for the value of e under key, read from the folded
+// key path.
+func (w *jsonWriter) child(e *entry, key, path string) *entry {
+	c := &entry{line: e.line, key: key, quoted: e.quoted && key == e.key, value: e.value}
+	if w.paths == nil {
+		w.paths = make(map[*entry]string)
+	}
+	w.paths[c] = path
+	return c
+}
+
+// extend returns a copy of the object field e with children added to its
+// fields, leaving the tree unchanged.
+func (w *jsonWriter) extend(e *entry, children ...*entry) *entry {
+	n := *e.value
+	n.entries = append(n.entries[:len(n.entries):len(n.entries)], children...)
+	c := *e
+	c.value = &n
+	if path, ok := w.paths[e]; ok {
+		w.paths[&c] = path
+	}
+	return &c
+}
+
+// path returns the key e was read from.
+func (w *jsonWriter) path(e *entry) string {
+	if path, ok := w.paths[e]; ok {
+		return path
+	}
+	return e.key
+}
+
+func (w *jsonWriter) errorAt(e *entry, format string, args ...interface{}) error {
+	return w.t.errorAt(e.line-1, format, args...)
+}
+
+func (w *jsonWriter) array(n *node) error {
+	w.buf.WriteByte('[')
+	switch {
+	case n.columns != nil:
+		for i, r := range n.rows {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			entries := make([]*entry, 0, len(r.cells))
+			for j, cell := range r.cells {
+				if w.dropAbsent && cell == "" {
+					continue
+				}
+				column := n.columns[j]
+				entries = append(entries, &entry{
+					line:   r.line,
+					key:    column.key,
+					quoted: column.quoted,
+					value:  &node{kind: primitiveNode, line: r.line, raw: cell},
+				})
+			}
+			if err := w.object(entries); err != nil {
+				return err
+			}
+		}
+	case n.list:
+		for i, item := range n.entries {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.value(item.value); err != nil {
+				return err
+			}
+		}
+	default:
+		for i, value := range n.values {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			w.primitive(value)
+		}
+	}
+	w.buf.WriteByte(']')
+	return nil
+}
+
+// primitive writes the primitive written as raw, classified as by
+// parsePrimitive.
+func (w *jsonWriter) primitive(raw string) {
+	switch {
+	case raw == "true", raw == "false", raw == "null":
+		w.buf.WriteString(raw)
+		return
+	case len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"':
+		w.string(unquote(raw[1 : len(raw)-1]))
+		return
+	}
+
+	if types.IsNumber(raw) {
+		w.buf.WriteString(raw)
+		return
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		// Numbers such as +1 or .5 that JSON does not allow as written.
+		w.buf.Write(types.AppendFloat(nil, f, 64))
+		return
+	}
+	// Parser reads what is not a number as a string, and JSON has no
+	// infinities.
+	w.string(raw)
+}
+
+func (w *jsonWriter) string(s string) {
+	// Encode only fails for unsupported types, and ends with a newline.
+	w.enc.Encode(s)
+	w.buf.Truncate(w.buf.Len() - 1)
+}
@@ -0,0 +1,124 @@
+package decoder
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "key order and number text",
+			input: "zeta: 1.50\nalpha: 12345678901234567890\nmid:\n  b: +1\n  a: .5\n",
+			want:  `{"zeta":1.50,"alpha":12345678901234567890,"mid":{"b":1,"a":0.5}}`,
+		},
+		{
+			name:  "tabular, inline and list arrays",
+			input: "rows[2|]{name|id}:\n  \"<Ada>\"|1e3\n  |2\ntags[2]: x,\"y\"\nitems[3]:\n  - k: 1\n    j: null\n  - [1]: true\n  -\n",
+			want:  `{"rows":[{"name":"<Ada>","id":1e3},{"name":"","id":2}],"tags":["x","y"],"items":[{"k":1,"j":null},[true],{}]}`,
+		},
+		{
+			name:  "strings",
+			input: "a: hello world\nb: Inf\nc: \"tab\\there\"\nd: 007\n",
+			want:  `{"a":"hello world","b":"Inf","c":"tab\there","d":7}`,
+		},
+		{
+			name:  "duplicate keys",
+			input: "a: 1\nb: 2\na: 3\n",
+			want:  `{"a":3,"b":2}`,
+		},
+		{name: "root array", input: "[2]: 1,2\n", want: `[1,2]`},
+		{name: "root primitive", input: "42\n", want: `42`},
+		{name: "empty document", input: "\n", want: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ToJSON failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if !json.Valid(got) {
+				t.Errorf("Output is not valid JSON: %s", got)
+			}
+		})
+	}
+
+	for _, input := range []string{
+		"a[3]: 1,2\n",
+		"rows[2]{c}:\n  #fff\n",
+		"items[2]:\n  - 1\n",
+		"%keys\n  @n: \"name\"\n@n: 1\n",
+	} {
+		_, err := ToJSON([]byte(input))
+		if _, perr := NewParser(strings.NewReader(input)).Parse(); err == nil || perr == nil {
+			t.Errorf("%q: expected ToJSON and Parser to fail, got %v and %v", input, err, perr)
+		}
+	}
+}
+
+func TestToJSONWithOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		input string
+		want  string
+	}{
+		{
+			name:  "expand paths in order",
+			opts:  Options{ExpandPaths: true},
+			input: "z: 0\na.b.c: 1\n\"x.y\": 2\na.b.d[2]: 1,2\na.e: 3\n",
+			want:  `{"z":0,"a":{"b":{"c":1,"d":[1,2]},"e":3},"x.y":2}`,
+		},
+		{
+			name:  "merge into objects",
+			opts:  Options{ExpandPaths: true},
+			input: "a:\n  x: 1\na.y: 2\nb.c:\n  p: 1\nb.c:\n  q: 2\n",
+			want:  `{"a":{"x":1,"y":2},"b":{"c":{"p":1,"q":2}}}`,
+		},
+		{
+			name:  "dotted columns",
+			opts:  Options{ExpandPaths: true},
+			input: "rows[2]{id,c.id,c.name,\"a.b\"}:\n  1,7,Ada,x\n  2,8,Linus,y\n",
+			want:  `{"rows":[{"id":1,"c":{"id":7,"name":"Ada"},"a.b":"x"},{"id":2,"c":{"id":8,"name":"Linus"},"a.b":"y"}]}`,
+		},
+		{
+			name:  "drop absent",
+			opts:  Options{DropAbsent: true},
+			input: "[2\t]{a\tb}:\n  \t1\n  2\t\"\"\n",
+			want:  `[{"b":1},{"a":2,"b":""}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSONWithOptions([]byte(tt.input), tt.opts)
+			if err != nil {
+				t.Fatalf("ToJSONWithOptions failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	for _, input := range []string{
+		"a: 1\na.b: 2\n",
+		"a.b: 1\na.b: 2\n",
+		"a:\n  b: 1\na.b: 2\n",
+	} {
+		_, err := ToJSONWithOptions([]byte(input), Options{ExpandPaths: true})
+		_, perr := NewParserWithOptions(strings.NewReader(input), Options{ExpandPaths: true}).Parse()
+		if err == nil || perr == nil || err.Error() != perr.Error() {
+			t.Errorf("%q: expected the errors of Parser, got %v and %v", input, err, perr)
+		}
+	}
+}
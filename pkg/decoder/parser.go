@@ -8,12 +8,37 @@ import (
 	"strings"
 )
 
+// Options controls optional decoding behaviour.
+type Options struct {
+	// ExpandPaths rebuilds the nesting of folded keys: an unquoted key such
+	// as "a.b.c" whose segments are all identifiers is decoded as nested
+	// objects. Dotted tabular columns such as "customer.id" are rebuilt the
+	// same way in every row. Quoted keys are always kept literally.
+	ExpandPaths bool
+	// Lenient runs Repair over the input before parsing, so that documents
+	// with wrong counts, code fences, surrounding prose or inconsistent
+	// indentation still decode. The applied fixes are available from
+	// Parser.Fixes.
+	Lenient bool
+	// KeyDictionary accepts documents that start with the "%keys" block
+	// written by the encoder's KeyDictionary option and expands the aliases
+	// it declares. Without it such documents are rejected.
+	KeyDictionary bool
+	// DropAbsent leaves the keys of empty tabular cells, such as the middle
+	// one of "3,,true", out of the row objects. The encoder writes such
+	// cells for keys missing from an object with its MarkAbsent option.
+	// Without it empty cells are read as empty strings.
+	DropAbsent bool
+}
+
 type Parser struct {
 	scanner  *bufio.Scanner
 	lineNum  int
 	lines    []string
 	linePos  int
 	hasLines bool
+	opts     Options
+	fixes    []Fix
 }
 
 func NewParser(r io.Reader) *Parser {
@@ -23,6 +48,19 @@ func NewParser(r io.Reader) *Parser {
 	}
 }
 
+// NewParserWithOptions returns a parser that applies opts.
+func NewParserWithOptions(r io.Reader, opts Options) *Parser {
+	p := NewParser(r)
+	p.opts = opts
+	return p
+}
+
+// Fixes returns the repairs applied by the last call to Parse in lenient
+// mode.
+func (p *Parser) Fixes() []Fix {
+	return p.fixes
+}
+
 func (p *Parser) Parse() (interface{}, error) {
 	var lines []string
 	for p.scanner.Scan() {
@@ -33,27 +71,97 @@ func (p *Parser) Parse() (interface{}, error) {
 		return nil, err
 	}
 
+	// Comment lines are blanked out so that line numbers stay accurate.
+	for i, comment := range commentLines(lines) {
+		if comment {
+			lines[i] = ""
+		}
+	}
+
+	if p.opts.Lenient {
+		repaired, fixes, err := Repair([]byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return nil, err
+		}
+		p.fixes = fixes
+		lines = strings.Split(strings.TrimSuffix(string(repaired), "\n"), "\n")
+	}
+
+	lines, err := p.expandKeys(lines)
+	if err != nil {
+		return nil, err
+	}
+
 	p.lines = lines
 	p.linePos = 0
 	p.hasLines = true
 
-	// Check if this is a root-level tabular array (starts with [count]{fields}:)
-	if len(lines) > 0 {
-		firstLine := strings.TrimSpace(lines[0])
-		if strings.HasPrefix(firstLine, "[") && strings.Contains(firstLine, "}:") {
-			// This is a root-level tabular array
-			array, err := p.parseRootTabularArray()
+	// Check if this is a root-level array (starts with [count])
+	firstIndex := p.firstContentLine()
+	if firstIndex >= 0 {
+		firstLine := strings.TrimSpace(lines[firstIndex])
+		if strings.HasPrefix(firstLine, "[") {
+			h, err := parseArrayHeader(firstLine)
+			if err != nil {
+				return nil, p.syntaxError(firstIndex+1, err)
+			}
+			if h.fields != nil && firstIndex == 0 {
+				// This is a root-level tabular array
+				array, err := p.parseRootTabularArray()
+				if err != nil {
+					return nil, err
+				}
+				return array, nil
+			}
+
+			p.linePos = firstIndex + 1
+			_, array, err := p.parseArrayField(firstLine, p.indentOf(lines[firstIndex]))
 			if err != nil {
-				return nil, err
+				return nil, p.syntaxError(firstIndex+1, err)
 			}
 			return array, nil
 		}
+
+		if p.isSingleValue(firstIndex, firstLine) {
+			return p.parsePrimitive(firstLine), nil
+		}
 	}
 
 	minIndent := p.findMinIndent()
 
 	return p.parseObject(minIndent)
 }
+
+// firstContentLine returns the index of the first non-blank line, or -1.
+func (p *Parser) firstContentLine() int {
+	for i, line := range p.lines {
+		if strings.TrimSpace(line) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// isSingleValue reports whether the document is a lone primitive such as
+// "hello" or 42 rather than an object.
+func (p *Parser) isSingleValue(index int, content string) bool {
+	for _, line := range p.lines[index+1:] {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	_, _, ok := splitKey(content)
+	return !ok
+}
+
+func (p *Parser) indentOf(line string) int {
+	indent := 0
+	for indent < len(line) && line[indent] == ' ' {
+		indent++
+	}
+	return indent
+}
+
 func (p *Parser) findMinIndent() int {
 	minIndent := -1
 	for _, line := range p.lines {
@@ -109,53 +217,123 @@ func (p *Parser) parseObject(currentIndent int) (interface{}, error) {
 			continue
 		}
 
-		if strings.Contains(content, "[") && strings.Contains(content, "}:") {
-			name, array, err := p.parseTabularArray(content, indent)
-			if err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.linePos, err)
-			}
-			obj[name] = array
+		_, rest, ok := splitKey(content)
+		if !ok {
 			continue
 		}
 
-		if strings.Contains(content, "[") && strings.HasSuffix(content, ":") && !strings.Contains(content, "{") {
-			name, array, err := p.parseRegularArray(content, indent)
+		keyLine := p.linePos
+		if strings.HasPrefix(rest, "[") {
+			name, array, err := p.parseArrayField(content, indent)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.linePos, err)
+				return nil, p.syntaxError(keyLine, err)
+			}
+			if err := p.setField(obj, name, array, content); err != nil {
+				return nil, p.syntaxError(keyLine, err)
 			}
-			obj[name] = array
 			continue
 		}
 
-		if strings.Contains(content, ":") {
-			name, value, err := p.parseKeyValue(content, indent)
-			if err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.linePos, err)
-			}
-			obj[name] = value
-			continue
+		name, value, err := p.parseKeyValue(content, indent)
+		if err != nil {
+			return nil, p.syntaxError(keyLine, err)
+		}
+		if err := p.setField(obj, name, value, content); err != nil {
+			return nil, p.syntaxError(keyLine, err)
 		}
 	}
 
 	return obj, nil
 }
 
+// setField stores value under key in obj, expanding folded paths when
+// enabled. content is the line the key was read from.
+func (p *Parser) setField(obj map[string]interface{}, key string, value interface{}, content string) error {
+	if !p.opts.ExpandPaths || strings.HasPrefix(content, "\"") || !isFoldedPath(key) {
+		obj[key] = value
+		return nil
+	}
+
+	segments := strings.Split(key, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		switch next := obj[segment].(type) {
+		case nil:
+			if _, exists := obj[segment]; exists {
+				return fmt.Errorf("path %q conflicts with key %q", key, segment)
+			}
+			child := make(map[string]interface{})
+			obj[segment] = child
+			obj = child
+		case map[string]interface{}:
+			obj = next
+		default:
+			return fmt.Errorf("path %q conflicts with key %q", key, segment)
+		}
+	}
+	return mergeField(obj, segments[len(segments)-1], value, key)
+}
+
+// mergeField stores value under key, merging objects that were already
+// created by other folded paths.
+func mergeField(obj map[string]interface{}, key string, value interface{}, path string) error {
+	existing, exists := obj[key]
+	if !exists {
+		obj[key] = value
+		return nil
+	}
+
+	dst, ok1 := existing.(map[string]interface{})
+	src, ok2 := value.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return fmt.Errorf("duplicate key %q", path)
+	}
+	for k, v := range src {
+		if err := mergeField(dst, k, v, path+"."+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isFoldedPath reports whether key is a dotted path of identifiers.
+func isFoldedPath(key string) bool {
+	if !strings.Contains(key, ".") {
+		return false
+	}
+	for _, segment := range strings.Split(key, ".") {
+		if segment == "" {
+			return false
+		}
+		for i := 0; i < len(segment); i++ {
+			c := segment[i]
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+			case c >= '0' && c <= '9' && i > 0:
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (p *Parser) parseKeyValue(line string, indent int) (string, interface{}, error) {
-	parts := strings.SplitN(line, ":", 2)
-	if len(parts) != 2 {
+	key, rest, ok := splitKey(line)
+	if !ok || !strings.HasPrefix(rest, ":") {
 		return "", nil, fmt.Errorf("invalid key-value format")
 	}
 
-	key := strings.TrimSpace(parts[0])
-	valueStr := strings.TrimSpace(parts[1])
+	valueStr := strings.TrimSpace(rest[1:])
 
-	if valueStr == "" && p.linePos < len(p.lines) {
-		if p.linePos < len(p.lines) {
-			nextLine := p.lines[p.linePos]
-			nextIndent := 0
-			for nextIndent < len(nextLine) && nextLine[nextIndent] == ' ' {
-				nextIndent++
-			}
+	if valueStr == "" {
+		// Blank and comment lines may separate the key from its fields.
+		next := p.linePos
+		for next < len(p.lines) && strings.TrimSpace(p.lines[next]) == "" {
+			next++
+		}
+		if next < len(p.lines) {
+			nextLine := p.lines[next]
+			nextIndent := p.indentOf(nextLine)
 
 			if nextIndent > indent {
 				nestedObj, err := p.parseObject(nextIndent)
@@ -165,56 +343,37 @@ func (p *Parser) parseKeyValue(line string, indent int) (string, interface{}, er
 				return key, nestedObj, nil
 			}
 		}
+		// A key with nothing nested below it is an empty object.
+		return key, map[string]interface{}{}, nil
 	}
 
 	value := p.parsePrimitive(valueStr)
 	return key, value, nil
 }
 
-func (p *Parser) parseTabularArray(header string, indent int) (string, []interface{}, error) {
-	colonIndex := strings.Index(header, "}:")
-	if colonIndex == -1 {
-		return "", nil, fmt.Errorf("invalid tabular array format")
-	}
-
-	headerPart := header[:colonIndex+1]
-
-	nameEnd := strings.Index(headerPart, "[")
-	if nameEnd == -1 {
-		return "", nil, fmt.Errorf("invalid tabular array format: missing count")
-	}
-
-	name := strings.TrimSpace(headerPart[:nameEnd])
-
-	countStart := nameEnd + 1
-	countEnd := strings.Index(headerPart, "]")
-	if countEnd == -1 {
-		return "", nil, fmt.Errorf("invalid tabular array format: missing closing bracket")
-	}
-
-	countStr := headerPart[countStart:countEnd]
-	countStr = strings.TrimRight(countStr, ",") // Remove vírgula se existir
-	count, err := strconv.Atoi(countStr)
+// parseArrayField parses an array whose header line has already been
+// consumed, dispatching on the header form: "key[N]{fields}:" for tabular
+// rows, "key[N]: a,b" for inline primitives and "key[N]:" for list items.
+func (p *Parser) parseArrayField(header string, indent int) (string, []interface{}, error) {
+	h, err := parseArrayHeader(header)
 	if err != nil {
-		return "", nil, fmt.Errorf("invalid count: %v", err)
-	}
-
-	fieldsStart := countEnd + 2 // Skip ']{'
-	if fieldsStart >= len(headerPart) || headerPart[fieldsStart-1] != '{' {
-		return "", nil, fmt.Errorf("invalid tabular array format: missing fields")
+		return "", nil, err
 	}
-
-	fieldsStr := headerPart[fieldsStart : len(headerPart)-1] // Exclude '}'
-	fields := strings.Split(fieldsStr, ",")
-	for i := range fields {
-		fields[i] = strings.TrimSpace(fields[i])
+	if h.fields != nil {
+		return p.parseTabularArray(h, indent)
 	}
+	return p.parseRegularArray(h, indent)
+}
 
-	var rows [][]string
-	rowCount := 0
+func (p *Parser) parseTabularArray(h arrayHeader, indent int) (string, []interface{}, error) {
+	array := make([]interface{}, 0, h.count)
 
-	for p.linePos < len(p.lines) && rowCount < count {
+	for p.linePos < len(p.lines) && len(array) < h.count {
 		rowLine := p.lines[p.linePos]
+		if strings.Trim(rowLine, " \r") == "" {
+			p.linePos++
+			continue
+		}
 
 		rowIndent := 0
 		for rowIndent < len(rowLine) && rowLine[rowIndent] == ' ' {
@@ -226,109 +385,223 @@ func (p *Parser) parseTabularArray(header string, indent int) (string, []interfa
 		}
 
 		dataContent := strings.TrimSpace(rowLine[rowIndent:])
+		if h.delim == '\t' {
+			// Keep the tabs around empty cells at the ends of the row.
+			dataContent = strings.Trim(rowLine[rowIndent:], " \r")
+		}
 		if dataContent == "" {
 			p.linePos++
 			continue
 		}
 
-		values := splitTabularValues(dataContent)
-
-		if len(values) != len(fields) {
-			return "", nil, fmt.Errorf("row %d: field count mismatch (expected %d, got %d)",
-				rowCount+1, len(fields), len(values))
+		obj, err := p.parseTabularRow(dataContent, h, len(array))
+		if err != nil {
+			return "", nil, p.syntaxError(p.linePos+1, err)
 		}
 
-		rows = append(rows, values)
-		rowCount++
+		array = append(array, obj)
 		p.linePos++
 	}
 
-	if rowCount != count {
-		return name, nil, fmt.Errorf("array count mismatch: declared %d, found %d", count, rowCount)
+	if len(array) != h.count {
+		return h.name, nil, fmt.Errorf("array count mismatch: declared %d, found %d", h.count, len(array))
 	}
 
-	array := make([]interface{}, len(rows))
-	for i, row := range rows {
-		obj := make(map[string]interface{})
-		for j, field := range fields {
-			obj[field] = p.parsePrimitive(row[j])
-		}
-		array[i] = obj
-	}
-
-	return name, array, nil
+	return h.name, array, nil
 }
-func (p *Parser) parseRegularArray(header string, indent int) (string, []interface{}, error) {
-	colonIndex := strings.Index(header, ":")
-	if colonIndex == -1 {
-		return "", nil, fmt.Errorf("invalid regular array format")
-	}
 
-	headerPart := strings.TrimSpace(header[:colonIndex])
+// arrayHeader is a parsed array header such as "users[2|]{id|name}:".
+type arrayHeader struct {
+	name  string
+	count int
+	// fields is nil unless the array is tabular.
+	fields []string
+	// quoted marks the fields written as quoted strings, which are never
+	// expanded as dotted paths.
+	quoted []bool
+	// inline holds the values after the colon of an inline primitive array.
+	inline string
+	// delim separates fields, rows and inline values: ',' unless the count
+	// is followed by a tab or '|' marker.
+	delim byte
+}
 
-	nameEnd := strings.Index(headerPart, "[")
-	if nameEnd == -1 {
-		return "", nil, fmt.Errorf("invalid regular array format: missing count")
+// parseArrayHeader splits any array header into its parts. Root-level
+// headers have an empty name.
+func parseArrayHeader(header string) (arrayHeader, error) {
+	name, rest, ok := splitKey(header)
+	if !ok || !strings.HasPrefix(rest, "[") {
+		return arrayHeader{}, fmt.Errorf("invalid array format: missing count")
 	}
 
-	name := strings.TrimSpace(headerPart[:nameEnd])
-
-	countStart := nameEnd + 1
-	countEnd := strings.Index(headerPart, "]")
+	countEnd := strings.Index(rest, "]")
 	if countEnd == -1 {
-		return "", nil, fmt.Errorf("invalid regular array format: missing closing bracket")
+		return arrayHeader{}, fmt.Errorf("invalid array format: missing closing bracket")
 	}
 
-	countStr := headerPart[countStart:countEnd]
+	h := arrayHeader{name: name, delim: ','}
+
+	countStr := rest[1:countEnd]
+	if n := len(countStr); n > 0 {
+		switch countStr[n-1] {
+		case '\t', '|':
+			h.delim = countStr[n-1]
+			countStr = countStr[:n-1]
+		case ',':
+			countStr = countStr[:n-1] // Remove vírgula se existir
+		}
+	}
 	count, err := strconv.Atoi(countStr)
 	if err != nil {
-		return "", nil, fmt.Errorf("invalid count: %v", err)
+		return arrayHeader{}, fmt.Errorf("invalid count: %v", err)
+	}
+	if count < 0 {
+		return arrayHeader{}, fmt.Errorf("invalid count: %d", count)
+	}
+	h.count = count
+
+	rest = rest[countEnd+1:]
+
+	if strings.HasPrefix(rest, "{") {
+		fieldsEnd := strings.Index(rest, "}")
+		if fieldsEnd == -1 {
+			return arrayHeader{}, fmt.Errorf("invalid tabular array format: missing fields")
+		}
+		h.fields = splitTabularValues(rest[1:fieldsEnd], h.delim)
+		h.quoted = make([]bool, len(h.fields))
+		for i := range h.fields {
+			h.quoted[i] = strings.HasPrefix(h.fields[i], "\"")
+			h.fields[i] = unquoteKey(h.fields[i])
+		}
+		rest = rest[fieldsEnd+1:]
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return arrayHeader{}, fmt.Errorf("invalid array format: missing colon")
+	}
+
+	h.inline = strings.TrimSpace(rest[1:])
+	return h, nil
+}
+
+// parseTabularRow decodes one data line of a tabular array straight into an
+// object keyed by the header fields.
+func (p *Parser) parseTabularRow(line string, h arrayHeader, index int) (map[string]interface{}, error) {
+	fields := h.fields
+	values := splitTabularValues(line, h.delim)
+
+	if len(values) != len(fields) {
+		return nil, fmt.Errorf("row %d: field count mismatch (expected %d, got %d)",
+			index+1, len(fields), len(values))
 	}
 
-	valuesStr := strings.TrimSpace(header[colonIndex+1:])
+	obj := make(map[string]interface{}, len(fields))
+	for j, field := range fields {
+		if p.opts.DropAbsent && values[j] == "" {
+			continue
+		}
+		if !p.opts.ExpandPaths || h.quoted[j] {
+			obj[field] = p.parsePrimitive(values[j])
+			continue
+		}
+		// Dotted columns rebuild the nested objects they were flattened from.
+		if err := p.setField(obj, field, p.parsePrimitive(values[j]), field); err != nil {
+			return nil, fmt.Errorf("row %d: %v", index+1, err)
+		}
+	}
+	return obj, nil
+}
+
+func (p *Parser) parseRegularArray(h arrayHeader, indent int) (string, []interface{}, error) {
+	name, count := h.name, h.count
 
 	var values []interface{}
+	var err error
 
-	if valuesStr != "" {
-		valueStrings := strings.Fields(valuesStr)
-		for _, valueStr := range valueStrings {
-			trimmed := strings.TrimSpace(valueStr)
-			values = append(values, p.parsePrimitive(trimmed))
+	if h.inline != "" {
+		for _, valueStr := range splitTabularValues(h.inline, h.delim) {
+			values = append(values, p.parsePrimitive(valueStr))
 		}
 	} else {
-		valueCount := 0
-		for p.linePos < len(p.lines) && valueCount < count {
-			rowLine := p.lines[p.linePos]
+		values, err = p.parseListItems(count, indent)
+		if err != nil {
+			return name, nil, err
+		}
+	}
 
-			rowIndent := 0
-			for rowIndent < len(rowLine) && rowLine[rowIndent] == ' ' {
-				rowIndent++
-			}
+	if count != len(values) {
+		return "", nil, fmt.Errorf("array count mismatch: declared %d, found %d", count, len(values))
+	}
 
-			if rowIndent <= indent {
-				break
-			}
+	if values == nil {
+		values = []interface{}{}
+	}
+	return name, values, nil
+}
+
+// parseListItems reads up to count "- " items indented below a list header.
+func (p *Parser) parseListItems(count int, headerIndent int) ([]interface{}, error) {
+	var items []interface{}
 
-			valueCount++
+	for p.linePos < len(p.lines) && len(items) < count {
+		line := p.lines[p.linePos]
+		content := strings.TrimSpace(line)
+		if content == "" {
 			p.linePos++
+			continue
+		}
+
+		itemIndent := p.indentOf(line)
+		if itemIndent <= headerIndent {
+			break
 		}
 
-		if valueCount != count {
-			return name, nil, fmt.Errorf("array count mismatch: declared %d, found %d", count, valueCount)
+		if content != "-" && !strings.HasPrefix(content, "- ") {
+			return nil, p.syntaxError(p.linePos+1, fmt.Errorf("expected list item, got %q", content))
 		}
+
+		item, err := p.parseListItem(content, itemIndent, itemIndent-headerIndent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
 	}
 
-	if count != len(values) {
-		return "", nil, fmt.Errorf("array count mismatch: declared %d, found %d", count, len(values))
+	return items, nil
+}
+
+// parseListItem parses the item starting on the current line. Content after
+// the marker is treated as if it started one indentation unit to the right of
+// the marker, which is where the remaining fields of an object item live.
+func (p *Parser) parseListItem(content string, itemIndent int, unit int) (interface{}, error) {
+	if content == "-" {
+		p.linePos++
+		return map[string]interface{}{}, nil
 	}
 
-	return name, values, nil
+	body := strings.TrimSpace(content[2:])
+	bodyIndent := itemIndent + unit
+
+	if strings.HasPrefix(body, "[") {
+		p.linePos++
+		_, array, err := p.parseArrayField(body, bodyIndent)
+		return array, err
+	}
+
+	if _, _, ok := splitKey(body); ok {
+		p.lines[p.linePos] = strings.Repeat(" ", bodyIndent) + body
+		return p.parseObject(bodyIndent)
+	}
+
+	p.linePos++
+	return p.parsePrimitive(body), nil
 }
+
 func (p *Parser) parsePrimitive(value string) interface{} {
 	trimmed := strings.TrimSpace(value)
 
 	if len(trimmed) >= 2 && trimmed[0] == '"' && trimmed[len(trimmed)-1] == '"' {
-		return trimmed[1 : len(trimmed)-1]
+		return unquote(trimmed[1 : len(trimmed)-1])
 	}
 
 	if trimmed == "true" {
@@ -359,47 +632,23 @@ func (p *Parser) parseRootTabularArray() ([]interface{}, error) {
 	}
 
 	// Parse the header line: [3]{Name,Age,Email,Active}:
-	headerLine := strings.TrimSpace(p.lines[0])
-	colonIndex := strings.Index(headerLine, "}:")
-	if colonIndex == -1 {
-		return nil, fmt.Errorf("invalid tabular array format")
-	}
-
-	headerPart := headerLine[:colonIndex+1]
-
-	// Extract count [3]
-	countStart := 1 // Skip '['
-	countEnd := strings.Index(headerPart, "]")
-	if countEnd == -1 {
-		return nil, fmt.Errorf("invalid tabular array format: missing closing bracket")
-	}
-
-	countStr := headerPart[countStart:countEnd]
-	count, err := strconv.Atoi(countStr)
+	h, err := parseArrayHeader(strings.TrimSpace(p.lines[0]))
 	if err != nil {
-		return nil, fmt.Errorf("invalid count: %v", err)
-	}
-
-	// Extract fields {Name,Age,Email,Active}
-	fieldsStart := countEnd + 2 // Skip ']{'
-	if fieldsStart >= len(headerPart) || headerPart[fieldsStart-1] != '{' {
-		return nil, fmt.Errorf("invalid tabular array format: missing fields")
-	}
-
-	fieldsStr := headerPart[fieldsStart : len(headerPart)-1] // Exclude '}'
-	fields := strings.Split(fieldsStr, ",")
-	for i := range fields {
-		fields[i] = strings.TrimSpace(fields[i])
+		return nil, p.syntaxError(1, err)
 	}
+	count := h.count
 
 	// Parse rows starting from line 1 (after header)
-	var rows [][]string
-	rowCount := 0
+	array := make([]interface{}, 0, count)
 	lineIndex := 1 // Start after header line
 
-	for lineIndex < len(p.lines) && rowCount < count {
+	for lineIndex < len(p.lines) && len(array) < count {
 		rowLine := p.lines[lineIndex]
 		dataContent := strings.TrimSpace(rowLine)
+		if h.delim == '\t' {
+			// Keep the tabs around empty cells at the ends of the row.
+			dataContent = strings.Trim(rowLine, " \r")
+		}
 
 		if dataContent == "" {
 			lineIndex++
@@ -412,40 +661,115 @@ func (p *Parser) parseRootTabularArray() ([]interface{}, error) {
 			continue
 		}
 
-		values := splitTabularValues(dataContent)
-
-		if len(values) != len(fields) {
-			return nil, fmt.Errorf("row %d: field count mismatch (expected %d, got %d)",
-				rowCount+1, len(fields), len(values))
+		obj, err := p.parseTabularRow(dataContent, h, len(array))
+		if err != nil {
+			return nil, p.syntaxError(lineIndex+1, err)
 		}
 
-		rows = append(rows, values)
-		rowCount++
+		array = append(array, obj)
 		lineIndex++
 	}
 
-	if rowCount != count {
-		return nil, fmt.Errorf("array count mismatch: declared %d, found %d", count, rowCount)
+	if len(array) != count {
+		return nil, p.syntaxError(1, fmt.Errorf("array count mismatch: declared %d, found %d", count, len(array)))
 	}
 
-	// Convert to array of objects
-	array := make([]interface{}, len(rows))
-	for i, row := range rows {
-		obj := make(map[string]interface{})
-		for j, field := range fields {
-			obj[field] = p.parsePrimitive(row[j])
+	return array, nil
+}
+
+// splitTabularValues divide uma linha tabular pelo delimitador, removendo espaços extras.
+// Delimitadores dentro de valores entre aspas não separam campos.
+func splitTabularValues(line string, delim byte) []string {
+	var parts []string
+	start := 0
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case delim:
+			if !inQuotes {
+				parts = append(parts, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
 		}
-		array[i] = obj
 	}
+	return append(parts, strings.TrimSpace(line[start:]))
+}
 
-	return array, nil
+// splitKey separates the key at the start of a line from the rest of the
+// line, which begins at the array bracket or colon following the key. Keys
+// may be quoted. ok is false when the line has no key.
+func splitKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "\"") {
+		end := closingQuote(content)
+		if end == -1 {
+			return "", content, false
+		}
+		rest := strings.TrimLeft(content[end+1:], " ")
+		if !strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, "[") {
+			return "", content, false
+		}
+		return unquote(content[1:end]), rest, true
+	}
+
+	i := strings.IndexAny(content, "[:")
+	if i == -1 {
+		return "", content, false
+	}
+	return strings.TrimSpace(content[:i]), content[i:], true
 }
 
-// splitTabularValues divide uma linha tabular por vírgula, removendo espaços extras
-func splitTabularValues(line string) []string {
-	parts := strings.Split(line, ",")
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
+// closingQuote returns the index of the quote ending the string that opens
+// at s[0], or -1 if it is unterminated.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteKey(key string) string {
+	if len(key) >= 2 && key[0] == '"' && key[len(key)-1] == '"' {
+		return unquote(key[1 : len(key)-1])
+	}
+	return key
+}
+
+// unquote resolves the escape sequences written by the encoder.
+func unquote(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
 	}
-	return parts
+	return b.String()
 }
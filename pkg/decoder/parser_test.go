@@ -1,6 +1,7 @@
 package decoder
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -128,3 +129,233 @@ func TestParseNamedTabularArray(t *testing.T) {
 		t.Errorf("Expected Age=25, got %v", second["Age"])
 	}
 }
+
+func TestParseTabularQuotedDelimiter(t *testing.T) {
+	input := `items[2]{name,note}:
+  "Smith, John","a"
+  Bob,"b, c"`
+
+	result, err := NewParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	items := result.(map[string]interface{})["items"].([]interface{})
+	first := items[0].(map[string]interface{})
+	if first["name"] != "Smith, John" {
+		t.Errorf("Expected name=Smith, John, got %v", first["name"])
+	}
+	second := items[1].(map[string]interface{})
+	if second["note"] != "b, c" {
+		t.Errorf("Expected note=b, c, got %v", second["note"])
+	}
+}
+
+func TestParseListAndInlineArrays(t *testing.T) {
+	input := `tags[2]: "a","b, c"
+items[3]:
+  - 1
+  - id: 2
+    tags[1]: "x"
+  - [2]: 3,4
+note: "line\nbreak"`
+
+	result, err := NewParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	obj := result.(map[string]interface{})
+
+	tags := obj["tags"].([]interface{})
+	if len(tags) != 2 || tags[1] != "b, c" {
+		t.Errorf("Expected tags [a, b, c], got %v", tags)
+	}
+
+	items := obj["items"].([]interface{})
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+	if items[0] != int64(1) {
+		t.Errorf("Expected first item 1, got %v", items[0])
+	}
+	second := items[1].(map[string]interface{})
+	if second["id"] != int64(2) || len(second["tags"].([]interface{})) != 1 {
+		t.Errorf("Expected object item, got %v", second)
+	}
+	if third := items[2].([]interface{}); len(third) != 2 {
+		t.Errorf("Expected nested array item, got %v", items[2])
+	}
+	if obj["note"] != "line\nbreak" {
+		t.Errorf("Expected escaped newline, got %q", obj["note"])
+	}
+}
+
+func TestParseDelimiters(t *testing.T) {
+	inputs := []string{
+		"items[2\t]{id\tname}:\n  1\t\"a|b\"\n  2\t\"c, d\"\ntags[2\t]: \"x\"\t\"y\"",
+		"items[2|]{id|name}:\n  1|\"a|b\"\n  2|\"c, d\"\ntags[2|]: \"x\"|\"y\"",
+	}
+
+	for _, input := range inputs {
+		result, err := NewParser(strings.NewReader(input)).Parse()
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", input, err)
+		}
+		obj := result.(map[string]interface{})
+
+		items := obj["items"].([]interface{})
+		if first := items[0].(map[string]interface{}); first["name"] != "a|b" {
+			t.Errorf("Expected name=a|b, got %v", first["name"])
+		}
+		if second := items[1].(map[string]interface{}); second["name"] != "c, d" || second["id"] != int64(2) {
+			t.Errorf("Expected second row {2, c, d}, got %v", second)
+		}
+		if tags := obj["tags"].([]interface{}); len(tags) != 2 || tags[1] != "y" {
+			t.Errorf("Expected tags [x y], got %v", tags)
+		}
+	}
+}
+
+func TestParseExpandPaths(t *testing.T) {
+	input := `a.b.c: 1
+a.b.d[2]: 1,2
+"x.y": 2
+data.meta:
+  page: 1`
+
+	result, err := NewParserWithOptions(strings.NewReader(input), Options{ExpandPaths: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	obj := result.(map[string]interface{})
+
+	b := obj["a"].(map[string]interface{})["b"].(map[string]interface{})
+	if b["c"] != int64(1) || len(b["d"].([]interface{})) != 2 {
+		t.Errorf("Expected a.b = {c: 1, d: [1 2]}, got %v", b)
+	}
+	if obj["x.y"] != int64(2) {
+		t.Errorf("Expected quoted key to stay literal, got %v", obj)
+	}
+	meta := obj["data"].(map[string]interface{})["meta"].(map[string]interface{})
+	if meta["page"] != int64(1) {
+		t.Errorf("Expected data.meta.page = 1, got %v", meta)
+	}
+
+	// Without the option dotted keys are literal.
+	result, err = NewParser(strings.NewReader("a.b: 1")).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if result.(map[string]interface{})["a.b"] != int64(1) {
+		t.Errorf("Expected literal key a.b, got %v", result)
+	}
+
+	_, err = NewParserWithOptions(strings.NewReader("a: 1\na.b: 2"), Options{ExpandPaths: true}).Parse()
+	if err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+}
+
+func TestParseDottedColumns(t *testing.T) {
+	input := `orders[2]{id,customer.id,customer.name,"a.b"}:
+  1,7,Ada,x
+  2,8,Linus,y`
+
+	result, err := NewParserWithOptions(strings.NewReader(input), Options{ExpandPaths: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	orders := result.(map[string]interface{})["orders"].([]interface{})
+	second := orders[1].(map[string]interface{})
+	customer := second["customer"].(map[string]interface{})
+	if customer["id"] != int64(8) || customer["name"] != "Linus" {
+		t.Errorf("Expected nested customer, got %v", second)
+	}
+	if second["a.b"] != "y" {
+		t.Errorf("Expected quoted column to stay literal, got %v", second)
+	}
+
+	result, err = NewParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	first := result.(map[string]interface{})["orders"].([]interface{})[0].(map[string]interface{})
+	if first["customer.id"] != int64(7) {
+		t.Errorf("Expected literal dotted column without ExpandPaths, got %v", first)
+	}
+}
+
+func TestParseDropAbsent(t *testing.T) {
+	input := "users[3\t]{id\tname\trole}:\n  1\t\"Ada\"\t\"admin\"\n  2\t\"Linus\"\t\n  \t\"Grace\"\t\"\""
+
+	result, err := NewParserWithOptions(strings.NewReader(input), Options{DropAbsent: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	users := result.(map[string]interface{})["users"].([]interface{})
+	if second := users[1].(map[string]interface{}); len(second) != 2 || second["name"] != "Linus" {
+		t.Errorf("Expected the empty role cell to be dropped, got %v", second)
+	}
+	if third := users[2].(map[string]interface{}); len(third) != 2 || third["role"] != "" {
+		t.Errorf("Expected the empty id cell to be dropped and the quoted role kept, got %v", third)
+	}
+
+	result, err = NewParser(strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	second := result.(map[string]interface{})["users"].([]interface{})[1].(map[string]interface{})
+	if role, ok := second["role"]; !ok || role != "" {
+		t.Errorf("Expected an empty string without DropAbsent, got %v", second)
+	}
+
+	// Root tables keep empty cells at both ends of a row.
+	result, err = NewParserWithOptions(strings.NewReader("[2\t]{a\tb}:\n  \t1\n  2\t"), Options{DropAbsent: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	rows := result.([]interface{})
+	if first := rows[0].(map[string]interface{}); len(first) != 1 || first["b"] != int64(1) {
+		t.Errorf("Expected the leading empty cell to be dropped, got %v", first)
+	}
+	if last := rows[1].(map[string]interface{}); len(last) != 1 || last["a"] != int64(2) {
+		t.Errorf("Expected the trailing empty cell to be dropped, got %v", last)
+	}
+}
+
+func TestParseSyntaxErrorPosition(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  SyntaxError
+	}{
+		{
+			name:  "tabular row",
+			input: "user:\n  name: \"Ada\"\n  roles[2]{id,name}:\n    1,\"admin\"\n    2",
+			want:  SyntaxError{Line: 5, Column: 5, Msg: "row 2: field count mismatch (expected 2, got 1)"},
+		},
+		{
+			name:  "count mismatch",
+			input: "a: 1\ntags[3]: x,y",
+			want:  SyntaxError{Line: 2, Column: 1, Msg: "array count mismatch: declared 3, found 2"},
+		},
+		{
+			name:  "list item",
+			input: "items[2]:\n  - 1\n  two",
+			want:  SyntaxError{Line: 3, Column: 3, Msg: `expected list item, got "two"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(strings.NewReader(tt.input)).Parse()
+			var serr *SyntaxError
+			if !errors.As(err, &serr) {
+				t.Fatalf("Expected a *SyntaxError, got %v", err)
+			}
+			if *serr != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *serr)
+			}
+		})
+	}
+}
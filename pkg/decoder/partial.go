@@ -0,0 +1,195 @@
+package decoder
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// PartialString is a string value of which only a prefix has been received
+// so far. It appears in the values returned by PartialDecoder.Value in place
+// of the plain string.
+type PartialString string
+
+// partialMarker ends a string completed by PartialDecoder so the value can
+// be found and flagged after parsing.
+const partialMarker = "\x00"
+
+// PartialDecoder decodes a document that arrives in chunks, such as a
+// streamed model completion. Chunks are pushed with Write, and Value returns
+// the best-effort value received so far: keys whose lines are complete,
+// tabular and list rows that have been fully received, with array counts
+// reduced to the rows present, and a trailing quoted string that is still
+// being received, as a PartialString. Incomplete numbers, booleans, rows
+// and headers are left out until their line ends.
+//
+// Each call to Value parses everything received so far, which is cheap for
+// the size of a model response but quadratic over very long streams.
+type PartialDecoder struct {
+	data  []byte
+	opts  Options
+	value interface{}
+	err   error
+	dirty bool
+}
+
+// NewPartialDecoder returns a decoder that parses with opts. Lenient is
+// ignored: partial input is never repaired beyond its array counts.
+func NewPartialDecoder(opts Options) *PartialDecoder {
+	opts.Lenient = false
+	return &PartialDecoder{opts: opts, dirty: true}
+}
+
+// Write appends a chunk of the document. It never fails.
+func (d *PartialDecoder) Write(p []byte) (int, error) {
+	d.data = append(d.data, p...)
+	d.dirty = true
+	return len(p), nil
+}
+
+// WriteString appends a chunk of the document. It never fails.
+func (d *PartialDecoder) WriteString(s string) (int, error) {
+	return d.Write([]byte(s))
+}
+
+// Value returns the best-effort value of the document received so far, or
+// an error if the complete lines received are not valid TOON.
+func (d *PartialDecoder) Value() (interface{}, error) {
+	if !d.dirty {
+		return d.value, d.err
+	}
+	d.dirty = false
+
+	d.value, d.err = NewParserWithOptions(bytes.NewReader(d.snapshot()), d.opts).Parse()
+	if d.err != nil {
+		d.value = nil
+		return nil, d.err
+	}
+	d.value = flagPartial(d.value)
+	return d.value, nil
+}
+
+// Close parses the complete document strictly, reporting array counts that
+// do not match the rows received.
+func (d *PartialDecoder) Close() (interface{}, error) {
+	return NewParserWithOptions(bytes.NewReader(d.data), d.opts).Parse()
+}
+
+// snapshot returns the complete lines received so far, followed by the
+// trailing partial line when it ends in a quoted string, closed and marked,
+// with array counts rewritten to match the rows present.
+func (d *PartialDecoder) snapshot() []byte {
+	text := strings.ReplaceAll(string(d.data), "\r\n", "\n")
+	complete, partial := text, ""
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		complete, partial = text[:i], text[i+1:]
+	} else {
+		complete, partial = "", text
+	}
+
+	r := &repairer{}
+	if complete != "" {
+		for i, line := range strings.Split(complete, "\n") {
+			r.lines = append(r.lines, repairLine{text: strings.TrimRight(line, " \r"), num: i + 1})
+		}
+	}
+	if line, ok := closePartialLine(partial, len(r.lines) == 0); ok {
+		r.lines = append(r.lines, repairLine{text: line, num: len(r.lines) + 1})
+	}
+	r.fixArrays()
+
+	var buf bytes.Buffer
+	for _, line := range r.lines {
+		buf.WriteString(line.text)
+		buf.WriteByte('\n')
+	}
+	if len(r.lines) == 1 && !isObjectDocument(r.lines) {
+		buf.Truncate(buf.Len() - 1)
+	}
+	return buf.Bytes()
+}
+
+// closePartialLine returns the line to parse in place of an incomplete last
+// line. Only values that end in a quoted string are kept: a key and string
+// value, a list item string, or a root string when root is set. A string
+// whose closing quote has not arrived is closed and marked as partial.
+func closePartialLine(line string, root bool) (string, bool) {
+	content := strings.TrimLeft(line, " ")
+	if content == "" {
+		return "", false
+	}
+
+	value := ""
+	switch {
+	case strings.HasPrefix(content, "- "):
+		value = strings.TrimLeft(content[2:], " ")
+		if !strings.HasPrefix(value, "\"") {
+			if _, rest, ok := splitKey(value); ok && strings.HasPrefix(rest, ":") {
+				value = strings.TrimLeft(rest[1:], " ")
+			}
+		}
+	case strings.HasPrefix(content, "\""):
+		if _, rest, ok := splitKey(content); ok {
+			if !strings.HasPrefix(rest, ":") {
+				return "", false
+			}
+			value = strings.TrimLeft(rest[1:], " ")
+		} else if root {
+			value = content
+		} else {
+			return "", false
+		}
+	default:
+		_, rest, ok := splitKey(content)
+		if !ok || !strings.HasPrefix(rest, ":") {
+			return "", false
+		}
+		value = strings.TrimLeft(rest[1:], " ")
+	}
+
+	if !strings.HasPrefix(value, "\"") {
+		return "", false
+	}
+	if end := closingQuote(value); end >= 0 {
+		return line, end == len(value)-1
+	}
+
+	// Drop an escape sequence or a UTF-8 sequence cut by the chunk boundary.
+	prefix := line[:len(line)-len(value)]
+	body := value[1:]
+	if trailingBackslashes(body)%2 == 1 {
+		body = body[:len(body)-1]
+	}
+	for len(body) > 0 && !utf8.ValidString(body) {
+		body = body[:len(body)-1]
+	}
+	return prefix + "\"" + body + partialMarker + "\"", true
+}
+
+func trailingBackslashes(s string) int {
+	n := 0
+	for n < len(s) && s[len(s)-1-n] == '\\' {
+		n++
+	}
+	return n
+}
+
+// flagPartial replaces the string ending in partialMarker with a
+// PartialString.
+func flagPartial(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if strings.HasSuffix(v, partialMarker) {
+			return PartialString(strings.TrimSuffix(v, partialMarker))
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = flagPartial(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = flagPartial(value)
+		}
+	}
+	return v
+}
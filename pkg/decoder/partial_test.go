@@ -0,0 +1,84 @@
+package decoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPartialDecoder(t *testing.T) {
+	d := NewPartialDecoder(Options{})
+
+	steps := []struct {
+		chunk string
+		want  interface{}
+	}{
+		{"title: \"Quarterly rep", map[string]interface{}{"title": PartialString("Quarterly rep")}},
+		{"ort\"\ncount: 4", map[string]interface{}{"title": "Quarterly report"}},
+		{"2\nusers[3]{id,name}:\n  1,Ada\n  2,Li", map[string]interface{}{
+			"title": "Quarterly report",
+			"count": int64(42),
+			"users": []interface{}{map[string]interface{}{"id": int64(1), "name": "Ada"}},
+		}},
+		{"nus\n  3,Grace\n", map[string]interface{}{
+			"title": "Quarterly report",
+			"count": int64(42),
+			"users": []interface{}{
+				map[string]interface{}{"id": int64(1), "name": "Ada"},
+				map[string]interface{}{"id": int64(2), "name": "Linus"},
+				map[string]interface{}{"id": int64(3), "name": "Grace"},
+			},
+		}},
+	}
+
+	for i, step := range steps {
+		d.WriteString(step.chunk)
+		got, err := d.Value()
+		if err != nil {
+			t.Fatalf("Step %d: Value failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("Step %d: expected %#v, got %#v", i, step.want, got)
+		}
+	}
+
+	if _, err := d.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestPartialDecoderEveryPrefix(t *testing.T) {
+	doc := "note: \"wörld \\\"quoted\\\"\"\nitems[2]:\n  - id: 1\n    tags[2]: a,b\n  - \"last\"\nrows[2|]{a|b}:\n  1|\"x|y\"\n  2|z\n"
+
+	d := NewPartialDecoder(Options{})
+	for i := 0; i < len(doc); i++ {
+		d.Write([]byte{doc[i]})
+		if _, err := d.Value(); err != nil {
+			t.Fatalf("Value failed after %q: %v", doc[:i+1], err)
+		}
+	}
+
+	got, err := d.Close()
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	want, _ := NewParser(strings.NewReader(doc)).Parse()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %#v, got %#v", want, got)
+	}
+}
+
+func TestPartialDecoderRootString(t *testing.T) {
+	d := NewPartialDecoder(Options{})
+	d.WriteString("\"Hello, wo")
+	got, err := d.Value()
+	if err != nil || got != PartialString("Hello, wo") {
+		t.Errorf("Expected partial root string, got %#v, %v", got, err)
+	}
+
+	d.WriteString("rld\"")
+	got, err = d.Value()
+	if err != nil || got != "Hello, world" {
+		t.Errorf("Expected complete root string, got %#v, %v", got, err)
+	}
+}
@@ -0,0 +1,501 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// FixKind classifies a change made by Repair.
+type FixKind int
+
+const (
+	// FixFence means the document was taken out of a Markdown code fence.
+	FixFence FixKind = iota
+	// FixProse means text before or after the document was removed.
+	FixProse
+	// FixIndentation means tabs or inconsistent indentation were normalized.
+	FixIndentation
+	// FixCount means an array header declared the wrong number of items.
+	FixCount
+	// FixQuoting means an unquoted value containing the delimiter was quoted.
+	FixQuoting
+	// FixRowLength means a tabular row with missing values was padded with null.
+	FixRowLength
+)
+
+func (k FixKind) String() string {
+	switch k {
+	case FixFence:
+		return "fence"
+	case FixProse:
+		return "prose"
+	case FixIndentation:
+		return "indentation"
+	case FixCount:
+		return "count"
+	case FixQuoting:
+		return "quoting"
+	case FixRowLength:
+		return "row length"
+	default:
+		return "FixKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Fix describes one change made by Repair.
+type Fix struct {
+	// Line is the 1-based line of the original input the fix applies to.
+	Line   int
+	Kind   FixKind
+	Detail string
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("line %d: %s: %s", f.Line, f.Kind, f.Detail)
+}
+
+// repairLine is a line of the document being repaired together with its
+// line number in the original input.
+type repairLine struct {
+	text string
+	num  int
+	// comment holds the text of a comment line, whose text is left blank
+	// while the document is repaired.
+	comment string
+}
+
+type repairer struct {
+	lines []repairLine
+	fixes []Fix
+}
+
+func (r *repairer) fix(line int, kind FixKind, format string, args ...interface{}) {
+	r.fixes = append(r.fixes, Fix{Line: line, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Repair fixes the mistakes language models commonly make when writing
+// TOON: it takes the document out of Markdown code fences, removes prose
+// before and after it, normalizes tab and inconsistent indentation to two
+// spaces per level, corrects array counts, quotes tabular values that
+// contain the delimiter and pads short rows with null. Every change is
+// reported as a Fix. Comment lines are kept, unindented, unless they are
+// outside the document.
+//
+// The repaired document is returned even when it still does not parse, in
+// which case the parse error is returned too.
+func Repair(data []byte) ([]byte, []Fix, error) {
+	r := &repairer{}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	comments := commentLines(lines)
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if comments[i] {
+			// Decoders skip comments, so the repairs ignore them.
+			r.lines = append(r.lines, repairLine{num: i + 1, comment: strings.TrimSpace(line)})
+			continue
+		}
+		r.lines = append(r.lines, repairLine{text: line, num: i + 1})
+	}
+
+	r.stripFences()
+	r.stripProse()
+	r.normalizeIndentation()
+	r.fixArrays()
+	sort.SliceStable(r.fixes, func(i, j int) bool { return r.fixes[i].Line < r.fixes[j].Line })
+
+	var buf bytes.Buffer
+	for i, line := range r.lines {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if line.comment != "" {
+			buf.WriteString(line.comment)
+			continue
+		}
+		buf.WriteString(line.text)
+	}
+	out := buf.Bytes()
+	if last := len(r.lines) - 1; last >= 0 && (r.lines[last].text != "" || r.lines[last].comment != "") && isObjectDocument(r.lines) {
+		out = append(out, '\n')
+	}
+
+	if _, err := NewParser(bytes.NewReader(out)).Parse(); err != nil {
+		return out, r.fixes, err
+	}
+	return out, r.fixes, nil
+}
+
+// isObjectDocument reports whether the document is more than a lone
+// primitive, which is written without a trailing newline.
+func isObjectDocument(lines []repairLine) bool {
+	count := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line.text) != "" {
+			count++
+		}
+	}
+	if count != 1 {
+		return true
+	}
+	for _, line := range lines {
+		if content := strings.TrimSpace(line.text); content != "" {
+			_, _, ok := splitKey(content)
+			return ok
+		}
+	}
+	return true
+}
+
+// stripFences keeps only the contents of the first code fence, preferring
+// one tagged toon.
+func (r *repairer) stripFences() {
+	type fence struct{ open, close int }
+	var fences []fence
+	var tagged []fence
+	for i := 0; i < len(r.lines); i++ {
+		content := strings.TrimSpace(r.lines[i].text)
+		if !strings.HasPrefix(content, "```") {
+			continue
+		}
+		lang := strings.TrimSpace(strings.TrimLeft(content, "`"))
+		f := fence{open: i, close: len(r.lines)}
+		for j := i + 1; j < len(r.lines); j++ {
+			if strings.TrimSpace(r.lines[j].text) == "```" {
+				f.close = j
+				break
+			}
+		}
+		fences = append(fences, f)
+		if strings.EqualFold(lang, "toon") {
+			tagged = append(tagged, f)
+		}
+		i = f.close
+	}
+	if len(fences) == 0 {
+		return
+	}
+
+	f := fences[0]
+	if len(tagged) > 0 {
+		f = tagged[0]
+	}
+
+	for i, line := range r.lines {
+		if (i < f.open || i > f.close) && strings.TrimSpace(line.text) != "" {
+			r.fix(line.num, FixProse, "removed text outside the code fence")
+			break
+		}
+	}
+	r.fix(r.lines[f.open].num, FixFence, "removed code fence")
+	r.lines = r.lines[f.open+1 : f.close]
+}
+
+// stripProse removes lines before the first line that looks like TOON and
+// from the first top-level line after it that does not.
+func (r *repairer) stripProse() {
+	first := -1
+	for i, line := range r.lines {
+		if content := strings.TrimSpace(line.text); content != "" && isTOONLine(content) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return
+	}
+
+	// Keep the comments just before the document.
+	for first > 0 && r.lines[first-1].comment != "" {
+		first--
+	}
+
+	for _, line := range r.lines[:first] {
+		if strings.TrimSpace(line.text) != "" {
+			r.fix(line.num, FixProse, "removed text before the document")
+			break
+		}
+	}
+
+	baseIndent := indentWidth(r.lines[first].text)
+	end := len(r.lines)
+	for i := first + 1; i < len(r.lines); i++ {
+		content := strings.TrimSpace(r.lines[i].text)
+		if content == "" || indentWidth(r.lines[i].text) > baseIndent || isTOONLine(content) {
+			continue
+		}
+		r.fix(r.lines[i].num, FixProse, "removed text after the document")
+		end = i
+		break
+	}
+
+	r.lines = r.lines[first:end]
+	for len(r.lines) > 0 && strings.TrimSpace(r.lines[len(r.lines)-1].text) == "" && r.lines[len(r.lines)-1].comment == "" {
+		r.lines = r.lines[:len(r.lines)-1]
+	}
+}
+
+// isTOONLine reports whether content, a trimmed line, could start a TOON
+// field, array header or list item rather than a sentence.
+func isTOONLine(content string) bool {
+	if content == keysDirective || content == "-" || strings.HasPrefix(content, "- ") {
+		return true
+	}
+	if strings.HasPrefix(content, "[") {
+		_, err := parseArrayHeader(content)
+		return err == nil
+	}
+
+	key, rest, ok := splitKey(content)
+	if !ok {
+		return false
+	}
+	if !strings.HasPrefix(content, "\"") && !isBareKeyText(key) {
+		return false
+	}
+	if strings.HasPrefix(rest, "[") {
+		_, err := parseArrayHeader(content)
+		return err == nil
+	}
+	return true
+}
+
+func isBareKeyText(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// indentWidth returns the width of the leading whitespace of line, counting
+// a tab as two spaces.
+func indentWidth(line string) int {
+	width := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			width++
+		case '\t':
+			width += 2
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// normalizeIndentation rebuilds indentation from the nesting implied by the
+// original widths, using two spaces per level.
+func (r *repairer) normalizeIndentation() {
+	var stack []int
+	changed, firstChanged := 0, 0
+	for i, line := range r.lines {
+		content := strings.TrimLeft(line.text, " \t")
+		if content == "" {
+			r.lines[i].text = ""
+			continue
+		}
+
+		width := indentWidth(line.text)
+		for len(stack) > 0 && stack[len(stack)-1] > width {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 || stack[len(stack)-1] < width {
+			stack = append(stack, width)
+		}
+
+		normalized := strings.Repeat("  ", len(stack)-1) + content
+		if normalized != line.text {
+			if changed == 0 {
+				firstChanged = line.num
+			}
+			changed++
+			r.lines[i].text = normalized
+		}
+	}
+	if changed > 0 {
+		r.fix(firstChanged, FixIndentation, "normalized indentation of %d lines", changed)
+	}
+}
+
+// fixArrays corrects the count of every array header and repairs the rows
+// of tabular arrays.
+func (r *repairer) fixArrays() {
+	for i, line := range r.lines {
+		content := strings.TrimLeft(line.text, " ")
+		indent := len(line.text) - len(content)
+
+		prefix := ""
+		bodyIndent := indent
+		if strings.HasPrefix(content, "- ") {
+			prefix = "- "
+			content = content[2:]
+			bodyIndent = indent + 2
+		}
+
+		var h arrayHeader
+		var err error
+		if strings.HasPrefix(content, "[") {
+			h, err = parseArrayHeader(content)
+		} else if _, rest, ok := splitKey(content); ok && strings.HasPrefix(rest, "[") {
+			h, err = parseArrayHeader(content)
+		} else {
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		children := r.childLines(i+1, bodyIndent)
+		found := 0
+		switch {
+		case h.inline != "":
+			found = len(splitTabularValues(h.inline, h.delim))
+		case h.fields != nil:
+			for _, j := range children {
+				r.fixRow(j, h)
+			}
+			found = len(children)
+		default:
+			for _, j := range children {
+				item := strings.TrimSpace(r.lines[j].text)
+				if item == "-" || strings.HasPrefix(item, "- ") {
+					found++
+				}
+			}
+		}
+
+		if found != h.count {
+			r.fix(line.num, FixCount, "declared %d items, found %d", h.count, found)
+			r.lines[i].text = strings.Repeat(" ", indent) + prefix + replaceCount(content, found)
+		}
+	}
+}
+
+// childLines returns the indexes of the non-blank lines directly below the
+// header, that is those at the indentation of the first line deeper than
+// indent, up to the first line that is not deeper.
+func (r *repairer) childLines(start, indent int) []int {
+	var children []int
+	childIndent := -1
+	for j := start; j < len(r.lines); j++ {
+		text := r.lines[j].text
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		width := len(text) - len(strings.TrimLeft(text, " "))
+		if width <= indent {
+			break
+		}
+		if childIndent < 0 {
+			childIndent = width
+		}
+		if width == childIndent {
+			children = append(children, j)
+		}
+	}
+	return children
+}
+
+// replaceCount rewrites the count in the array header at the start of
+// content, keeping any delimiter marker.
+func replaceCount(content string, count int) string {
+	open := strings.Index(content, "[")
+	if !strings.HasPrefix(content, "[") {
+		_, rest, _ := splitKey(content)
+		open = len(content) - len(rest)
+	}
+	end := open + strings.Index(content[open:], "]")
+
+	marker := ""
+	if inner := content[open+1 : end]; inner != "" {
+		if last := inner[len(inner)-1]; last == '\t' || last == '|' || last == ',' {
+			marker = string(last)
+		}
+	}
+	return content[:open+1] + strconv.Itoa(count) + marker + content[end:]
+}
+
+// fixRow makes a tabular row match the header: runs of unquoted text split
+// by the delimiter are merged into one quoted value, and short rows are
+// padded with null.
+func (r *repairer) fixRow(index int, h arrayHeader) {
+	line := r.lines[index]
+	content := strings.TrimLeft(line.text, " ")
+	indent := line.text[:len(line.text)-len(content)]
+
+	spans := splitSpans(content, h.delim)
+	switch {
+	case len(spans) > len(h.fields):
+		extra := len(spans) - len(h.fields)
+		for start := 0; start+extra < len(spans); start++ {
+			if !allText(content, spans[start:start+extra+1]) {
+				continue
+			}
+			value := strings.TrimSpace(content[spans[start][0]:spans[start+extra][1]])
+			rebuilt := content[:spans[start][0]] + string(types.AppendString(nil, value)) + content[spans[start+extra][1]:]
+			r.lines[index].text = indent + rebuilt
+			r.fix(line.num, FixQuoting, "quoted %q, which contains the delimiter", value)
+			return
+		}
+
+	case len(spans) < len(h.fields):
+		missing := len(h.fields) - len(spans)
+		r.lines[index].text = line.text + strings.Repeat(string(h.delim)+"null", missing)
+		r.fix(line.num, FixRowLength, "padded %d missing values with null", missing)
+	}
+}
+
+// splitSpans returns the byte ranges of the values of a delimited line,
+// ignoring delimiters inside quotes.
+func splitSpans(line string, delim byte) [][2]int {
+	var spans [][2]int
+	start := 0
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case delim:
+			if !inQuotes {
+				spans = append(spans, [2]int{start, i})
+				start = i + 1
+			}
+		}
+	}
+	return append(spans, [2]int{start, len(line)})
+}
+
+// allText reports whether every span holds an unquoted value that is not a
+// number, boolean or null.
+func allText(line string, spans [][2]int) bool {
+	for _, span := range spans {
+		value := strings.TrimSpace(line[span[0]:span[1]])
+		if value == "" || strings.HasPrefix(value, "\"") {
+			return false
+		}
+		switch value {
+		case "true", "false", "null":
+			return false
+		}
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package decoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		kinds []FixKind
+	}{
+		{
+			name:  "valid document is unchanged",
+			input: "name: Alice\ntags[2]: a,b\n",
+			want:  "name: Alice\ntags[2]: a,b\n",
+		},
+		{
+			name:  "fence and prose",
+			input: "Here is the data:\n\n```toon\nid: 1\n```\n\nAnything else?",
+			want:  "id: 1\n",
+			kinds: []FixKind{FixProse, FixFence},
+		},
+		{
+			name:  "trailing prose",
+			input: "id: 1\nname: Bob\nI hope this helps!",
+			want:  "id: 1\nname: Bob\n",
+			kinds: []FixKind{FixProse},
+		},
+		{
+			name:  "inline count",
+			input: "tags[5]: a,b,c\n",
+			want:  "tags[3]: a,b,c\n",
+			kinds: []FixKind{FixCount},
+		},
+		{
+			name:  "tabular count and quoting",
+			input: "users[3]{id,name,active}:\n  1,Smith, John,true\n  2,Ada,false\n",
+			want:  "users[2]{id,name,active}:\n  1,\"Smith, John\",true\n  2,Ada,false\n",
+			kinds: []FixKind{FixCount, FixQuoting},
+		},
+		{
+			name:  "short row",
+			input: "users[1|]{id|name}:\n  1\n",
+			want:  "users[1|]{id|name}:\n  1|null\n",
+			kinds: []FixKind{FixRowLength},
+		},
+		{
+			name:  "list count and indentation",
+			input: "items[1]:\n    - id: 1\n      name: a\n    - id: 2\n",
+			want:  "items[2]:\n  - id: 1\n    name: a\n  - id: 2\n",
+			kinds: []FixKind{FixCount, FixIndentation},
+		},
+		{
+			name:  "comments",
+			input: "Here you go.\n# users\ndata:\n  users[1]{c,n}:\n    #fff,1\n  # the second\n    #000,2\n",
+			want:  "# users\ndata:\n  users[2]{c,n}:\n    #fff,1\n# the second\n    #000,2\n",
+			kinds: []FixKind{FixProse, FixCount},
+		},
+		{
+			name:  "tabs",
+			input: "user:\n\tid: 1\n\ttags[0]: x\n",
+			want:  "user:\n  id: 1\n  tags[1]: x\n",
+			kinds: []FixKind{FixIndentation, FixCount},
+		},
+		{
+			name:  "primitive",
+			input: "42",
+			want:  "42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, fixes, err := Repair([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Repair failed: %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Repair output mismatch\nwant:\n%s\ngot:\n%s", tt.want, out)
+			}
+			if len(fixes) != len(tt.kinds) {
+				t.Fatalf("Expected %d fixes, got %v", len(tt.kinds), fixes)
+			}
+			for i, fix := range fixes {
+				if fix.Kind != tt.kinds[i] {
+					t.Errorf("Fix %d: expected %v, got %v", i, tt.kinds[i], fix)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairUnfixable(t *testing.T) {
+	out, _, err := Repair([]byte("users[1]{id,name}:\n  1,2,3\n"))
+	if err == nil {
+		t.Fatalf("Expected a parse error, got %q", out)
+	}
+	if !strings.HasPrefix(string(out), "users[1]{id,name}:") {
+		t.Errorf("Expected the repaired document to be returned, got %q", out)
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	input := "```\nusers[5]{id,name}:\n  1,Ada\n  2,Linus\n```"
+
+	if _, err := NewParser(strings.NewReader(input)).Parse(); err == nil {
+		t.Fatal("Expected strict parse to fail")
+	}
+
+	p := NewParserWithOptions(strings.NewReader(input), Options{Lenient: true})
+	result, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Lenient parse failed: %v", err)
+	}
+	users := result.(map[string]interface{})["users"].([]interface{})
+	if len(users) != 2 || users[1].(map[string]interface{})["name"] != "Linus" {
+		t.Errorf("Unexpected users: %v", users)
+	}
+	if len(p.Fixes()) != 2 {
+		t.Errorf("Expected fence and count fixes, got %v", p.Fixes())
+	}
+}
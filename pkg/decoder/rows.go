@@ -0,0 +1,156 @@
+package decoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RowReader reads the rows of a single tabular array one line at a time.
+// Unlike Parser.Parse it never holds more than the current line in memory,
+// which makes it suitable for tables with millions of rows.
+type RowReader struct {
+	scanner *bufio.Scanner
+	parser  Parser
+	lineNum int
+	name    string
+	fields  []string
+	count   int
+	delim   byte
+	indent  int
+	read    int
+}
+
+type pathEntry struct {
+	indent int
+	key    string
+}
+
+// NewRowReader scans r until it finds the header of the tabular array at
+// path name and positions the reader on its first row. Nested arrays are
+// addressed with dotted paths such as "data.events"; a root-level array is
+// addressed with the empty string.
+func NewRowReader(r io.Reader, name string) (*RowReader, error) {
+	rr := &RowReader{
+		scanner: bufio.NewScanner(r),
+		name:    name,
+	}
+
+	var stack []pathEntry
+	for rr.scanner.Scan() {
+		rr.lineNum++
+		line := rr.scanner.Text()
+
+		content := strings.TrimSpace(line)
+		if content == "" {
+			continue
+		}
+
+		indent := 0
+		for indent < len(line) && line[indent] == ' ' {
+			indent++
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		key, rest, ok := splitKey(content)
+		if !ok {
+			continue
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			h, err := parseArrayHeader(content)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", rr.lineNum, err)
+			}
+			if h.fields != nil && joinPath(stack, key) == name {
+				rr.fields = h.fields
+				rr.count = h.count
+				rr.delim = h.delim
+				rr.indent = indent
+				return rr, nil
+			}
+			continue
+		}
+
+		if strings.TrimSpace(rest) == ":" {
+			stack = append(stack, pathEntry{indent: indent, key: key})
+		}
+	}
+
+	if err := rr.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("tabular array %q not found", name)
+}
+
+func joinPath(stack []pathEntry, key string) string {
+	if len(stack) == 0 {
+		return key
+	}
+	parts := make([]string, 0, len(stack)+1)
+	for _, entry := range stack {
+		parts = append(parts, entry.key)
+	}
+	return strings.Join(append(parts, key), ".")
+}
+
+// Fields returns the column names declared in the array header.
+func (rr *RowReader) Fields() []string {
+	return rr.fields
+}
+
+// Count returns the number of rows declared in the array header.
+func (rr *RowReader) Count() int {
+	return rr.count
+}
+
+// Next decodes the next row into a slice of primitive values ordered like
+// Fields. It returns io.EOF once all declared rows have been read.
+func (rr *RowReader) Next() ([]interface{}, error) {
+	if rr.read >= rr.count {
+		return nil, io.EOF
+	}
+
+	for rr.scanner.Scan() {
+		rr.lineNum++
+		line := rr.scanner.Text()
+
+		rowIndent := 0
+		for rowIndent < len(line) && line[rowIndent] == ' ' {
+			rowIndent++
+		}
+
+		dataContent := strings.TrimSpace(line[rowIndent:])
+		if dataContent == "" {
+			continue
+		}
+
+		if rr.name != "" && rowIndent <= rr.indent {
+			break
+		}
+
+		values := splitTabularValues(dataContent, rr.delim)
+		if len(values) != len(rr.fields) {
+			return nil, fmt.Errorf("line %d: row %d: field count mismatch (expected %d, got %d)",
+				rr.lineNum, rr.read+1, len(rr.fields), len(values))
+		}
+
+		row := make([]interface{}, len(values))
+		for i, value := range values {
+			row[i] = rr.parser.parsePrimitive(value)
+		}
+		rr.read++
+		return row, nil
+	}
+
+	if err := rr.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("array count mismatch: declared %d, found %d", rr.count, rr.read)
+}
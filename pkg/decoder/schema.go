@@ -0,0 +1,409 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema constrains the values of a document checked by Validate. It is
+// read by ParseSchema from a subset of JSON Schema with these keywords:
+//
+//	type                  "object", "array", "string", "number", "integer",
+//	                      "boolean" or "null", or an array of them
+//	properties            schemas of the fields of an object
+//	required              names of the fields an object must have
+//	additionalProperties  false to reject fields not in properties
+//	items                 schema of every element of an array
+//	enum                  the values allowed
+//	minimum, maximum      bounds of numbers, inclusive
+//	minItems, maxItems    bounds of array lengths
+//	minLength, maxLength  bounds of string lengths, in characters
+//	pattern               a regular expression strings must match
+//
+// Other keywords, such as $schema, title and description, are ignored.
+type Schema struct {
+	types      []string
+	properties map[string]*Schema
+	required   []string
+	closed     bool
+	items      *Schema
+	enum       []interface{}
+
+	minimum, maximum     *float64
+	minItems, maxItems   int
+	minLength, maxLength int
+	pattern              *regexp.Regexp
+}
+
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// ParseSchema reads a schema written as JSON, or as TOON when data does not
+// start with "{".
+func ParseSchema(data []byte) (*Schema, error) {
+	var v interface{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &v); err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+	} else {
+		var err error
+		if v, err = NewParser(bytes.NewReader(data)).Parse(); err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+	}
+	return buildSchema(v, "")
+}
+
+func schemaErrorf(path, format string, args ...interface{}) error {
+	if path == "" {
+		return fmt.Errorf("schema: "+format, args...)
+	}
+	return fmt.Errorf("schema: %s: "+format, append([]interface{}{path}, args...)...)
+}
+
+func buildSchema(v interface{}, path string) (*Schema, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, schemaErrorf(path, "must be an object")
+	}
+	s := &Schema{minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+	keyword := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	for name, value := range m {
+		var err error
+		switch name {
+		case "type":
+			s.types, err = schemaTypeList(value, keyword(name))
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, schemaErrorf(keyword(name), "must be an object")
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for key, prop := range props {
+				if s.properties[key], err = buildSchema(prop, keyword(name)+"."+key); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = stringList(value, keyword(name))
+		case "additionalProperties":
+			allowed, ok := value.(bool)
+			if !ok {
+				return nil, schemaErrorf(keyword(name), "must be a boolean")
+			}
+			s.closed = !allowed
+		case "items":
+			s.items, err = buildSchema(value, keyword(name))
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, schemaErrorf(keyword(name), "must be an array")
+			}
+			s.enum = values
+		case "minimum", "maximum":
+			f, ok := toFloat(value)
+			if !ok {
+				return nil, schemaErrorf(keyword(name), "must be a number")
+			}
+			if name == "minimum" {
+				s.minimum = &f
+			} else {
+				s.maximum = &f
+			}
+		case "minItems":
+			s.minItems, err = schemaCount(value, keyword(name))
+		case "maxItems":
+			s.maxItems, err = schemaCount(value, keyword(name))
+		case "minLength":
+			s.minLength, err = schemaCount(value, keyword(name))
+		case "maxLength":
+			s.maxLength, err = schemaCount(value, keyword(name))
+		case "pattern":
+			expr, ok := value.(string)
+			if !ok {
+				return nil, schemaErrorf(keyword(name), "must be a string")
+			}
+			if s.pattern, err = regexp.Compile(expr); err != nil {
+				return nil, schemaErrorf(keyword(name), "%v", err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func schemaTypeList(v interface{}, path string) ([]string, error) {
+	if name, ok := v.(string); ok {
+		v = []interface{}{name}
+	}
+	types, err := stringList(v, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range types {
+		if !slices.Contains(schemaTypes, name) {
+			return nil, schemaErrorf(path, "unknown type %q", name)
+		}
+	}
+	return types, nil
+}
+
+func stringList(v interface{}, path string) ([]string, error) {
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, schemaErrorf(path, "must be an array of strings")
+	}
+	list := make([]string, len(values))
+	for i, value := range values {
+		if list[i], ok = value.(string); !ok {
+			return nil, schemaErrorf(path, "must be an array of strings")
+		}
+	}
+	return list, nil
+}
+
+func schemaCount(v interface{}, path string) (int, error) {
+	f, ok := toFloat(v)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, schemaErrorf(path, "must be a non-negative integer")
+	}
+	return int(f), nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// SchemaError reports a value that does not satisfy a Schema. Line and
+// Column are 1-based and locate the key, row or item holding the value;
+// Path names it, as in "users[2].email", and is empty for the root.
+type SchemaError struct {
+	Line   int
+	Column int
+	Path   string
+	Msg    string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", e.Line, e.Column, e.Path, e.Msg)
+}
+
+// schemaChecker checks a tree against a schema, collecting every
+// violation.
+type schemaChecker struct {
+	lines []string
+	errs  []error
+}
+
+func (c *schemaChecker) errorf(line int, path, format string, args ...interface{}) {
+	column := 1
+	if line >= 1 && line <= len(c.lines) {
+		text := c.lines[line-1]
+		column = len(text) - len(strings.TrimLeft(text, " ")) + 1
+	}
+	c.errs = append(c.errs, &SchemaError{Line: line, Column: column, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// check checks the value n found at path.
+func (c *schemaChecker) check(s *Schema, n *node, path string) {
+	var value interface{}
+	kind := "object"
+	switch n.kind {
+	case arrayNode:
+		kind = "array"
+	case primitiveNode:
+		value = (&Parser{}).parsePrimitive(n.raw)
+		kind = primitiveType(value)
+	}
+
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool {
+		return t == kind || t == "number" && kind == "integer"
+	}) {
+		c.errorf(n.line, path, "expected %s, got %s", strings.Join(s.types, " or "), kind)
+		return
+	}
+	if s.enum != nil && n.kind == primitiveNode && !slices.ContainsFunc(s.enum, func(allowed interface{}) bool {
+		return equalPrimitive(allowed, value)
+	}) {
+		allowed, _ := json.Marshal(s.enum)
+		c.errorf(n.line, path, "value %s is not one of %s", n.raw, allowed)
+	}
+
+	switch n.kind {
+	case objectNode:
+		c.checkKeys(s, entryKeys(n.entries), n.line, path)
+		c.checkFields(s, n.entries, path)
+	case arrayNode:
+		c.checkArray(s, n, path)
+	default:
+		c.checkPrimitive(s, n, value, path)
+	}
+}
+
+func entryKeys(entries []*entry) []string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// checkKeys checks the field names of an object starting at line.
+func (c *schemaChecker) checkKeys(s *Schema, keys []string, line int, path string) {
+	for _, name := range s.required {
+		if !slices.Contains(keys, name) {
+			c.errorf(line, path, "missing required field %q", name)
+		}
+	}
+}
+
+// checkFields checks the values of fields and, for closed schemas, their
+// names.
+func (c *schemaChecker) checkFields(s *Schema, entries []*entry, path string) {
+	for _, e := range entries {
+		prop, ok := s.properties[e.key]
+		switch {
+		case ok:
+			c.check(prop, e.value, fieldPath(path, e.key))
+		case s.closed:
+			c.errorf(e.line, path, "unexpected field %q", e.key)
+		}
+	}
+}
+
+func (c *schemaChecker) checkArray(s *Schema, n *node, path string) {
+	count := len(n.values)
+	switch {
+	case n.columns != nil:
+		count = len(n.rows)
+	case n.list:
+		count = len(n.entries)
+	}
+	if s.minItems >= 0 && count < s.minItems {
+		c.errorf(n.line, path, "has %d items, fewer than %d", count, s.minItems)
+	}
+	if s.maxItems >= 0 && count > s.maxItems {
+		c.errorf(n.line, path, "has %d items, more than %d", count, s.maxItems)
+	}
+	if s.items == nil {
+		return
+	}
+
+	switch {
+	case n.columns != nil:
+		// Rows are objects with the same fields, so their names are
+		// checked once, at the header.
+		items := s.items
+		checkRows := len(items.types) == 0 || slices.Contains(items.types, "object")
+		if checkRows {
+			columns := make([]string, len(n.columns))
+			for i, column := range n.columns {
+				columns[i] = column.key
+				if _, ok := items.properties[column.key]; items.closed && !ok {
+					c.errorf(n.line, path+"[*]", "unexpected field %q", column.key)
+				}
+			}
+			c.checkKeys(items, columns, n.line, path+"[*]")
+		}
+		for i, r := range n.rows {
+			fields := make([]*entry, len(r.cells))
+			for j, cell := range r.cells {
+				fields[j] = &entry{line: r.line, key: n.columns[j].key, value: &node{kind: primitiveNode, line: r.line, raw: cell}}
+			}
+			rowPath := path + "[" + strconv.Itoa(i) + "]"
+			if !checkRows {
+				c.check(items, &node{kind: objectNode, line: r.line, entries: fields}, rowPath)
+				continue
+			}
+			c.checkFields(&Schema{properties: items.properties}, fields, rowPath)
+		}
+	case n.list:
+		for i, item := range n.entries {
+			c.check(s.items, item.value, path+"["+strconv.Itoa(i)+"]")
+		}
+	default:
+		for i, value := range n.values {
+			c.check(s.items, &node{kind: primitiveNode, line: n.line, raw: value}, path+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+func (c *schemaChecker) checkPrimitive(s *Schema, n *node, value interface{}, path string) {
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength >= 0 && length < s.minLength {
+			c.errorf(n.line, path, "length %d is less than %d", length, s.minLength)
+		}
+		if s.maxLength >= 0 && length > s.maxLength {
+			c.errorf(n.line, path, "length %d is more than %d", length, s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			c.errorf(n.line, path, "%q does not match pattern %q", v, s.pattern)
+		}
+	case int64, float64:
+		f, _ := toFloat(v)
+		if s.minimum != nil && f < *s.minimum {
+			c.errorf(n.line, path, "%s is less than the minimum %v", n.raw, *s.minimum)
+		}
+		if s.maximum != nil && f > *s.maximum {
+			c.errorf(n.line, path, "%s is more than the maximum %v", n.raw, *s.maximum)
+		}
+	}
+}
+
+func primitiveType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "number"
+	}
+	return "string"
+}
+
+// equalPrimitive reports whether a schema enum value equals a decoded
+// primitive, comparing numbers by value.
+func equalPrimitive(a, b interface{}) bool {
+	fa, ok := toFloat(a)
+	if fb, ok2 := toFloat(b); ok && ok2 {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
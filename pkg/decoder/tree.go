@@ -0,0 +1,535 @@
+package decoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commentLines reports which lines are comments, which decoders skip:
+// those starting with "#" outside the rows of tabular arrays. A line
+// indented as a row is data, the first cell of a row such as "#fff", and
+// comments between rows are indented no further than the header.
+func commentLines(lines []string) []bool {
+	comments := make([]bool, len(lines))
+	// parents holds the indentation of the lines enclosing the current one.
+	var parents []int
+	// rows is the indentation the rows of the open tabular array are
+	// indented beyond, or -1.
+	rows := -1
+	for i, line := range lines {
+		content := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		indent := len(line) - len(content)
+		if rows >= 0 && indent > rows {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(content, "\t"), "#") {
+			comments[i] = true
+			continue
+		}
+		rows = -1
+
+		for len(parents) > 0 && parents[len(parents)-1] >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		header, body := strings.TrimSpace(content), indent
+		if header == "-" || strings.HasPrefix(header, "- ") {
+			// As in Parser, the content after the marker counts as
+			// indented one unit beyond it.
+			header = strings.TrimSpace(header[1:])
+			if len(parents) > 0 {
+				body += indent - parents[len(parents)-1]
+			}
+		}
+		if strings.Contains(header, "]{") {
+			if h, err := parseArrayHeader(header); err == nil && h.fields != nil {
+				rows = body
+			}
+		}
+		parents = append(parents, indent)
+	}
+	return comments
+}
+
+// tree is a document read by parseTree. Unlike the values returned by
+// Parser it keeps key order, the text of primitives, array layouts,
+// comments and the line each value was read from.
+type tree struct {
+	root *node
+	// comments holds the comments before a root array or primitive; those
+	// of a root object belong to its fields.
+	comments []string
+	// trailing holds the comments after the last value.
+	trailing []string
+}
+
+type nodeKind int
+
+const (
+	primitiveNode nodeKind = iota
+	objectNode
+	arrayNode
+)
+
+type node struct {
+	kind nodeKind
+	// line is the 1-based line the node starts on.
+	line int
+	// raw is the text of a primitive as written.
+	raw string
+	// entries holds the fields of an object or the items of a list array.
+	entries []*entry
+
+	delim byte
+	// columns is nil unless the array is tabular.
+	columns []entry
+	rows    []*row
+	// list marks arrays of "- " items; other arrays without columns are
+	// inline, with the text of their values in values.
+	list   bool
+	values []string
+}
+
+// entry is a field of an object, or an item of a list array with an empty
+// key.
+type entry struct {
+	comments []string
+	// blank marks entries preceded by a blank line.
+	blank  bool
+	line   int
+	key    string
+	quoted bool
+	value  *node
+}
+
+type row struct {
+	// comments holds the comments before the row, which are indented no
+	// further than the header of the array.
+	comments []string
+	line     int
+	cells    []string
+}
+
+type treeParser struct {
+	lines []string
+	// comments marks the comment lines, as found by commentLines.
+	comments []bool
+	pos      int
+	pending  []string
+	blank    bool
+
+	// strict adds the checks of Validate and makes errors recoverable:
+	// they are collected in errs and reading resumes after the offending
+	// lines.
+	strict bool
+	errs   []error
+	// unit is the first indentation step seen, when strict.
+	unit int
+	// recount skips the check of declared array counts, for Format, which
+	// writes the number of items present.
+	recount bool
+}
+
+func newTreeParser(data []byte) *treeParser {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	return &treeParser{lines: lines, comments: commentLines(lines)}
+}
+
+func parseTree(data []byte) (*tree, error) {
+	return newTreeParser(data).document()
+}
+
+func (t *treeParser) document() (*tree, error) {
+	doc := &tree{}
+
+	if indent := t.peek(); indent >= 0 {
+		content := strings.TrimSpace(t.lines[t.pos])
+		_, _, ok := splitKey(content)
+		switch {
+		case content == keysDirective:
+			// As in Parser without Options.KeyDictionary.
+			err := t.errorf("document uses a key dictionary; decode it with Options.KeyDictionary")
+			if t.strict {
+				t.errs = append(t.errs, err)
+			}
+			return nil, err
+		case strings.HasPrefix(content, "["):
+			e, err := t.entry(indent, indent)
+			if err != nil {
+				return nil, err
+			}
+			doc.root, doc.comments = e.value, e.comments
+		case !ok:
+			doc.comments, _ = t.take()
+			t.checkValue(content)
+			doc.root = &node{kind: primitiveNode, line: t.pos + 1, raw: content}
+			t.pos++
+		default:
+			line := t.pos + 1
+			entries, err := t.object(indent)
+			if err != nil {
+				return nil, err
+			}
+			doc.root = &node{kind: objectNode, line: line, entries: entries}
+		}
+	}
+
+	if t.peek() >= 0 {
+		msg := "unexpected line after the root value"
+		if doc.root.kind == objectNode {
+			msg = "unexpected indentation"
+		}
+		if err := t.report(t.errorf("%s", msg)); err != nil {
+			return nil, err
+		}
+		for t.peek() >= 0 {
+			t.pos++
+		}
+	}
+	doc.trailing = t.pending
+	return doc, nil
+}
+
+// errorf returns a SyntaxError for the current line.
+func (t *treeParser) errorf(format string, args ...interface{}) *SyntaxError {
+	return t.errorAt(t.pos, format, args...)
+}
+
+// errorAt returns a SyntaxError for the line at index.
+func (t *treeParser) errorAt(index int, format string, args ...interface{}) *SyntaxError {
+	line := t.lines[index]
+	return &SyntaxError{
+		Line:   index + 1,
+		Column: len(line) - len(strings.TrimLeft(line, " ")) + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// report returns err, or records it and returns nil when strict so that
+// the caller can recover.
+func (t *treeParser) report(err *SyntaxError) error {
+	if !t.strict {
+		return err
+	}
+	t.errs = append(t.errs, err)
+	return nil
+}
+
+// skip moves past the current line and the lines nested below it, which
+// are indented beyond indent.
+func (t *treeParser) skip(indent int) {
+	t.pos++
+	for t.peek() > indent {
+		t.pos++
+	}
+}
+
+// checkIndent reports, when strict, a nested block whose indentation step
+// differs from the first one in the document.
+func (t *treeParser) checkIndent(next, parent int) {
+	if !t.strict {
+		return
+	}
+	step := next - parent
+	switch {
+	case t.unit == 0:
+		t.unit = step
+	case step != t.unit:
+		t.errs = append(t.errs, t.errorf("indentation of %d spaces, expected %d", step, t.unit))
+	}
+}
+
+// checkValue reports, when strict, a quoted value on the current line that
+// is unterminated or followed by other text.
+func (t *treeParser) checkValue(raw string) {
+	if !t.strict || !strings.HasPrefix(raw, "\"") {
+		return
+	}
+	msg := ""
+	switch end := closingQuote(raw); {
+	case end == -1:
+		msg = "unterminated string"
+	case end != len(raw)-1:
+		msg = "unexpected text after string"
+	default:
+		return
+	}
+	line := t.lines[t.pos]
+	t.errs = append(t.errs, &SyntaxError{
+		Line:   t.pos + 1,
+		Column: strings.Index(line, raw) + 1,
+		Msg:    msg,
+	})
+}
+
+// peek moves to the next content line, collecting the comments before it,
+// and returns its indentation, or -1 at the end of the document.
+func (t *treeParser) peek() int {
+	for ; t.pos < len(t.lines); t.pos++ {
+		line := strings.TrimRight(t.lines[t.pos], " \r")
+		content := strings.TrimLeft(line, " ")
+		switch {
+		case content == "":
+			t.blank = true
+		case t.comments[t.pos]:
+			t.pending = append(t.pending, content)
+		default:
+			return len(line) - len(content)
+		}
+	}
+	return -1
+}
+
+// take returns and clears the comments and blank line before the current
+// line.
+func (t *treeParser) take() ([]string, bool) {
+	comments, blank := t.pending, t.blank
+	t.pending, t.blank = nil, false
+	return comments, blank
+}
+
+// object reads the fields at indent.
+func (t *treeParser) object(indent int) ([]*entry, error) {
+	var entries []*entry
+	var seen map[string]bool
+	if t.strict {
+		seen = make(map[string]bool)
+	}
+	for {
+		next := t.peek()
+		if next < indent {
+			return entries, nil
+		}
+		if next > indent {
+			if err := t.report(t.errorf("unexpected indentation")); err != nil {
+				return nil, err
+			}
+			t.skip(indent)
+			continue
+		}
+		index := t.pos
+		e, err := t.entry(indent, indent)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			continue
+		}
+		if seen != nil {
+			if seen[e.key] {
+				t.errs = append(t.errs, t.errorAt(index, "duplicate key %q", e.key))
+			}
+			seen[e.key] = true
+		}
+		entries = append(entries, e)
+	}
+}
+
+// entry reads the field on the current line, whose content starts at
+// column start and whose nested lines must be indented beyond indent. It
+// returns nil when strict and the field was skipped after an error.
+func (t *treeParser) entry(start, indent int) (*entry, error) {
+	comments, blank := t.take()
+	content := strings.TrimSpace(t.lines[t.pos][start:])
+	e, err := t.field(content, indent)
+	if e == nil {
+		return nil, err
+	}
+	e.comments, e.blank = comments, blank
+	return e, nil
+}
+
+// field reads a field whose text is content and advances past it.
+func (t *treeParser) field(content string, indent int) (*entry, error) {
+	key, rest, ok := splitKey(content)
+	if !ok {
+		if err := t.report(t.errorf("expected a key")); err != nil {
+			return nil, err
+		}
+		t.skip(indent)
+		return nil, nil
+	}
+	e := &entry{line: t.pos + 1, key: key, quoted: strings.HasPrefix(content, "\"")}
+
+	if strings.HasPrefix(rest, "[") {
+		value, err := t.array(content, indent)
+		if err != nil {
+			return nil, err
+		}
+		e.value = value
+		return e, nil
+	}
+
+	if value := strings.TrimSpace(rest[1:]); value != "" {
+		t.checkValue(value)
+		e.value = &node{kind: primitiveNode, line: e.line, raw: value}
+		t.pos++
+		return e, nil
+	}
+
+	t.pos++
+	e.value = &node{kind: objectNode, line: e.line}
+	if next := t.peek(); next > indent {
+		t.checkIndent(next, indent)
+		entries, err := t.object(next)
+		if err != nil {
+			return nil, err
+		}
+		e.value.entries = entries
+	}
+	return e, nil
+}
+
+// array reads the array whose header is content and the rows or items
+// indented beyond indent below it. Counts that differ from the items
+// present are reported at the header.
+func (t *treeParser) array(content string, indent int) (*node, error) {
+	header := t.pos
+	n := &node{kind: arrayNode, line: header + 1, delim: ','}
+	h, err := parseArrayHeader(content)
+	if err != nil {
+		if err := t.report(t.errorf("%v", err)); err != nil {
+			return nil, err
+		}
+		t.skip(indent)
+		return n, nil
+	}
+	n.delim = h.delim
+
+	switch {
+	case h.fields != nil:
+		if h.inline != "" {
+			if err := t.report(t.errorf("unexpected values after tabular header")); err != nil {
+				return nil, err
+			}
+		}
+		t.pos++
+		n.columns = make([]entry, len(h.fields))
+		for i, field := range h.fields {
+			n.columns[i] = entry{line: n.line, key: field, quoted: h.quoted[i]}
+		}
+		// read counts the rows skipped after errors too, so that they do
+		// not also make the count mismatch.
+		read := 0
+		for {
+			next := t.peek()
+			if next <= indent {
+				break
+			}
+			t.checkIndent(next, indent)
+			line := t.lines[t.pos][next:]
+			if h.delim == '\t' {
+				line = strings.Trim(line, " \r")
+			} else {
+				line = strings.TrimSpace(line)
+			}
+			cells := splitTabularValues(line, h.delim)
+			comments, _ := t.take()
+			read++
+			if len(cells) != len(n.columns) {
+				if err := t.report(t.errorf("row has %d fields, header has %d", len(cells), len(n.columns))); err != nil {
+					return nil, err
+				}
+				t.pos++
+				continue
+			}
+			for _, cell := range cells {
+				t.checkValue(cell)
+			}
+			n.rows = append(n.rows, &row{comments: comments, line: t.pos + 1, cells: cells})
+			t.pos++
+		}
+		if err := t.checkCount(header, h.count, read); err != nil {
+			return nil, err
+		}
+
+	case h.inline != "":
+		n.values = splitTabularValues(h.inline, h.delim)
+		for _, value := range n.values {
+			t.checkValue(value)
+		}
+		if err := t.checkCount(header, h.count, len(n.values)); err != nil {
+			return nil, err
+		}
+		t.pos++
+
+	default:
+		t.pos++
+		for {
+			next := t.peek()
+			if next <= indent {
+				break
+			}
+			t.checkIndent(next, indent)
+			item, err := t.item(next, next-indent)
+			if err != nil {
+				return nil, err
+			}
+			n.list = true
+			if item != nil {
+				n.entries = append(n.entries, item)
+			}
+		}
+		if err := t.checkCount(header, h.count, len(n.entries)); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// checkCount reports an array whose header at index declares a count other
+// than found.
+func (t *treeParser) checkCount(index, declared, found int) error {
+	if declared == found || t.recount {
+		return nil
+	}
+	return t.report(t.errorAt(index, "array count mismatch: declared %d, found %d", declared, found))
+}
+
+// item reads the list item on the current line. As in Parser, the content
+// after the marker counts as indented by unit beyond the marker.
+func (t *treeParser) item(indent, unit int) (*entry, error) {
+	content := strings.TrimSpace(t.lines[t.pos])
+	if content != "-" && !strings.HasPrefix(content, "- ") {
+		if err := t.report(t.errorf("expected list item, got %q", content)); err != nil {
+			return nil, err
+		}
+		t.skip(indent)
+		return nil, nil
+	}
+	comments, blank := t.take()
+	e := &entry{comments: comments, blank: blank, line: t.pos + 1}
+
+	body := strings.TrimSpace(content[1:])
+	bodyIndent := indent + unit
+	_, _, isField := splitKey(body)
+	var err error
+	switch {
+	case body == "":
+		t.pos++
+		e.value = &node{kind: objectNode, line: e.line}
+	case strings.HasPrefix(body, "["):
+		e.value, err = t.array(body, bodyIndent)
+	case isField:
+		var first *entry
+		if first, err = t.field(body, bodyIndent); err != nil {
+			return nil, err
+		}
+		var rest []*entry
+		if rest, err = t.object(bodyIndent); err != nil {
+			return nil, err
+		}
+		if first != nil {
+			rest = append([]*entry{first}, rest...)
+		}
+		e.value = &node{kind: objectNode, line: e.line, entries: rest}
+	default:
+		t.checkValue(body)
+		e.value = &node{kind: primitiveNode, line: e.line, raw: body}
+		t.pos++
+	}
+	return e, err
+}
@@ -0,0 +1,65 @@
+package decoder
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+)
+
+// Validate checks a document in strict mode and returns every error found
+// rather than only the first, ordered by line. Syntax errors, array counts
+// that differ from the items present, duplicate keys, inconsistent
+// indentation and unterminated strings are reported as *SyntaxError. When
+// schema is not nil and the document is well formed, values that do not
+// satisfy it are reported as *SchemaError. The result is nil for a valid
+// document.
+//
+// After an error Validate skips the offending line and the lines nested
+// below it, so one mistake may hide others within the skipped block.
+func Validate(data []byte, schema *Schema) []error {
+	t := newTreeParser(data)
+	t.strict = true
+	doc, _ := t.document()
+
+	errs := t.errs
+	if len(errs) == 0 {
+		// Parse as Unmarshal would, so that nothing the tree accepts is
+		// rejected later.
+		if _, err := NewParser(bytes.NewReader(data)).Parse(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 && schema != nil {
+		root := doc.root
+		if root == nil {
+			root = &node{kind: objectNode, line: 1}
+		}
+		c := &schemaChecker{lines: t.lines}
+		c.check(schema, root, "")
+		errs = c.errs
+	}
+
+	slices.SortStableFunc(errs, func(a, b error) int {
+		la, ca := errorPosition(a)
+		lb, cb := errorPosition(b)
+		if la != lb {
+			return la - lb
+		}
+		return ca - cb
+	})
+	return errs
+}
+
+// errorPosition returns the line and column of a SyntaxError or
+// SchemaError.
+func errorPosition(err error) (line, column int) {
+	var serr *SyntaxError
+	if errors.As(err, &serr) {
+		return serr.Line, serr.Column
+	}
+	var scerr *SchemaError
+	if errors.As(err, &scerr) {
+		return scerr.Line, scerr.Column
+	}
+	return 0, 0
+}
@@ -0,0 +1,165 @@
+package decoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []SyntaxError
+	}{
+		{
+			name:  "valid",
+			input: "name: \"Ada\"\ntags[2]: \"a\",\"b\"\n",
+		},
+		{
+			name: "every error",
+			input: `a: 1
+a: 2
+users[3]{id,name}:
+  1,"Ada"
+  2
+  3,"Linus
+items[1]:
+  - 1
+  - 2
+b:
+    c: 1
+`,
+			want: []SyntaxError{
+				{Line: 2, Column: 1, Msg: `duplicate key "a"`},
+				{Line: 5, Column: 3, Msg: "row has 1 fields, header has 2"},
+				{Line: 6, Column: 5, Msg: "unterminated string"},
+				{Line: 7, Column: 1, Msg: "array count mismatch: declared 1, found 2"},
+				{Line: 11, Column: 5, Msg: "indentation of 4 spaces, expected 2"},
+			},
+		},
+		{
+			name:  "recovery",
+			input: "a: 1\n   b: 2\n     c: 3\nplain\nd: \"x\"y\n",
+			want: []SyntaxError{
+				{Line: 2, Column: 4, Msg: "unexpected indentation"},
+				{Line: 4, Column: 1, Msg: "expected a key"},
+				{Line: 5, Column: 4, Msg: "unexpected text after string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate([]byte(tt.input), nil)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.want), errs)
+			}
+			for i, err := range errs {
+				var serr *SyntaxError
+				if !errors.As(err, &serr) || *serr != tt.want[i] {
+					t.Errorf("Error %d: expected %+v, got %v", i, tt.want[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schemas := map[string]string{
+		"json": `{
+  "type": "object",
+  "required": ["name", "users"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "age": {"type": "integer", "minimum": 0},
+    "tags": {"type": "array", "maxItems": 2, "items": {"pattern": "^[a-z]+$"}},
+    "users": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["id", "email"],
+        "properties": {"id": {"type": "integer"}, "role": {"enum": ["admin", "dev"]}}
+      }
+    }
+  }
+}`,
+		"toon": `type: "object"
+required[2]: "name","users"
+additionalProperties: false
+properties:
+  name:
+    type: "string"
+    minLength: 1
+  age:
+    type: "integer"
+    minimum: 0
+  tags:
+    type: "array"
+    maxItems: 2
+    items:
+      pattern: "^[a-z]+$"
+  users:
+    type: "array"
+    items:
+      type: "object"
+      required[2]: "id","email"
+      properties:
+        id:
+          type: "integer"
+        role:
+          enum[2]: "admin","dev"
+`,
+	}
+
+	input := `age: -1
+extra: true
+tags[3]: "ok","No","x"
+users[2]{id,role}:
+  1,"admin"
+  "two","boss"
+`
+	want := []SchemaError{
+		{Line: 1, Column: 1, Msg: `missing required field "name"`},
+		{Line: 1, Column: 1, Path: "age", Msg: "-1 is less than the minimum 0"},
+		{Line: 2, Column: 1, Msg: `unexpected field "extra"`},
+		{Line: 3, Column: 1, Path: "tags", Msg: "has 3 items, more than 2"},
+		{Line: 3, Column: 1, Path: "tags[1]", Msg: `"No" does not match pattern "^[a-z]+$"`},
+		{Line: 4, Column: 1, Path: "users[*]", Msg: `missing required field "email"`},
+		{Line: 6, Column: 3, Path: "users[1].id", Msg: "expected integer, got string"},
+		{Line: 6, Column: 3, Path: "users[1].role", Msg: `value "boss" is not one of ["admin","dev"]`},
+	}
+
+	for name, text := range schemas {
+		t.Run(name, func(t *testing.T) {
+			schema, err := ParseSchema([]byte(text))
+			if err != nil {
+				t.Fatalf("ParseSchema failed: %v", err)
+			}
+			errs := Validate([]byte(input), schema)
+			if len(errs) != len(want) {
+				t.Fatalf("Expected %d errors, got %v", len(want), errs)
+			}
+			for i, err := range errs {
+				var serr *SchemaError
+				if !errors.As(err, &serr) || *serr != want[i] {
+					t.Errorf("Error %d: expected %+v, got %v", i, want[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := map[string]string{
+		`{"type": "text"}`:                        `schema: type: unknown type "text"`,
+		`{"properties": {"a": {"minItems": -1}}}`: "schema: properties.a.minItems: must be a non-negative integer",
+		`{"pattern": "("}`:                        "schema: pattern: error parsing regexp: missing closing ): `(`",
+		`"text"`:                                  "schema: must be an object",
+	}
+	for input, want := range tests {
+		if _, err := ParseSchema([]byte(input)); err == nil || err.Error() != want {
+			t.Errorf("%s: expected %q, got %v", input, want, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package encoder
+
+import (
+	"reflect"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// The functions in this file expose the encoder's building blocks to code
+// generated by cmd/toongen, which writes TOON without reflection.
+
+// AppendString appends s as a quoted TOON string.
+func AppendString(dst []byte, s string) []byte {
+	return types.AppendString(dst, s)
+}
+
+// AppendKey appends an object key, quoting it only when necessary.
+func AppendKey(dst []byte, key string) []byte {
+	return types.AppendKey(dst, key)
+}
+
+// AppendFloat appends f the way the encoder formats floats of the given bit
+// size, writing null for NaN and infinities.
+func AppendFloat(dst []byte, f float64, bits int) []byte {
+	return types.AppendFloat(dst, f, bits)
+}
+
+// AppendField appends key and the reflection-based encoding of v at depth,
+// using the default options. Nested values that implement Marshaler are
+// spliced in and re-indented.
+func AppendField(dst []byte, key string, v interface{}, depth int) ([]byte, error) {
+	s := newEncodeState(&defaultOptions)
+	scratch := s.buf
+	s.buf = dst
+
+	err := s.encodeField(key, reflect.ValueOf(v), depth)
+
+	out := s.buf
+	s.buf = scratch
+	s.release()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AppendListItem appends v as one "- " item of a list array at depth, the
+// depth of the item marker. A nil opts uses the same defaults as Append.
+func AppendListItem(dst []byte, v interface{}, depth int, opts *Options) ([]byte, error) {
+	if opts == nil {
+		opts = &defaultOptions
+	}
+	s := newEncodeState(opts)
+	scratch := s.buf
+	s.buf = dst
+
+	err := s.encodeListItem(reflect.ValueOf(v), depth)
+
+	out := s.buf
+	s.buf = scratch
+	s.release()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
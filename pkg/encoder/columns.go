@@ -0,0 +1,74 @@
+package encoder
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// flattenRows returns the elements of rv as rows whose nested objects are
+// flattened into dotted columns, such as "customer.id", for
+// Options.DottedColumns. It fails unless every element is an object of
+// primitives and non-empty nested objects of primitives with the same
+// columns, and unless every key of a flattened object is an identifier, so
+// that decoders can split the columns unambiguously. Keys containing dots
+// are never flattened.
+func (s *encodeState) flattenRows(rv reflect.Value) (reflect.Value, bool) {
+	rows := make([]types.Object, rv.Len())
+	for i := range rows {
+		item, m := resolve(rv.Index(i))
+		if m != nil || !isObjectValue(item) {
+			return reflect.Value{}, false
+		}
+		row, ok := flattenObject(nil, "", item)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		rows[i] = row
+	}
+
+	flat := reflect.ValueOf(rows)
+	if !s.shouldUseTabularFormat(flat) {
+		return reflect.Value{}, false
+	}
+	return flat, true
+}
+
+// flattenObject appends the columns of the object rv to dst, prefixing
+// their keys with prefix.
+func flattenObject(dst types.Object, prefix string, rv reflect.Value) (types.Object, bool) {
+	ok := true
+	count := 0
+	eachField(rv, func(key string, value reflect.Value) {
+		count++
+		if !ok {
+			return
+		}
+		if strings.Contains(key, ".") || prefix != "" && !isIdentifier(key) {
+			ok = false
+			return
+		}
+
+		value, m := resolve(value)
+		switch {
+		case m != nil:
+			ok = false
+		case isObjectValue(value):
+			if !isIdentifier(key) {
+				ok = false
+				return
+			}
+			dst, ok = flattenObject(dst, prefix+key+".", value)
+		case value.Kind() == reflect.Slice || value.Kind() == reflect.Array:
+			ok = false
+		case !value.IsValid():
+			dst = append(dst, types.Member{Key: prefix + key})
+		case value.CanInterface():
+			dst = append(dst, types.Member{Key: prefix + key, Value: value.Interface()})
+		default:
+			ok = false
+		}
+	})
+	return dst, ok && count > 0
+}
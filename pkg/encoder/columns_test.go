@@ -0,0 +1,93 @@
+package encoder
+
+import (
+	"testing"
+)
+
+type columnsCustomer struct {
+	ID   int    `toon:"id"`
+	Name string `toon:"name"`
+}
+
+type columnsOrder struct {
+	ID       int             `toon:"id"`
+	Customer columnsCustomer `toon:"customer"`
+	Total    float64         `toon:"total"`
+}
+
+func TestDottedColumns(t *testing.T) {
+	orders := []columnsOrder{
+		{1, columnsCustomer{7, "Ada"}, 9.5},
+		{2, columnsCustomer{8, "Linus"}, 12},
+	}
+
+	opts := DefaultOptions()
+	opts.DottedColumns = true
+	got, err := Append(nil, map[string]interface{}{"orders": orders}, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	want := `orders[2]{id,customer.id,customer.name,total}:
+  1,7,"Ada",9.5
+  2,8,"Linus",12
+`
+	if string(got) != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+
+	// Without the option the nested objects keep the list layout.
+	got, err = Append(nil, map[string]interface{}{"orders": orders}, nil)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if string(got) == want {
+		t.Error("Expected dotted columns to be opt-in")
+	}
+}
+
+func TestDottedColumnsFallback(t *testing.T) {
+	opts := DefaultOptions()
+	opts.DottedColumns = true
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{
+			name: "different columns",
+			value: []map[string]interface{}{
+				{"id": 1, "meta": map[string]int{"a": 1}},
+				{"id": 2, "meta": map[string]int{"b": 2}},
+			},
+			want: "[2]:\n  - id: 1\n    meta:\n      a: 1\n  - id: 2\n    meta:\n      b: 2\n",
+		},
+		{
+			name: "nested array",
+			value: []map[string]interface{}{
+				{"id": 1, "meta": map[string]interface{}{"tags": []int{1}}},
+				{"id": 2, "meta": map[string]interface{}{"tags": []int{2}}},
+			},
+			want: "[2]:\n  - id: 1\n    meta:\n      tags[1]: 1\n  - id: 2\n    meta:\n      tags[1]: 2\n",
+		},
+		{
+			name: "literal dotted key is quoted",
+			value: []map[string]interface{}{
+				{"a.b": 1, "c": 2},
+				{"a.b": 3, "c": 4},
+			},
+			want: "[2]{\"a.b\",c}:\n  1,2\n  3,4\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Append(nil, tt.value, &opts)
+			if err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package encoder
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// keysDirective opens the key dictionary block written with
+// Options.KeyDictionary. It has no colon, so decoders that do not support
+// the extension reject the document instead of misreading it.
+const keysDirective = "%keys"
+
+// encodeRootWithDictionary encodes rv twice: the first pass counts how often
+// each key is written, the second writes the dictionary block followed by
+// the document with aliases in place of the keys worth replacing.
+func (s *encodeState) encodeRootWithDictionary(rv reflect.Value) error {
+	start := len(s.buf)
+	s.keyCounts = map[string]int{}
+	err := s.encodeRoot(rv)
+	counts := s.keyCounts
+	s.keyCounts = nil
+	s.buf = s.buf[:start]
+	if err != nil {
+		return err
+	}
+
+	s.aliases, s.buf = s.appendDictionary(s.buf, counts)
+	return s.encodeRoot(rv)
+}
+
+// appendDictionary chooses aliases for the keys whose replacement saves more
+// bytes than their dictionary entry costs and appends the dictionary block.
+// The most valuable keys get the shortest aliases. Nothing is appended when
+// no key is worth an alias.
+func (s *encodeState) appendDictionary(dst []byte, counts map[string]int) (map[string]string, []byte) {
+	type keyUse struct {
+		key   string
+		count int
+		size  int
+	}
+	uses := make([]keyUse, 0, len(counts))
+	for key, count := range counts {
+		uses = append(uses, keyUse{key: key, count: count, size: len(types.AppendKey(nil, key))})
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		a, b := uses[i].count*uses[i].size, uses[j].count*uses[j].size
+		if a != b {
+			return a > b
+		}
+		return uses[i].key < uses[j].key
+	})
+
+	var aliases map[string]string
+	var block []byte
+	saved := 0
+	for _, u := range uses {
+		alias := "@" + strconv.FormatInt(int64(len(aliases)), 36)
+		entry := s.opts.Indent + alias + ": "
+		entry = string(types.AppendString([]byte(entry), u.key)) + "\n"
+		if gain := u.count*(u.size-len(alias)) - len(entry); gain > 0 {
+			if aliases == nil {
+				aliases = map[string]string{}
+			}
+			aliases[u.key] = alias
+			block = append(block, entry...)
+			saved += gain
+		}
+	}
+	if saved <= len(keysDirective)+1 {
+		return nil, dst
+	}
+
+	dst = append(dst, keysDirective+"\n"...)
+	return aliases, append(dst, block...)
+}
+
+// dictionaryKey counts key during the first pass of Options.KeyDictionary
+// and writes its alias during the second. It reports whether it wrote the
+// key. Dotted keys are never aliased when keys may be folded, because their
+// quoting decides whether decoders expand them.
+func (s *encodeState) dictionaryKey(key string) bool {
+	if s.keyCounts != nil {
+		if !s.opts.dottedPaths() || !strings.Contains(key, ".") {
+			s.keyCounts[key]++
+		}
+		return false
+	}
+	if alias, ok := s.aliases[key]; ok {
+		s.buf = append(s.buf, alias...)
+		return true
+	}
+	return false
+}
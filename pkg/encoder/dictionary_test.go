@@ -0,0 +1,67 @@
+package encoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyDictionary(t *testing.T) {
+	type event struct {
+		Duration int `toon:"requestDurationMilliseconds"`
+		Status   int `toon:"status"`
+	}
+	type span struct {
+		Attrs  map[string]int `toon:"attributes"`
+		Events []event        `toon:"events"`
+	}
+	spans := make([]span, 3)
+	for i := range spans {
+		spans[i] = span{
+			Attrs:  map[string]int{"requestDurationMilliseconds": i},
+			Events: []event{{1, 200}, {2, 404}},
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.KeyDictionary = true
+	got, err := Append(nil, map[string]interface{}{"spans": spans}, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	want := `%keys
+  @0: "requestDurationMilliseconds"
+  @1: "attributes"
+spans[3]:
+  - @1:
+      @0: 0
+    events[2]{@0,status}:
+      1,200
+      2,404
+  - @1:
+      @0: 1
+    events[2]{@0,status}:
+      1,200
+      2,404
+  - @1:
+      @0: 2
+    events[2]{@0,status}:
+      1,200
+      2,404
+`
+	if string(got) != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestKeyDictionaryNotWorthIt(t *testing.T) {
+	opts := DefaultOptions()
+	opts.KeyDictionary = true
+	got, err := Append(nil, map[string]int{"requestDurationMilliseconds": 1}, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if strings.Contains(string(got), "%keys") {
+		t.Errorf("Expected no dictionary for a single use, got:\n%s", got)
+	}
+}
@@ -1,19 +1,121 @@
 package encoder
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/devalexandre/toon-go/pkg/types"
 )
 
 type Options struct {
-	Indent         string
+	Indent string
+	// ForceTabular writes every non-empty array of objects holding only
+	// primitives as a tabular array over the union of their keys, whatever
+	// its length, TokenOptimized and TabularThreshold.
 	ForceTabular   bool
 	MaxArraySize   int
 	TokenOptimized bool
+	// Delimiter separates inline array values and tabular fields. It is one
+	// of ',' (the default when zero), '\t' or '|'; the latter two are
+	// declared in array headers as in "users[2|]{id|name}:".
+	Delimiter byte
+	// KeyFolding writes chains of single-key objects as dotted paths, so
+	// {"a": {"b": {"c": 1}}} becomes "a.b.c: 1". Literal keys containing
+	// dots are quoted. Decoders need decoder.Options.ExpandPaths to rebuild
+	// the nesting.
+	KeyFolding bool
+	// AutoLayout estimates the Cost of every candidate layout of each array
+	// (inline, tabular or list, with each delimiter) and of each chain of
+	// single-key objects (folded or nested) and emits the cheapest. It
+	// implies the key quoting of KeyFolding, and the output only round-trips
+	// when the decoder sets decoder.Options.ExpandPaths: without it, folded
+	// chains come back as literal dotted keys such as "a.b.c".
+	AutoLayout bool
+	// Cost estimates the cost of an encoded fragment for AutoLayout. Nil
+	// counts tokens with tokens.Default.
+	Cost func(fragment []byte) int
+	// KeyDictionary replaces long keys that are written many times with
+	// short aliases such as "@0", declared in a "%keys" block at the top of
+	// the document. This is an extension to TOON: only decoders with
+	// decoder.Options.KeyDictionary accept such documents, and others
+	// reject them.
+	KeyDictionary bool
+	// Include, when not empty, keeps only the fields whose paths match one
+	// of its patterns, along with everything below them. Exclude drops the
+	// fields matching any of its patterns. Paths join keys with dots and
+	// mark array elements with "[]", as in "users[].email"; in patterns "*"
+	// matches within a key, "**" matches any number of keys and "[]" may be
+	// omitted. Both apply to maps, structs and tabular columns alike.
+	Include []string
+	Exclude []string
+	// DottedColumns writes arrays of objects whose nested objects hold only
+	// primitives as tabular arrays with one dotted column per nested field,
+	// as in "orders[2]{id,customer.id,customer.name}:", when every element
+	// has the same columns. Literal keys containing dots are quoted.
+	// Decoders need decoder.Options.ExpandPaths to rebuild the nesting.
+	DottedColumns bool
+	// TabularThreshold writes arrays of objects of primitives whose keys
+	// differ as tabular arrays over the union of their keys when at least
+	// this fraction of the cells hold a value, so 0.8 accepts tables with up
+	// to one cell in five missing. Missing cells are written as null, or
+	// left empty with MarkAbsent. Zero requires every object to have the
+	// same keys.
+	TabularThreshold float64
+	// MarkAbsent leaves the cells of keys missing from an object empty
+	// instead of writing null, as in "3,,true", so that decoders with
+	// decoder.Options.DropAbsent can leave the keys out again. Other
+	// decoders read empty cells as empty strings. Tables with a single
+	// column still write null, since an empty row would be a blank line.
+	MarkAbsent bool
+}
+
+// Delimiters accepted by Options.Delimiter.
+const (
+	Comma = ','
+	Tab   = '\t'
+	Pipe  = '|'
+)
+
+// delimiter returns the configured delimiter, defaulting to a comma.
+func (o *Options) delimiter() byte {
+	if o.Delimiter == 0 {
+		return Comma
+	}
+	return o.Delimiter
+}
+
+// dottedPaths reports whether decoders are expected to expand dotted keys,
+// in which case literal keys containing dots must be quoted.
+func (o *Options) dottedPaths() bool {
+	return o.KeyFolding || o.AutoLayout || o.DottedColumns
+}
+
+// appendCount appends an array length and, for non-comma delimiters, the
+// delimiter marker, leaving the closing bracket to the caller.
+func appendCount(dst []byte, n int, delim byte) []byte {
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	if delim != Comma {
+		dst = append(dst, delim)
+	}
+	return dst
+}
+
+var defaultOptions = Options{
+	Indent:         "  ",
+	MaxArraySize:   1000,
+	TokenOptimized: true,
+}
+
+// DefaultOptions returns the options used when nil is passed to NewEncoder
+// or Append, as a starting point for overriding single settings.
+func DefaultOptions() Options {
+	return defaultOptions
 }
 
 type Encoder struct {
@@ -23,11 +125,8 @@ type Encoder struct {
 
 func NewEncoder(w io.Writer, opts *Options) *Encoder {
 	if opts == nil {
-		opts = &Options{
-			Indent:         "  ",
-			MaxArraySize:   1000,
-			TokenOptimized: true,
-		}
+		defaults := defaultOptions
+		opts = &defaults
 	}
 	return &Encoder{
 		writer: w,
@@ -35,423 +134,660 @@ func NewEncoder(w io.Writer, opts *Options) *Encoder {
 	}
 }
 
+// Encode writes the TOON encoding of v to the underlying writer with a
+// single Write call.
 func (e *Encoder) Encode(v interface{}) error {
-	return e.encodeValue(v, 0, "")
-}
+	s := newEncodeState(e.opts)
+	defer s.release()
 
-func (e *Encoder) encodeValue(v interface{}, depth int, fieldName string) error {
-	if v == nil {
-		_, err := e.writer.Write([]byte("null"))
+	if err := s.encode(reflect.ValueOf(v)); err != nil {
 		return err
 	}
+	_, err := e.writer.Write(s.buf)
+	return err
+}
 
-	rv := reflect.ValueOf(v)
-	kind := rv.Kind()
-
-	if kind == reflect.Ptr {
-		if rv.IsNil() {
-			_, err := e.writer.Write([]byte("null"))
-			return err
-		}
-		return e.encodeValue(rv.Elem().Interface(), depth, fieldName)
+// Append appends the TOON encoding of v to dst and returns the extended
+// buffer. A nil opts uses the same defaults as NewEncoder.
+func Append(dst []byte, v interface{}, opts *Options) ([]byte, error) {
+	if opts == nil {
+		opts = &defaultOptions
 	}
+	s := newEncodeState(opts)
+	scratch := s.buf
+	s.buf = dst
 
-	switch kind {
-	case reflect.Bool:
-		var err error
-		if rv.Bool() {
-			_, err = e.writer.Write([]byte("true"))
-		} else {
-			_, err = e.writer.Write([]byte("false"))
-		}
-		return err
+	err := s.encode(reflect.ValueOf(v))
 
-	case reflect.String:
-		str := rv.String()
-		if e.needsQuotes(str) {
-			_, err := e.writer.Write([]byte(fmt.Sprintf("\"%s\"", str)))
-			return err
-		}
-		_, err := e.writer.Write([]byte(str))
-		return err
+	out := s.buf
+	s.buf = scratch
+	s.release()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		_, err := e.writer.Write([]byte(strconv.FormatInt(rv.Int(), 10)))
-		return err
+// encodeState holds the scratch space for a single encoding pass. States are
+// pooled so that steady-state encoding only allocates for the output itself.
+type encodeState struct {
+	buf        []byte
+	opts       *Options
+	entries    []mapEntry
+	fields     []string
+	keys       []reflect.Value
+	skipIndent bool
+	bareKey    bool
+	// dottedHeader marks the next tabular header as holding columns
+	// flattened by flattenRows.
+	dottedHeader bool
+	keyCounts    map[string]int
+	aliases      map[string]string
+}
 
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		_, err := e.writer.Write([]byte(strconv.FormatUint(rv.Uint(), 10)))
-		return err
+type mapEntry struct {
+	key   string
+	value reflect.Value
+}
 
-	case reflect.Float32, reflect.Float64:
-		str := strconv.FormatFloat(rv.Float(), 'g', -1, 64)
-		_, err := e.writer.Write([]byte(str))
-		return err
+// maxPooledBuffer keeps a single huge document from pinning memory in the pool.
+const maxPooledBuffer = 64 << 10
 
-	case reflect.Slice, reflect.Array:
-		return e.encodeArray(rv, depth, fieldName)
+var statePool = sync.Pool{
+	New: func() interface{} { return new(encodeState) },
+}
 
-	case reflect.Map:
-		return e.encodeMap(rv, depth, fieldName)
+func newEncodeState(opts *Options) *encodeState {
+	s := statePool.Get().(*encodeState)
+	s.opts = opts
+	s.buf = s.buf[:0]
+	return s
+}
 
-	case reflect.Struct:
-		return e.encodeStruct(rv, depth, fieldName)
+func (s *encodeState) release() {
+	if cap(s.buf) > maxPooledBuffer {
+		s.buf = nil
+	}
+	clear(s.entries[:cap(s.entries)])
+	clear(s.keys[:cap(s.keys)])
+	s.entries = s.entries[:0]
+	s.keys = s.keys[:0]
+	s.fields = s.fields[:0]
+	s.opts = nil
+	s.skipIndent = false
+	s.bareKey = false
+	s.dottedHeader = false
+	s.keyCounts = nil
+	s.aliases = nil
+	statePool.Put(s)
+}
 
-	default:
-		str := fmt.Sprintf("%v", v)
-		if e.needsQuotes(str) {
-			_, err := e.writer.Write([]byte(fmt.Sprintf("\"%s\"", str)))
-			return err
+// indirect follows pointers and interfaces, returning the zero Value for nil.
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
 		}
-		_, err := e.writer.Write([]byte(str))
-		return err
+		rv = rv.Elem()
 	}
+	return rv
 }
 
-func (e *Encoder) needsQuotes(s string) bool {
-	if s == "" {
-		return true
+// encode writes the document for rv, applying the field projection and key
+// dictionary when enabled.
+func (s *encodeState) encode(rv reflect.Value) error {
+	if p := newProjection(s.opts); p != nil {
+		rv = reflect.ValueOf(p.project(rv))
 	}
-	return true
+	if s.opts.KeyDictionary {
+		return s.encodeRootWithDictionary(rv)
+	}
+	return s.encodeRoot(rv)
 }
 
-func (e *Encoder) encodeArray(rv reflect.Value, depth int, fieldName string) error {
-	length := rv.Len()
-	indent := strings.Repeat(e.opts.Indent, depth)
-
-	if length == 0 {
-		if fieldName != "" {
-			_, err := fmt.Fprintf(e.writer, "%s%s[0]:\n", indent, fieldName)
+func (s *encodeState) encodeRoot(rv reflect.Value) error {
+	rv, m := resolve(rv)
+	if m != nil {
+		doc, err := marshalDocument(m)
+		if err != nil {
 			return err
 		}
-		_, err := fmt.Fprintf(e.writer, "%s[]\n", indent)
-		return err
+		s.buf = append(s.buf, doc...)
+		if !isPrimitiveLine(doc) {
+			s.buf = append(s.buf, '\n')
+		}
+		return nil
 	}
 
-	slice := make([]interface{}, length)
-	for i := 0; i < length; i++ {
-		slice[i] = rv.Index(i).Interface()
+	switch {
+	case isObjectValue(rv):
+		return s.encodeFields(rv, 0)
+	case rv.Kind() == reflect.Slice, rv.Kind() == reflect.Array:
+		return s.encodeArray("", false, rv, 0)
+	default:
+		s.buf = appendPrimitive(s.buf, rv)
+		return nil
 	}
+}
 
-	if e.opts.TokenOptimized && e.shouldUseTabularFormat(slice) {
-		return e.encodeTabularArray(slice, depth, fieldName)
+var (
+	orderedObjectType = reflect.TypeFor[types.Object]()
+	numberType        = reflect.TypeFor[json.Number]()
+)
+
+// isObjectValue reports whether rv is written as an object: a map, a struct
+// or a types.Object.
+func isObjectValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	case reflect.Slice:
+		return rv.Type() == orderedObjectType
 	}
+	return false
+}
 
-	if fieldName != "" {
-		if _, err := fmt.Fprintf(e.writer, "%s%s[%d]:\n", indent, fieldName, length); err != nil {
-			return err
-		}
+// encodeFields writes the fields of the object rv at depth.
+func (s *encodeState) encodeFields(rv reflect.Value, depth int) error {
+	switch rv.Kind() {
+	case reflect.Map:
+		return s.encodeMapFields(rv, depth)
+	case reflect.Struct:
+		return s.encodeStructFields(rv, depth)
+	default:
+		return s.encodeOrderedFields(rv.Interface().(types.Object), depth)
 	}
+}
 
-	for _, item := range slice {
-		if err := e.encodeValue(item, depth+1, ""); err != nil {
-			return err
+// eachField calls fn for every field the encoder would write for the
+// object rv, in encoding order.
+func eachField(rv reflect.Value, fn func(string, reflect.Value)) {
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(mapKeyString(a), mapKeyString(b))
+		})
+		for _, key := range keys {
+			fn(mapKeyString(key), rv.MapIndex(key))
 		}
-		if _, err := e.writer.Write([]byte("\n")); err != nil {
-			return err
+	case reflect.Struct:
+		info := types.CachedStruct(rv.Type())
+		for i := range info.Fields {
+			field := &info.Fields[i]
+			value := field.Value(rv)
+			if !value.IsValid() || field.OmitEmpty && types.IsEmptyValue(value) {
+				continue
+			}
+			fn(field.Name, value)
+		}
+	default:
+		for _, m := range rv.Interface().(types.Object) {
+			fn(m.Key, reflect.ValueOf(m.Value))
 		}
 	}
+}
 
+func (s *encodeState) encodeOrderedFields(obj types.Object, depth int) error {
+	for _, m := range obj {
+		if err := s.encodeField(m.Key, reflect.ValueOf(m.Value), depth); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (e *Encoder) shouldUseTabularFormat(slice []interface{}) bool {
-	if len(slice) < 2 {
-		return false
+func (s *encodeState) appendIndent(depth int) {
+	if s.skipIndent {
+		// The line was already started by a list item marker.
+		s.skipIndent = false
+		return
 	}
+	for i := 0; i < depth; i++ {
+		s.buf = append(s.buf, s.opts.Indent...)
+	}
+}
 
-	var firstFields []string
+// encodeField writes key and its value at depth, followed by any nested lines.
+func (s *encodeState) encodeField(key string, rv reflect.Value, depth int) error {
+	rv, m := resolve(rv)
+	if m != nil {
+		return s.encodeMarshaler(m, key, depth, false)
+	}
 
-	for i, item := range slice {
-		if obj, ok := item.(map[string]interface{}); ok {
-			for _, v := range obj {
-				if !e.isPrimitiveType(v) {
-					return false
-				}
+	switch {
+	case isObjectValue(rv):
+		if s.opts.KeyFolding || s.opts.AutoLayout {
+			if folded, err := s.encodeFolded(key, rv, depth); folded {
+				return err
 			}
+		}
+		s.appendIndent(depth)
+		s.appendFieldKey(key)
+		s.buf = append(s.buf, ":\n"...)
+		return s.encodeFields(rv, depth+1)
 
-			fields := make([]string, 0, len(obj))
-			for key := range obj {
-				fields = append(fields, key)
-			}
-			sort.Strings(fields)
+	case rv.Kind() == reflect.Slice, rv.Kind() == reflect.Array:
+		return s.encodeArray(key, true, rv, depth)
 
-			if i == 0 {
-				firstFields = fields
-			} else {
-				if len(fields) != len(firstFields) {
-					return false
-				}
-				for j, field := range fields {
-					if field != firstFields[j] {
-						return false
-					}
-				}
-			}
-		} else if obj, ok := item.(map[string]string); ok {
-			fields := make([]string, 0, len(obj))
-			for key := range obj {
-				fields = append(fields, key)
-			}
-			sort.Strings(fields)
+	default:
+		s.appendIndent(depth)
+		s.appendFieldKey(key)
+		s.buf = append(s.buf, ": "...)
+		s.buf = appendPrimitive(s.buf, rv)
+		s.buf = append(s.buf, '\n')
+		return nil
+	}
+}
 
-			if i == 0 {
-				firstFields = fields
-			} else {
-				if len(fields) != len(firstFields) {
-					return false
-				}
-				for j, field := range fields {
-					if field != firstFields[j] {
-						return false
-					}
-				}
-			}
-		} else {
-			return false
+func (s *encodeState) encodeMapFields(rv reflect.Value, depth int) error {
+	start := len(s.entries)
+	iter := rv.MapRange()
+	for iter.Next() {
+		s.entries = append(s.entries, mapEntry{key: mapKeyString(iter.Key()), value: iter.Value()})
+	}
+
+	entries := s.entries[start:]
+	slices.SortFunc(entries, func(a, b mapEntry) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	for _, entry := range entries {
+		if err := s.encodeField(entry.key, entry.value, depth); err != nil {
+			return err
 		}
 	}
 
-	return len(firstFields) > 0
+	clear(entries)
+	s.entries = s.entries[:start]
+	return nil
 }
 
-func (e *Encoder) isPrimitiveType(v interface{}) bool {
-	switch v.(type) {
-	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool, nil:
-		return true
+func mapKeyString(key reflect.Value) string {
+	switch key.Kind() {
+	case reflect.String:
+		return key.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(key.Uint(), 10)
 	default:
-		return false
+		return fmt.Sprint(key.Interface())
 	}
 }
 
-func (e *Encoder) encodeTabularArray(slice []interface{}, depth int, fieldName string) error {
-	if len(slice) == 0 {
-		return nil
-	}
-
-	var fields []string
-	if obj, ok := slice[0].(map[string]interface{}); ok {
-		fields = make([]string, 0, len(obj))
-		for key := range obj {
-			fields = append(fields, key)
+func (s *encodeState) encodeStructFields(rv reflect.Value, depth int) error {
+	info := types.CachedStruct(rv.Type())
+	for i := range info.Fields {
+		field := &info.Fields[i]
+		value := field.Value(rv)
+		if !value.IsValid() || field.OmitEmpty && types.IsEmptyValue(value) {
+			continue
+		}
+		if field.Append != nil && !implementsMarshaler(field.Type) {
+			s.appendIndent(depth)
+			s.appendFieldKey(field.Name)
+			s.buf = append(s.buf, ": "...)
+			s.buf = field.Append(s.buf, value)
+			s.buf = append(s.buf, '\n')
+			continue
 		}
-		sort.Strings(fields)
-	} else if obj, ok := slice[0].(map[string]string); ok {
-		fields = make([]string, 0, len(obj))
-		for key := range obj {
-			fields = append(fields, key)
+		if err := s.encodeField(field.Name, value, depth); err != nil {
+			return err
 		}
-		sort.Strings(fields)
 	}
+	return nil
+}
 
-	if len(fields) == 0 {
-		return e.encodeArray(reflect.ValueOf(slice), depth, fieldName)
+// encodeArray writes an array header at depth followed by its items,
+// choosing between the inline, tabular and list layouts. The header names
+// key when keyed, which may be empty; root arrays and list items are not.
+func (s *encodeState) encodeArray(key string, keyed bool, rv reflect.Value, depth int) error {
+	length := rv.Len()
+	if s.opts.AutoLayout && length > 0 {
+		return s.encodeArrayAuto(key, keyed, rv, depth)
 	}
 
-	indent := strings.Repeat(e.opts.Indent, depth)
+	delim := s.opts.delimiter()
+	s.appendArrayHeader(key, keyed, length, depth, delim)
 
-	if _, err := fmt.Fprintf(e.writer, "%s%s[%d]{%s}:\n", indent, fieldName, len(slice), strings.Join(fields, ",")); err != nil {
-		return err
+	if length == 0 {
+		s.buf = append(s.buf, ":\n"...)
+		return nil
 	}
 
-	for _, item := range slice {
-		if _, err := fmt.Fprintf(e.writer, "%s%s", indent, e.opts.Indent); err != nil {
-			return err
-		}
+	if s.isPrimitiveArray(rv) {
+		s.appendInlineValues(rv, delim)
+		return nil
+	}
 
-		if obj, ok := item.(map[string]interface{}); ok {
-			values := make([]string, len(fields))
-			for i, field := range fields {
-				value := obj[field]
-				values[i] = e.formatValueForTabular(value)
-			}
-			if _, err := e.writer.Write([]byte(strings.Join(values, " "))); err != nil {
-				return err
-			}
-		} else if obj, ok := item.(map[string]string); ok {
-			values := make([]string, len(fields))
-			for i, field := range fields {
-				value := obj[field]
-				values[i] = e.formatValueForTabular(value)
-			}
-			if _, err := e.writer.Write([]byte(strings.Join(values, " "))); err != nil {
-				return err
-			}
-		}
-		if _, err := e.writer.Write([]byte("\n")); err != nil {
-			return err
+	if s.opts.TokenOptimized && length >= 2 && s.shouldUseTabularFormat(rv) {
+		return s.encodeTabularArray(rv, depth, delim)
+	}
+	if s.opts.ForceTabular || s.opts.TokenOptimized && length >= 2 {
+		if rows, dotted, ok := s.tabularRows(rv); ok {
+			s.dottedHeader = dotted
+			return s.encodeTabularArray(rows, depth, delim)
 		}
 	}
 
-	return nil
+	return s.encodeListItems(rv, depth)
 }
 
-func (e *Encoder) formatValueForTabular(v interface{}) string {
-	if v == nil {
-		return "null"
+// appendArrayHeader writes the header up to the closing bracket, leaving
+// the field list and colon to the layout.
+func (s *encodeState) appendArrayHeader(key string, keyed bool, length, depth int, delim byte) {
+	s.appendIndent(depth)
+	if keyed {
+		s.appendFieldKey(key)
 	}
+	s.buf = append(s.buf, '[')
+	s.buf = appendCount(s.buf, length, delim)
+	s.buf = append(s.buf, ']')
+}
 
-	switch val := v.(type) {
-	case string:
-		if e.needsQuotes(val) {
-			return fmt.Sprintf("\"%s\"", val)
-		}
-		return val
-	case bool:
-		if val {
-			return "true"
+func (s *encodeState) appendInlineValues(rv reflect.Value, delim byte) {
+	s.buf = append(s.buf, ": "...)
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			s.buf = append(s.buf, delim)
 		}
-		return "false"
-	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("%v", val)
-	case float32, float64:
-		return fmt.Sprintf("%v", val)
-	default:
-		str := fmt.Sprintf("%v", val)
-		if e.needsQuotes(str) {
-			return fmt.Sprintf("\"%s\"", str)
+		s.buf = appendPrimitive(s.buf, rv.Index(i))
+	}
+	s.buf = append(s.buf, '\n')
+}
+
+func (s *encodeState) encodeListItems(rv reflect.Value, depth int) error {
+	s.buf = append(s.buf, ":\n"...)
+	for i := 0; i < rv.Len(); i++ {
+		if err := s.encodeListItem(rv.Index(i), depth+1); err != nil {
+			return err
 		}
-		return str
 	}
+	return nil
 }
 
-func (e *Encoder) encodeMap(rv reflect.Value, depth int, fieldName string) error {
-	indent := strings.Repeat(e.opts.Indent, depth)
+// encodeListItem writes one "- " item of a list array. Objects put their
+// first field on the marker line and the rest one level deeper; nested
+// arrays and objects under the item are two levels deeper than the marker.
+func (s *encodeState) encodeListItem(rv reflect.Value, depth int) error {
+	rv, m := resolve(rv)
+
+	s.appendIndent(depth)
+	s.buf = append(s.buf, "- "...)
 
-	keys := rv.MapKeys()
-	keyStrings := make([]string, len(keys))
-	for i, key := range keys {
-		keyStrings[i] = key.String()
+	if m != nil {
+		return s.encodeMarshaler(m, "", depth, true)
 	}
-	sort.Strings(keyStrings)
 
-	if fieldName != "" {
-		if _, err := fmt.Fprintf(e.writer, "%s%s:\n", indent, fieldName); err != nil {
-			return err
-		}
+	var err error
+	switch {
+	case isObjectValue(rv):
+		s.skipIndent = true
+		err = s.encodeFields(rv, depth+1)
+	case rv.Kind() == reflect.Slice, rv.Kind() == reflect.Array:
+		s.skipIndent = true
+		err = s.encodeArray("", false, rv, depth+1)
+	default:
+		s.buf = appendPrimitive(s.buf, rv)
+		s.buf = append(s.buf, '\n')
 	}
 
-	for _, keyStr := range keyStrings {
-		var value reflect.Value
-		for _, key := range keys {
-			if key.String() == keyStr {
-				value = rv.MapIndex(key)
-				break
-			}
+	if s.skipIndent {
+		// Empty object: nothing consumed the marker line.
+		s.skipIndent = false
+		s.buf = append(s.buf[:len(s.buf)-1], '\n')
+	}
+	return err
+}
+
+func (s *encodeState) isPrimitiveArray(rv reflect.Value) bool {
+	for i := 0; i < rv.Len(); i++ {
+		if !isPrimitiveValue(rv.Index(i)) {
+			return false
 		}
+	}
+	return true
+}
 
-		if !value.IsValid() {
-			continue
+// shouldUseTabularFormat reports whether every element of rv is an object
+// with the same primitive-only fields. On success the header fields are left
+// in s.fields (and the matching map keys in s.keys for maps).
+func (s *encodeState) shouldUseTabularFormat(rv reflect.Value) bool {
+	first, m := resolve(rv.Index(0))
+	if m != nil {
+		return false
+	}
+	switch first.Kind() {
+	case reflect.Map:
+		return s.uniformMaps(rv, first)
+	case reflect.Struct:
+		return s.uniformStructs(rv, first.Type())
+	case reflect.Slice:
+		if first.Type() == orderedObjectType {
+			return s.uniformObjects(rv, first.Interface().(types.Object))
 		}
+	}
+	return false
+}
 
-		valueInterface := value.Interface()
+// uniformObjects requires every element to be a types.Object with the keys
+// of first in the same order.
+func (s *encodeState) uniformObjects(rv reflect.Value, first types.Object) bool {
+	if len(first) == 0 {
+		return false
+	}
 
-		if e.isNestedType(valueInterface) {
-			if err := e.encodeValue(valueInterface, depth+1, keyStr); err != nil {
-				return err
-			}
-		} else {
-			if _, err := fmt.Fprintf(e.writer, "%s%s: ", indent+e.opts.Indent, keyStr); err != nil {
-				return err
-			}
-			if err := e.encodeValue(valueInterface, 0, ""); err != nil {
-				return err
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		if !item.IsValid() || item.Type() != orderedObjectType {
+			return false
+		}
+		obj := item.Interface().(types.Object)
+		if len(obj) != len(first) {
+			return false
+		}
+		for j, m := range obj {
+			if m.Key != first[j].Key {
+				return false
 			}
-			if _, err := e.writer.Write([]byte("\n")); err != nil {
-				return err
+			if _, ok := m.Value.(absentCell); !ok && !isPrimitiveValue(reflect.ValueOf(m.Value)) {
+				return false
 			}
 		}
 	}
 
-	return nil
+	s.fields = s.fields[:0]
+	for _, m := range first {
+		s.fields = append(s.fields, m.Key)
+	}
+	return true
 }
 
-func (e *Encoder) encodeStruct(rv reflect.Value, depth int, structFieldName string) error {
-	indent := strings.Repeat(e.opts.Indent, depth)
+func (s *encodeState) uniformMaps(rv, first reflect.Value) bool {
+	if first.Type().Key().Kind() != reflect.String || first.Len() == 0 {
+		return false
+	}
 
-	rt := rv.Type()
-	numField := rv.NumField()
+	s.fields = s.fields[:0]
+	s.keys = s.keys[:0]
+	iter := first.MapRange()
+	for iter.Next() {
+		s.fields = append(s.fields, iter.Key().String())
+	}
+	slices.Sort(s.fields)
+	for _, field := range s.fields {
+		s.keys = append(s.keys, reflect.ValueOf(field).Convert(first.Type().Key()))
+	}
 
-	if structFieldName != "" {
-		if _, err := fmt.Fprintf(e.writer, "%s%s:\n", indent, structFieldName); err != nil {
-			return err
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		if item.Kind() != reflect.Map || item.Type() != first.Type() || item.Len() != len(s.fields) {
+			return false
+		}
+		for _, key := range s.keys {
+			value := item.MapIndex(key)
+			if !value.IsValid() || !isPrimitiveValue(value) {
+				return false
+			}
 		}
 	}
+	return true
+}
 
-	type fieldInfo struct {
-		name  string
-		value reflect.Value
-		field reflect.StructField
+func (s *encodeState) uniformStructs(rv reflect.Value, rt reflect.Type) bool {
+	info := types.CachedStruct(rt)
+	if !info.Primitive {
+		return false
 	}
 
-	var validFields []fieldInfo
-
-	for i := 0; i < numField; i++ {
-		field := rt.Field(i)
-		value := rv.Field(i)
-
-		if !value.CanInterface() {
-			continue
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		if !item.IsValid() || item.Type() != rt {
+			return false
 		}
-
-		fieldName := field.Name
-		if toonTag := field.Tag.Get("toon"); toonTag != "" && toonTag != "-" {
-			if commaIndex := strings.Index(toonTag, ","); commaIndex != -1 {
-				fieldName = toonTag[:commaIndex]
-			} else {
-				fieldName = toonTag
-			}
-		} else if jsonTag := field.Tag.Get("json"); jsonTag != "" && jsonTag != "-" {
-			if commaIndex := strings.Index(jsonTag, ","); commaIndex != -1 {
-				fieldName = jsonTag[:commaIndex]
-			} else {
-				fieldName = jsonTag
+		for j := range info.Fields {
+			field := &info.Fields[j]
+			if field.Kind == types.KindDynamic && !isPrimitiveValue(field.Value(item)) {
+				return false
 			}
 		}
+	}
 
-		validFields = append(validFields, fieldInfo{
-			name:  fieldName,
-			value: value,
-			field: field,
-		})
+	s.fields = s.fields[:0]
+	for i := range info.Fields {
+		s.fields = append(s.fields, info.Fields[i].Name)
+	}
+	return true
+}
+
+// encodeTabularArray finishes a header started by encodeArray with the field
+// list from shouldUseTabularFormat and writes one delimited row per element.
+func (s *encodeState) encodeTabularArray(rv reflect.Value, depth int, delim byte) error {
+	isMap := indirect(rv.Index(0)).Kind() == reflect.Map
+	dotted := s.dottedHeader
+	s.dottedHeader = false
+
+	s.buf = append(s.buf, '{')
+	for i, field := range s.fields {
+		if i > 0 {
+			s.buf = append(s.buf, delim)
+		}
+		switch {
+		case s.dictionaryKey(field):
+		case dotted && strings.Contains(field, "."):
+			// A column flattened from a nested object.
+			s.buf = append(s.buf, field...)
+		case s.opts.dottedPaths() && strings.Contains(field, "."):
+			s.buf = types.AppendString(s.buf, field)
+		default:
+			s.buf = types.AppendKey(s.buf, field)
+		}
 	}
+	s.buf = append(s.buf, "}:\n"...)
 
-	for _, fieldInfo := range validFields {
-		fieldValue := fieldInfo.value.Interface()
-		fieldName := fieldInfo.name
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		s.appendIndent(depth + 1)
 
-		if e.isNestedType(fieldValue) {
-			if err := e.encodeValue(fieldValue, depth+1, fieldName); err != nil {
-				return err
-			}
-		} else {
-			if _, err := fmt.Fprintf(e.writer, "%s%s: ", indent+e.opts.Indent, fieldName); err != nil {
-				return err
+		switch {
+		case isMap:
+			for j, key := range s.keys {
+				if j > 0 {
+					s.buf = append(s.buf, delim)
+				}
+				s.buf = appendPrimitive(s.buf, item.MapIndex(key))
 			}
-			if err := e.encodeValue(fieldValue, 0, ""); err != nil {
-				return err
+		case item.Kind() == reflect.Slice:
+			for j, m := range item.Interface().(types.Object) {
+				if j > 0 {
+					s.buf = append(s.buf, delim)
+				}
+				if _, ok := m.Value.(absentCell); ok {
+					if !s.opts.MarkAbsent {
+						s.buf = append(s.buf, "null"...)
+					}
+					continue
+				}
+				s.buf = appendPrimitive(s.buf, reflect.ValueOf(m.Value))
 			}
-			if _, err := e.writer.Write([]byte("\n")); err != nil {
-				return err
+		default:
+			info := types.CachedStruct(item.Type())
+			for j := range info.Fields {
+				if j > 0 {
+					s.buf = append(s.buf, delim)
+				}
+				field := &info.Fields[j]
+				if field.Append != nil {
+					s.buf = field.Append(s.buf, field.Value(item))
+				} else {
+					s.buf = appendPrimitive(s.buf, field.Value(item))
+				}
 			}
 		}
+		s.buf = append(s.buf, '\n')
 	}
-
 	return nil
 }
 
-func (e *Encoder) isNestedType(v interface{}) bool {
-	if v == nil {
+func isPrimitiveValue(rv reflect.Value) bool {
+	rv = indirect(rv)
+	switch rv.Kind() {
+	case reflect.Invalid, reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
 		return false
 	}
+}
 
-	rv := reflect.ValueOf(v)
+// appendPrimitive appends a scalar value. Anything that is not a TOON
+// primitive is written as its quoted fmt representation.
+func appendPrimitive(dst []byte, rv reflect.Value) []byte {
+	rv = indirect(rv)
 	switch rv.Kind() {
-	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
-		return true
-	case reflect.Ptr:
-		return !rv.IsNil() && e.isNestedType(rv.Elem().Interface())
+	case reflect.Invalid:
+		return append(dst, "null"...)
+	case reflect.Bool:
+		return strconv.AppendBool(dst, rv.Bool())
+	case reflect.String:
+		if rv.Type() == numberType && types.IsNumber(rv.String()) {
+			// A json.Number keeps the exact text of a decoded number.
+			return append(dst, rv.String()...)
+		}
+		return types.AppendString(dst, rv.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(dst, rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(dst, rv.Uint(), 10)
+	case reflect.Float32:
+		return types.AppendFloat(dst, rv.Float(), 32)
+	case reflect.Float64:
+		return types.AppendFloat(dst, rv.Float(), 64)
 	default:
-		return false
+		return types.AppendString(dst, fmt.Sprint(rv.Interface()))
+	}
+}
+
+// appendFieldKey appends the key of an object field. With key folding,
+// auto layout or dotted columns enabled, literal keys containing dots are
+// quoted so that decoders expanding dotted paths leave them alone.
+func (s *encodeState) appendFieldKey(key string) {
+	folded := s.bareKey
+	s.bareKey = false
+	if s.dictionaryKey(key) {
+		return
+	}
+	if folded {
+		// A folded path whose segments were already checked.
+		s.buf = append(s.buf, key...)
+		return
+	}
+	if s.opts.dottedPaths() && strings.Contains(key, ".") {
+		s.buf = types.AppendString(s.buf, key)
+		return
 	}
+	s.buf = types.AppendKey(s.buf, key)
 }
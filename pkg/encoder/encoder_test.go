@@ -0,0 +1,148 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+func TestEncodeLayouts(t *testing.T) {
+	type item struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{
+			name:     "inline primitives",
+			input:    map[string]interface{}{"tags": []string{"a", "b"}},
+			expected: "tags[2]: \"a\",\"b\"\n",
+		},
+		{
+			name:     "tabular structs",
+			input:    map[string]interface{}{"items": []item{{1, "a"}, {2, "b"}}},
+			expected: "items[2]{id,name}:\n  1,\"a\"\n  2,\"b\"\n",
+		},
+		{
+			name:  "list items",
+			input: map[string]interface{}{"mixed": []interface{}{1, map[string]interface{}{"a": 1, "b": []int{2}}, []int{3, 4}}},
+			expected: "mixed[3]:\n" +
+				"  - 1\n" +
+				"  - a: 1\n" +
+				"    b[1]: 2\n" +
+				"  - [2]: 3,4\n",
+		},
+		{
+			name:     "root tabular",
+			input:    []item{{1, "a"}, {2, "b"}},
+			expected: "[2]{id,name}:\n  1,\"a\"\n  2,\"b\"\n",
+		},
+		{
+			name:     "escaped string and quoted key",
+			input:    map[string]string{"a key": "say \"hi\"\n"},
+			expected: "\"a key\": \"say \\\"hi\\\"\\n\"\n",
+		},
+		{
+			name: "ordered object",
+			input: types.Object{
+				{Key: "b", Value: 1},
+				{Key: "a", Value: types.Object{{Key: "z", Value: true}, {Key: "y", Value: nil}}},
+			},
+			expected: "b: 1\na:\n  z: true\n  y: null\n",
+		},
+		{
+			name: "tabular ordered objects",
+			input: map[string]interface{}{"rows": []types.Object{
+				{{Key: "name", Value: "a"}, {Key: "id", Value: 1}},
+				{{Key: "name", Value: "b"}, {Key: "id", Value: 2}},
+			}},
+			expected: "rows[2]{name,id}:\n  \"a\",1\n  \"b\",2\n",
+		},
+		{
+			name: "list of ordered objects",
+			input: []interface{}{
+				types.Object{{Key: "x", Value: 1}, {Key: "y", Value: []int{1}}},
+				types.Object{},
+			},
+			expected: "[2]:\n  - x: 1\n    y[1]: 1\n  -\n",
+		},
+		{
+			name: "json numbers",
+			input: types.Object{
+				{Key: "price", Value: json.Number("1.50")},
+				{Key: "big", Value: json.Number("12345678901234567890")},
+				{Key: "exp", Value: []json.Number{"1e-7", "2E+3"}},
+				{Key: "bad", Value: json.Number("0x10")},
+			},
+			expected: "price: 1.50\nbig: 12345678901234567890\nexp[2]: 1e-7,2E+3\nbad: \"0x10\"\n",
+		},
+		{
+			name:     "empty key",
+			input:    map[string]interface{}{"": []int{1, 2}},
+			expected: "\"\"[2]: 1,2\n",
+		},
+		{
+			name:     "empty values",
+			input:    map[string]interface{}{"list": []int{}, "obj": map[string]int{}},
+			expected: "list[0]:\nobj:\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf, nil).Encode(tt.input); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Encode() = %q, want %q", buf.String(), tt.expected)
+			}
+
+			appended, err := Append([]byte("prefix:"), tt.input, nil)
+			if err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			if string(appended) != "prefix:"+tt.expected {
+				t.Errorf("Append() = %q", appended)
+			}
+		})
+	}
+}
+
+func TestEncodeDelimiters(t *testing.T) {
+	type item struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	input := map[string]interface{}{
+		"items": []item{{1, "a|b"}, {2, "c"}},
+		"tags":  []string{"x", "y"},
+	}
+
+	tests := []struct {
+		delim    byte
+		expected string
+	}{
+		{Comma, "items[2]{id,name}:\n  1,\"a|b\"\n  2,\"c\"\ntags[2]: \"x\",\"y\"\n"},
+		{Tab, "items[2\t]{id\tname}:\n  1\t\"a|b\"\n  2\t\"c\"\ntags[2\t]: \"x\"\t\"y\"\n"},
+		{Pipe, "items[2|]{id|name}:\n  1|\"a|b\"\n  2|\"c\"\ntags[2|]: \"x\"|\"y\"\n"},
+	}
+
+	for _, tt := range tests {
+		opts := defaultOptions
+		opts.Delimiter = tt.delim
+		got, err := Append(nil, input, &opts)
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("delimiter %q: got %q, want %q", tt.delim, got, tt.expected)
+		}
+	}
+}
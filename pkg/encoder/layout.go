@@ -0,0 +1,214 @@
+package encoder
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/tokens"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+type arrayLayout int
+
+const (
+	layoutInline arrayLayout = iota
+	layoutTabular
+	layoutList
+)
+
+type layoutCandidate struct {
+	layout arrayLayout
+	delim  byte
+	// dotted marks tabular candidates over rows flattened by flattenRows.
+	dotted bool
+}
+
+// cost returns the estimated cost of an encoded fragment.
+func (s *encodeState) cost(fragment []byte) int {
+	if s.opts.Cost != nil {
+		return s.opts.Cost(fragment)
+	}
+	return tokens.Default().Count(string(fragment))
+}
+
+// encodeArrayAuto renders every layout rv supports, keeps the cheapest and
+// writes it. Only arrays of primitives and of uniform primitive objects have
+// alternatives, so the candidates are cheap to render; other arrays go
+// straight to the list layout.
+func (s *encodeState) encodeArrayAuto(key string, keyed bool, rv reflect.Value, depth int) error {
+	var layout arrayLayout
+	rows, dotted := rv, false
+	switch {
+	case s.isPrimitiveArray(rv):
+		layout = layoutInline
+	case s.shouldUseTabularFormat(rv):
+		layout = layoutTabular
+	default:
+		converted, isDotted, ok := s.tabularRows(rv)
+		if !ok {
+			return s.renderArray(key, keyed, rv, depth, layoutCandidate{layout: layoutList, delim: Comma})
+		}
+		layout, rows, dotted = layoutTabular, converted, isDotted
+	}
+
+	candidates := make([]layoutCandidate, 0, 4)
+	candidates = append(candidates, layoutCandidate{layout, s.opts.delimiter(), dotted})
+	for _, delim := range []byte{Comma, Tab, Pipe} {
+		if delim != s.opts.delimiter() {
+			candidates = append(candidates, layoutCandidate{layout, delim, dotted})
+		}
+	}
+	candidates = append(candidates, layoutCandidate{layoutList, Comma, false})
+
+	// Tabular candidates render the converted rows, the list the original.
+	source := func(c layoutCandidate) reflect.Value {
+		if c.layout == layoutTabular {
+			return rows
+		}
+		return rv
+	}
+
+	start := len(s.buf)
+	skipIndent, bareKey := s.skipIndent, s.bareKey
+	// Keys of discarded candidates must not count towards the dictionary.
+	keyCounts := s.keyCounts
+	s.keyCounts = nil
+	best, bestCost := 0, 0
+	for i, c := range candidates {
+		s.skipIndent, s.bareKey = skipIndent, bareKey
+		if err := s.renderArray(key, keyed, source(c), depth, c); err != nil {
+			return err
+		}
+		if cost := s.cost(s.buf[start:]); i == 0 || cost < bestCost {
+			best, bestCost = i, cost
+		}
+		s.buf = s.buf[:start]
+	}
+
+	s.skipIndent, s.bareKey = skipIndent, bareKey
+	s.keyCounts = keyCounts
+	return s.renderArray(key, keyed, source(candidates[best]), depth, candidates[best])
+}
+
+func (s *encodeState) renderArray(key string, keyed bool, rv reflect.Value, depth int, c layoutCandidate) error {
+	s.appendArrayHeader(key, keyed, rv.Len(), depth, c.delim)
+	switch c.layout {
+	case layoutInline:
+		s.appendInlineValues(rv, c.delim)
+		return nil
+	case layoutTabular:
+		// Rendering a list candidate may have reused s.fields.
+		s.shouldUseTabularFormat(rv)
+		s.dottedHeader = c.dotted
+		return s.encodeTabularArray(rv, depth, c.delim)
+	default:
+		return s.encodeListItems(rv, depth)
+	}
+}
+
+// encodeFolded writes key and a chain of single-key objects below it as one
+// dotted path, such as "a.b.c: 1". It reports false, writing nothing, when
+// there is no chain to fold or when AutoLayout finds the nested form
+// cheaper. Every folded segment must be an identifier so that decoders can
+// split the path unambiguously.
+func (s *encodeState) encodeFolded(key string, rv reflect.Value, depth int) (bool, error) {
+	if !isIdentifier(key) {
+		return false, nil
+	}
+
+	segments := []string{key}
+	leaf := rv
+	for {
+		name, value, ok := singleField(leaf)
+		if !ok || !isIdentifier(name) {
+			break
+		}
+		segments = append(segments, name)
+		next, m := resolve(value)
+		if m != nil || !isObjectValue(next) {
+			leaf = value
+			break
+		}
+		leaf = next
+	}
+	if len(segments) < 2 {
+		return false, nil
+	}
+
+	path := strings.Join(segments, ".")
+	if !s.opts.KeyFolding {
+		// The value below the chain is written the same way in both forms,
+		// only less indented when folded, so comparing the key lines is
+		// enough.
+		indent := strings.Repeat(s.opts.Indent, depth)
+		folded := indent + path + ":"
+		var nested strings.Builder
+		for i, segment := range segments {
+			if i > 0 {
+				nested.WriteByte('\n')
+			}
+			nested.WriteString(indent + strings.Repeat(s.opts.Indent, i) + segment + ":")
+		}
+		if s.cost([]byte(folded)) > s.cost([]byte(nested.String())) {
+			return false, nil
+		}
+	}
+
+	s.bareKey = true
+	return true, s.encodeField(path, leaf, depth)
+}
+
+// singleField returns the only field rv would encode, if it has exactly one.
+func singleField(rv reflect.Value) (string, reflect.Value, bool) {
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Len() != 1 {
+			return "", reflect.Value{}, false
+		}
+		iter := rv.MapRange()
+		iter.Next()
+		return mapKeyString(iter.Key()), iter.Value(), true
+
+	case reflect.Struct:
+		var name string
+		var value reflect.Value
+		count := 0
+		info := types.CachedStruct(rv.Type())
+		for i := range info.Fields {
+			field := &info.Fields[i]
+			v := field.Value(rv)
+			if !v.IsValid() || field.OmitEmpty && types.IsEmptyValue(v) {
+				continue
+			}
+			if count++; count > 1 {
+				return "", reflect.Value{}, false
+			}
+			name, value = field.Name, v
+		}
+		return name, value, count == 1
+
+	case reflect.Slice:
+		if rv.Type() == orderedObjectType && rv.Len() == 1 {
+			m := rv.Interface().(types.Object)[0]
+			return m.Key, reflect.ValueOf(m.Value), true
+		}
+	}
+	return "", reflect.Value{}, false
+}
+
+// isIdentifier reports whether key can be a segment of a folded path.
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package encoder
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+func TestAutoLayout(t *testing.T) {
+	type item struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+
+	byteCost := func(fragment []byte) int { return len(fragment) }
+	// Commas cost extra, so tab or pipe delimited layouts win.
+	commaCost := func(fragment []byte) int { return len(fragment) + 10*bytes.Count(fragment, []byte(",")) }
+	// Every line costs a lot, so layouts with fewer lines win.
+	lineCost := func(fragment []byte) int { return len(fragment) + 100*bytes.Count(fragment, []byte("\n")) }
+	// Dots cost a lot, so folding loses.
+	dotCost := func(fragment []byte) int { return len(fragment) + 100*bytes.Count(fragment, []byte(".")) }
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		cost     func([]byte) int
+		expected string
+	}{
+		{
+			name:     "single row tabular",
+			input:    map[string]interface{}{"items": []item{{1, "a"}}},
+			cost:     byteCost,
+			expected: "items[1]{id,name}:\n  1,\"a\"\n",
+		},
+		{
+			name:     "tab delimiter",
+			input:    map[string]interface{}{"items": []item{{1, "a"}, {2, "b"}}, "tags": []int{1, 2, 3}},
+			cost:     commaCost,
+			expected: "items[2\t]{id\tname}:\n  1\t\"a\"\n  2\t\"b\"\ntags[3\t]: 1\t2\t3\n",
+		},
+		{
+			name:     "list when lines are cheap",
+			input:    map[string]interface{}{"tags": []int{1, 2}},
+			cost:     func(fragment []byte) int { return len(fragment) - 100*bytes.Count(fragment, []byte("\n")) },
+			expected: "tags[2]:\n  - 1\n  - 2\n",
+		},
+		{
+			name:     "folded keys",
+			input:    map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}}, "x.y": 2},
+			cost:     lineCost,
+			expected: "a.b.c: 1\n\"x.y\": 2\n",
+		},
+		{
+			name: "fold stops at multi-key object",
+			input: map[string]interface{}{
+				"data": map[string]interface{}{"meta": map[string]interface{}{"page": 1, "size": 10}},
+			},
+			cost:     lineCost,
+			expected: "data.meta:\n  page: 1\n  size: 10\n",
+		},
+		{
+			name:     "nested when dots are expensive",
+			input:    map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			cost:     dotCost,
+			expected: "a:\n  b: 1\n",
+		},
+		{
+			name:     "fold stops at non-identifier key",
+			input:    map[string]interface{}{"a": map[string]interface{}{"b c": 1}},
+			cost:     lineCost,
+			expected: "a:\n  \"b c\": 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.AutoLayout = true
+			opts.Cost = tt.cost
+			got, err := Append(nil, tt.input, &opts)
+			if err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Append() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKeyFolding(t *testing.T) {
+	type inner struct {
+		Value int `toon:"value"`
+	}
+	type outer struct {
+		Config struct {
+			Inner inner `toon:"inner"`
+		} `toon:"config"`
+		List []int `toon:"list"`
+	}
+
+	var v outer
+	v.Config.Inner.Value = 3
+	v.List = []int{1}
+
+	opts := DefaultOptions()
+	opts.KeyFolding = true
+	got, err := Append(nil, v, &opts)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if want := "config.inner.value: 3\nlist[1]: 1\n"; string(got) != want {
+		t.Errorf("Append() = %q, want %q", got, want)
+	}
+}
+
+func TestAutoLayoutDefaultCost(t *testing.T) {
+	opts := DefaultOptions()
+	opts.AutoLayout = true
+	got, err := Append(nil, map[string]interface{}{"users": []map[string]interface{}{
+		{"id": 1, "name": "Ada"},
+		{"id": 2, "name": "Grace"},
+	}}, &opts)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if !bytes.HasPrefix(got, []byte("users[2")) || !bytes.Contains(got, []byte("]{id")) {
+		t.Errorf("expected a tabular layout, got %q", got)
+	}
+}
+
+func TestAutoLayoutRoundTrip(t *testing.T) {
+	input := map[string]interface{}{
+		"a":    map[string]interface{}{"b": map[string]interface{}{"c": int64(1)}},
+		"x.y":  int64(2),
+		"tags": []interface{}{int64(1), int64(2)},
+	}
+
+	opts := DefaultOptions()
+	opts.AutoLayout = true
+	opts.Cost = func(fragment []byte) int { return len(fragment) + 100*bytes.Count(fragment, []byte("\n")) }
+	data, err := Append(nil, input, &opts)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if !bytes.Contains(data, []byte("a.b.c: 1")) {
+		t.Fatalf("expected a folded key, got %q", data)
+	}
+
+	got, err := decoder.NewParserWithOptions(strings.NewReader(string(data)), decoder.Options{ExpandPaths: true}).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("decoded with ExpandPaths = %v, want %v", got, input)
+	}
+
+	got, err = decoder.NewParser(strings.NewReader(string(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, ok := got.(map[string]interface{})["a.b.c"]; !ok {
+		t.Errorf("decoded without ExpandPaths = %v, want the literal key %q", got, "a.b.c")
+	}
+}
@@ -0,0 +1,145 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Marshaler mirrors toon.Marshaler. It is declared here so the encoder can
+// recognise implementations without importing the toon package.
+type Marshaler interface {
+	MarshalTOON() ([]byte, error)
+}
+
+var marshalerType = reflect.TypeFor[Marshaler]()
+
+var marshalerTypes sync.Map // map[reflect.Type]bool
+
+// implementsMarshaler reports whether values of type t, or of a type it
+// points to, may implement Marshaler, in which case they are encoded
+// through resolve rather than a compiled field func.
+func implementsMarshaler(t reflect.Type) bool {
+	if ok, found := marshalerTypes.Load(t); found {
+		return ok.(bool)
+	}
+	ok := false
+	for u := t; ; u = u.Elem() {
+		if u.Implements(marshalerType) || u.Kind() != reflect.Ptr && reflect.PointerTo(u).Implements(marshalerType) {
+			ok = true
+			break
+		}
+		if u.Kind() != reflect.Ptr {
+			break
+		}
+	}
+	marshalerTypes.Store(t, ok)
+	return ok
+}
+
+// resolve follows pointers and interfaces like indirect, stopping early at
+// the first value that implements Marshaler.
+func resolve(rv reflect.Value) (reflect.Value, Marshaler) {
+	for rv.IsValid() {
+		if m, ok := asMarshaler(rv); ok {
+			return rv, m
+		}
+		if rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Interface {
+			return rv, nil
+		}
+		if rv.IsNil() {
+			return reflect.Value{}, nil
+		}
+		rv = rv.Elem()
+	}
+	return rv, nil
+}
+
+func asMarshaler(rv reflect.Value) (Marshaler, bool) {
+	if rv.Kind() == reflect.Ptr && rv.IsNil() || !rv.CanInterface() {
+		return nil, false
+	}
+	if rv.Kind() != reflect.Interface && rv.Type().Implements(marshalerType) {
+		return rv.Interface().(Marshaler), true
+	}
+	if rv.Kind() != reflect.Ptr && rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(marshalerType) {
+		return rv.Addr().Interface().(Marshaler), true
+	}
+	return nil, false
+}
+
+func marshalDocument(m Marshaler) ([]byte, error) {
+	doc, err := m.MarshalTOON()
+	if err != nil {
+		return nil, fmt.Errorf("toon: MarshalTOON for type %T: %w", m, err)
+	}
+	return bytes.TrimRight(doc, "\n"), nil
+}
+
+// encodeMarshaler splices the standalone document returned by MarshalTOON
+// into the output under key, re-indenting it to depth. For list items the
+// "- " marker has already been written.
+func (s *encodeState) encodeMarshaler(m Marshaler, key string, depth int, item bool) error {
+	doc, err := marshalDocument(m)
+	if err != nil {
+		return err
+	}
+
+	first, rest, _ := bytes.Cut(doc, []byte("\n"))
+	primitive := len(rest) == 0 && isPrimitiveLine(first)
+	array := bytes.HasPrefix(first, []byte("["))
+
+	restDepth := depth + 1
+	if !item {
+		s.appendIndent(depth)
+		s.appendFieldKey(key)
+		switch {
+		case len(doc) == 0:
+			s.buf = append(s.buf, ":\n"...)
+			return nil
+		case primitive:
+			s.buf = append(s.buf, ": "...)
+		case array:
+			restDepth = depth
+		default:
+			s.buf = append(s.buf, ":\n"...)
+			s.appendIndent(depth + 1)
+		}
+	} else if len(doc) == 0 {
+		s.buf = append(s.buf[:len(s.buf)-1], '\n')
+		return nil
+	}
+
+	s.buf = append(s.buf, first...)
+	s.buf = append(s.buf, '\n')
+
+	for len(rest) > 0 {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		s.appendIndent(restDepth)
+		s.buf = append(s.buf, line...)
+		s.buf = append(s.buf, '\n')
+	}
+	return nil
+}
+
+// isPrimitiveLine reports whether a single-line document is a bare value
+// rather than a key or array header.
+func isPrimitiveLine(line []byte) bool {
+	if bytes.HasPrefix(line, []byte(`"`)) {
+		for i := 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return i == len(line)-1
+			}
+		}
+		return false
+	}
+	return !bytes.ContainsAny(line, ":[\n")
+}
@@ -0,0 +1,189 @@
+package encoder
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// projection filters the fields of a value by the path patterns of
+// Options.Include and Options.Exclude.
+//
+// A path names a field by its keys joined with dots. Array elements add
+// "[]" to the key of the array, so the email of every user is
+// "users[].email" and a field of the elements of a root array is "[].id".
+// In patterns, "*" matches any part of a single key, "**" matches any
+// number of keys, and "[]" may be left out: "users.email" matches
+// "users[].email" too.
+type projection struct {
+	include [][]string
+	exclude [][]string
+}
+
+func newProjection(opts *Options) *projection {
+	if len(opts.Include) == 0 && len(opts.Exclude) == 0 {
+		return nil
+	}
+	p := &projection{}
+	for _, pattern := range opts.Include {
+		p.include = append(p.include, strings.Split(pattern, "."))
+	}
+	for _, pattern := range opts.Exclude {
+		p.exclude = append(p.exclude, strings.Split(pattern, "."))
+	}
+	return p
+}
+
+// project returns a copy of rv holding only the selected fields, with
+// objects converted to types.Object so the encoder keeps their order.
+// Values implementing Marshaler are kept whole.
+func (p *projection) project(rv reflect.Value) interface{} {
+	v, _ := p.value(rv, nil, len(p.include) == 0)
+	return v
+}
+
+// value projects rv found at path. included reports whether an ancestor,
+// or the absence of include patterns, already selects the whole subtree.
+// It returns false when rv is dropped.
+func (p *projection) value(rv reflect.Value, path []string, included bool) (interface{}, bool) {
+	rv, m := resolve(rv)
+	if m != nil {
+		return m, true
+	}
+	if !rv.IsValid() {
+		return nil, included
+	}
+
+	switch {
+	case isObjectValue(rv):
+		obj := types.Object{}
+		eachField(rv, func(key string, value reflect.Value) {
+			fieldPath := append(slices.Clone(path), key)
+			keep, all := p.selects(fieldPath, included)
+			if !keep {
+				return
+			}
+			if v, ok := p.value(value, fieldPath, all); ok {
+				obj = append(obj, types.Member{Key: key, Value: v})
+			}
+		})
+		return obj, included || len(obj) > 0
+
+	case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+		itemPath := []string{"[]"}
+		if len(path) > 0 {
+			itemPath = append(slices.Clone(path[:len(path)-1]), path[len(path)-1]+"[]")
+		}
+		items := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if v, ok := p.value(rv.Index(i), itemPath, included); ok {
+				items = append(items, v)
+			}
+		}
+		return items, included || len(items) > 0 || rv.Len() == 0
+
+	default:
+		if !rv.CanInterface() {
+			return nil, included
+		}
+		return rv.Interface(), included
+	}
+}
+
+// selects reports whether the field at path is kept and whether its whole
+// subtree is. A field is kept when no exclude pattern matches it and it is
+// either inside an included subtree, matched by an include pattern, or on
+// the way to a path an include pattern could match.
+func (p *projection) selects(path []string, included bool) (keep, all bool) {
+	for _, pattern := range p.exclude {
+		if full, _ := matchPath(pattern, path); full {
+			return false, false
+		}
+	}
+	if included {
+		return true, true
+	}
+	for _, pattern := range p.include {
+		full, prefix := matchPath(pattern, path)
+		if full {
+			return true, true
+		}
+		keep = keep || prefix
+	}
+	return keep, false
+}
+
+// matchPath reports whether path matches pattern completely, and whether
+// longer paths starting with path could match it.
+func matchPath(pattern, path []string) (full, prefix bool) {
+	if len(path) == 0 {
+		full = true
+		for _, segment := range pattern {
+			if segment != "**" {
+				full = false
+			}
+		}
+		return full, len(pattern) > 0
+	}
+	if len(pattern) == 0 {
+		return false, false
+	}
+	if pattern[0] == "**" {
+		full, prefix = matchPath(pattern[1:], path)
+		deeperFull, deeperPrefix := matchPath(pattern, path[1:])
+		return full || deeperFull, prefix || deeperPrefix
+	}
+	if !matchSegment(pattern[0], path[0]) {
+		// The key of an array is matched by a segment naming its elements,
+		// as "users[]" in "users[].email": the fields below it carry the
+		// suffix in their paths.
+		if len(path) == 1 && matchElements(pattern[0], path[0]) {
+			return matchPath(pattern[1:], nil)
+		}
+		return false, false
+	}
+	return matchPath(pattern[1:], path[1:])
+}
+
+// matchElements reports whether pattern is a segment ending in "[]" whose
+// key, without the suffix, matches key.
+func matchElements(pattern, key string) bool {
+	trimmed := strings.TrimSuffix(pattern, "[]")
+	return trimmed != pattern && glob(trimmed, key)
+}
+
+// matchSegment matches one key against a pattern segment in which "*"
+// matches any run of characters. A pattern without "[]" also matches the
+// key of an array without its "[]" suffixes.
+func matchSegment(pattern, key string) bool {
+	if glob(pattern, key) {
+		return true
+	}
+	if strings.Contains(pattern, "[]") {
+		return false
+	}
+	trimmed := key
+	for strings.HasSuffix(trimmed, "[]") {
+		trimmed = strings.TrimSuffix(trimmed, "[]")
+	}
+	return trimmed != key && glob(pattern, trimmed)
+}
+
+func glob(pattern, s string) bool {
+	star := strings.IndexByte(pattern, '*')
+	if star < 0 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, pattern[:star]) {
+		return false
+	}
+	rest := pattern[star+1:]
+	for i := star; i <= len(s); i++ {
+		if glob(rest, s[i:]) {
+			return true
+		}
+	}
+	return false
+}
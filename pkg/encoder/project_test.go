@@ -0,0 +1,108 @@
+package encoder
+
+import (
+	"testing"
+)
+
+func TestProjection(t *testing.T) {
+	type user struct {
+		ID       int               `toon:"id"`
+		Name     string            `toon:"name"`
+		Email    string            `toon:"email"`
+		Internal map[string]string `toon:"internalNotes"`
+	}
+	type doc struct {
+		Users []user                 `toon:"users"`
+		Meta  map[string]interface{} `toon:"meta"`
+		Total int                    `toon:"total"`
+	}
+	in := doc{
+		Users: []user{
+			{1, "Ada", "ada@example.com", map[string]string{"flag": "x"}},
+			{2, "Linus", "linus@example.com", map[string]string{"flag": "y"}},
+		},
+		Meta:  map[string]interface{}{"page": 1, "cursor": map[string]string{"next": "abc"}},
+		Total: 2,
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             string
+	}{
+		{
+			name:    "exclude glob turns list into table",
+			exclude: []string{"*.internal*", "meta.**"},
+			want: `users[2]{id,name,email}:
+  1,"Ada","ada@example.com"
+  2,"Linus","linus@example.com"
+total: 2
+`,
+		},
+		{
+			name:    "include array element fields",
+			include: []string{"users[].id", "users.name", "total"},
+			want: `users[2]{id,name}:
+  1,"Ada"
+  2,"Linus"
+total: 2
+`,
+		},
+		{
+			name:    "include only array element fields",
+			include: []string{"users[].email"},
+			want: `users[2]{email}:
+  "ada@example.com"
+  "linus@example.com"
+`,
+		},
+		{
+			name:    "include subtree",
+			include: []string{"meta.**"},
+			want: `meta:
+  cursor:
+    next: "abc"
+  page: 1
+`,
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"meta"},
+			exclude: []string{"**.next"},
+			want: `meta:
+  cursor:
+  page: 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Include, opts.Exclude = tt.include, tt.exclude
+			got, err := Append(nil, in, &opts)
+			if err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProjectionRootArray(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "secret": "a"},
+		{"id": 2, "secret": "b"},
+	}
+	opts := DefaultOptions()
+	opts.Exclude = []string{"[].secret"}
+	got, err := Append(nil, rows, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if want := "[2]{id}:\n  1\n  2\n"; string(got) != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,113 @@
+package encoder
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// absentCell stands in the rows built by sparseRows for the keys an object
+// lacks. It is written as null, or as an empty cell with
+// Options.MarkAbsent.
+type absentCell struct{}
+
+// tabularRows returns rows for a tabular layout of an array that
+// shouldUseTabularFormat rejected: nested objects flattened into dotted
+// columns, or objects with differing keys spread over the union of their
+// keys. dotted reports the former.
+func (s *encodeState) tabularRows(rv reflect.Value) (rows reflect.Value, dotted, ok bool) {
+	if s.opts.DottedColumns {
+		if rows, ok := s.flattenRows(rv); ok {
+			return rows, true, true
+		}
+	}
+	if s.opts.ForceTabular || s.opts.TabularThreshold > 0 {
+		if rows, ok := s.sparseRows(rv); ok {
+			return rows, false, true
+		}
+	}
+	return reflect.Value{}, false, false
+}
+
+// sparseRows returns the elements of rv as rows over the union of their
+// keys, with absentCell in the columns a row lacks. Every element must be an
+// object of primitives and, unless Options.ForceTabular is set, at least
+// Options.TabularThreshold of the cells must hold a value. Columns come in
+// the order they are first seen, or sorted for maps as in shouldUseTabularFormat.
+func (s *encodeState) sparseRows(rv reflect.Value) (reflect.Value, bool) {
+	objects := make([]types.Object, rv.Len())
+	var columns []string
+	seen := map[string]bool{}
+	sorted := false
+	cells := 0
+	for i := range objects {
+		item, m := resolve(rv.Index(i))
+		if m != nil || !isObjectValue(item) {
+			return reflect.Value{}, false
+		}
+		sorted = sorted || item.Kind() == reflect.Map
+
+		ok := true
+		eachField(item, func(key string, value reflect.Value) {
+			value, m := resolve(value)
+			switch {
+			case !ok:
+				return
+			case m != nil || !isPrimitiveValue(value):
+				ok = false
+				return
+			case !value.IsValid():
+				objects[i] = append(objects[i], types.Member{Key: key})
+			case value.CanInterface():
+				objects[i] = append(objects[i], types.Member{Key: key, Value: value.Interface()})
+			default:
+				ok = false
+				return
+			}
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		})
+		if !ok {
+			return reflect.Value{}, false
+		}
+		cells += len(objects[i])
+	}
+
+	if len(columns) == 0 {
+		return reflect.Value{}, false
+	}
+	if !s.opts.ForceTabular && float64(cells) < s.opts.TabularThreshold*float64(len(objects)*len(columns)) {
+		return reflect.Value{}, false
+	}
+	if sorted {
+		slices.Sort(columns)
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column] = i
+	}
+	// With a single column, an empty cell would make a blank row, which
+	// decoders skip, so absent keys are written as null.
+	var absent interface{} = absentCell{}
+	if len(columns) == 1 {
+		absent = nil
+	}
+	rows := make([]types.Object, len(objects))
+	for i, obj := range objects {
+		row := make(types.Object, len(columns))
+		for j, column := range columns {
+			row[j] = types.Member{Key: column, Value: absent}
+		}
+		for _, m := range obj {
+			row[index[m.Key]].Value = m.Value
+		}
+		rows[i] = row
+	}
+
+	sparse := reflect.ValueOf(rows)
+	return sparse, s.shouldUseTabularFormat(sparse)
+}
@@ -0,0 +1,188 @@
+package encoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+type sparseUser struct {
+	ID   int    `toon:"id"`
+	Name string `toon:"name"`
+}
+
+func TestTabularThreshold(t *testing.T) {
+	users := []map[string]interface{}{
+		{"id": 1, "name": "Ada", "role": "admin"},
+		{"id": 2, "name": "Linus"},
+		{"id": 3, "name": "Grace", "role": "dev"},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		absent    bool
+		want      string
+	}{
+		{
+			name:      "null",
+			threshold: 0.8,
+			want: `[3]{id,name,role}:
+  1,"Ada","admin"
+  2,"Linus",null
+  3,"Grace","dev"
+`,
+		},
+		{
+			name:      "absent",
+			threshold: 0.8,
+			absent:    true,
+			want: `[3]{id,name,role}:
+  1,"Ada","admin"
+  2,"Linus",
+  3,"Grace","dev"
+`,
+		},
+		{
+			name:      "below threshold",
+			threshold: 0.95,
+			want: `[3]:
+  - id: 1
+    name: "Ada"
+    role: "admin"
+  - id: 2
+    name: "Linus"
+  - id: 3
+    name: "Grace"
+    role: "dev"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.TabularThreshold = tt.threshold
+			opts.MarkAbsent = tt.absent
+			got, err := Append(nil, users, &opts)
+			if err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTabularThresholdColumnOrder(t *testing.T) {
+	opts := DefaultOptions()
+	opts.TabularThreshold = 0.5
+
+	objects := []types.Object{
+		{{Key: "id", Value: 1}, {Key: "name", Value: "Ada"}},
+		{{Key: "id", Value: 2}, {Key: "role", Value: "admin"}},
+	}
+	got, err := Append(nil, objects, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	want := `[2]{id,name,role}:
+  1,"Ada",null
+  2,null,"admin"
+`
+	if string(got) != want {
+		t.Errorf("Expected first-seen order:\n%s\nGot:\n%s", want, got)
+	}
+
+	maps := []map[string]interface{}{
+		{"b": 1, "c": 2},
+		{"a": 3, "b": 4},
+	}
+	got, err = Append(nil, maps, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	want = `[2]{a,b,c}:
+  null,1,2
+  3,4,null
+`
+	if string(got) != want {
+		t.Errorf("Expected sorted map columns:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestForceTabular(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ForceTabular = true
+	opts.TokenOptimized = false
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "single object",
+			value: []sparseUser{{1, "Ada"}},
+			want: `[1]{id,name}:
+  1,"Ada"
+`,
+		},
+		{
+			name: "disjoint keys",
+			value: []map[string]interface{}{
+				{"a": 1},
+				{"b": 2},
+			},
+			want: `[2]{a,b}:
+  1,null
+  null,2
+`,
+		},
+		{
+			name: "nested values stay lists",
+			value: []map[string]interface{}{
+				{"a": 1},
+				{"a": []int{1}},
+			},
+			want: `[2]:
+  - a: 1
+  - a[1]: 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Append(nil, tt.value, &opts)
+			if err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMarkAbsentSingleColumn(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ForceTabular = true
+	opts.MarkAbsent = true
+
+	got, err := Append(nil, []map[string]interface{}{{}, {"a": -91}}, &opts)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if want := "[2]{a}:\n  null\n  -91\n"; string(got) != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+
+	rows, err := decoder.NewParserWithOptions(strings.NewReader(string(got)), decoder.Options{DropAbsent: true}).Parse()
+	if err != nil || len(rows.([]interface{})) != 2 {
+		t.Errorf("Expected two rows, got %v (%v)", rows, err)
+	}
+}
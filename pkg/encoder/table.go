@@ -0,0 +1,351 @@
+package encoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// headerPadding is the number of bytes reserved after a backpatched header so
+// the final count fits in place of the "0" placeholder. Trailing spaces on a
+// header line are ignored by the decoder.
+const headerPadding = 19
+
+type tableMode int
+
+const (
+	tableKnownCount tableMode = iota
+	tableSpooled
+	tableBackpatched
+)
+
+// TableWriter writes a single tabular array one row at a time, for sources
+// such as database cursors where the rows are never held in a slice.
+//
+// Every row is validated against the declared field list. Close must be
+// called once all rows have been written; it completes the header and
+// reports a mismatch between the declared and written row counts.
+type TableWriter struct {
+	enc       *Encoder
+	out       io.Writer
+	name      string
+	fields    []string
+	index     map[string]int
+	rowIndent string
+	line      []byte
+	mode      tableMode
+	count     int
+	written   int
+	closed    bool
+
+	rows       *bufio.Writer
+	spool      *os.File
+	seeker     io.WriteSeeker
+	headerPos  int64
+	headerSize int
+}
+
+// NewTableWriter writes the header for an array of exactly count rows to w
+// and returns a writer for its rows.
+func NewTableWriter(w io.Writer, name string, fields []string, count int, opts *Options) (*TableWriter, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("toon: negative table row count %d", count)
+	}
+
+	t, err := newTableWriter(w, name, fields, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.mode = tableKnownCount
+	t.count = count
+	t.rows = bufio.NewWriter(w)
+
+	if _, err := t.rows.WriteString(t.header(count)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewSpooledTableWriter returns a writer for an array whose row count is not
+// known in advance. Rows are spooled to a temporary file and copied to w,
+// after the header, when the writer is closed.
+func NewSpooledTableWriter(w io.Writer, name string, fields []string, opts *Options) (*TableWriter, error) {
+	t, err := newTableWriter(w, name, fields, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	spool, err := os.CreateTemp("", "toon-table-*")
+	if err != nil {
+		return nil, err
+	}
+	t.mode = tableSpooled
+	t.spool = spool
+	t.rows = bufio.NewWriter(spool)
+	return t, nil
+}
+
+// NewSeekingTableWriter returns a writer for an array whose row count is not
+// known in advance. It writes a header with a placeholder count directly to
+// w and overwrites it with the real count when the writer is closed.
+func NewSeekingTableWriter(w io.WriteSeeker, name string, fields []string, opts *Options) (*TableWriter, error) {
+	t, err := newTableWriter(w, name, fields, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pos, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mode = tableBackpatched
+	t.seeker = w
+	t.headerPos = pos
+	t.rows = bufio.NewWriter(w)
+
+	header := t.header(0)
+	t.headerSize = len(header) + headerPadding
+	if _, err := t.rows.WriteString(t.paddedHeader(0)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func newTableWriter(w io.Writer, name string, fields []string, opts *Options) (*TableWriter, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("toon: table %q has no fields", name)
+	}
+
+	index := make(map[string]int, len(fields))
+	for i, field := range fields {
+		if _, ok := index[field]; ok {
+			return nil, fmt.Errorf("toon: table %q declares field %q twice", name, field)
+		}
+		index[field] = i
+	}
+
+	enc := NewEncoder(w, opts)
+	rowIndent := enc.opts.Indent
+	if rowIndent == "" {
+		// Rows must be indented below a named header to be read back.
+		rowIndent = "  "
+	}
+
+	return &TableWriter{
+		enc:       enc,
+		out:       w,
+		name:      name,
+		fields:    fields,
+		index:     index,
+		rowIndent: rowIndent,
+	}, nil
+}
+
+// header writes the name and fields quoted where the encoder would quote
+// them as keys.
+func (t *TableWriter) header(count int) string {
+	delim := t.enc.opts.delimiter()
+	var b []byte
+	if t.name != "" {
+		b = types.AppendKey(b, t.name)
+	}
+	b = append(b, '[')
+	b = appendCount(b, count, delim)
+	b = append(b, "]{"...)
+	for i, field := range t.fields {
+		if i > 0 {
+			b = append(b, delim)
+		}
+		b = types.AppendKey(b, field)
+	}
+	return string(append(b, "}:\n"...))
+}
+
+func (t *TableWriter) paddedHeader(count int) string {
+	header := t.header(count)
+	padding := t.headerSize - len(header)
+	return header[:len(header)-1] + strings.Repeat(" ", padding) + "\n"
+}
+
+// Fields returns the declared column names.
+func (t *TableWriter) Fields() []string {
+	return t.fields
+}
+
+// Written returns the number of rows written so far.
+func (t *TableWriter) Written() int {
+	return t.written
+}
+
+// WriteRow writes one row. v may be a map keyed by field name, a slice or
+// array holding one value per field in declaration order, or a struct.
+// Maps and structs must provide exactly the declared fields.
+func (t *TableWriter) WriteRow(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return fmt.Errorf("toon: table %q: nil row", t.name)
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return t.writeStruct(rv)
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("toon: table %q: map key type %s is not string", t.name, rv.Type().Key())
+		}
+		if rv.Len() != len(t.fields) {
+			return fmt.Errorf("toon: table %q row %d: expected %d fields, got %d", t.name, t.written+1, len(t.fields), rv.Len())
+		}
+		values := make([]interface{}, len(t.fields))
+		for i, field := range t.fields {
+			value := rv.MapIndex(reflect.ValueOf(field).Convert(rv.Type().Key()))
+			if !value.IsValid() {
+				return fmt.Errorf("toon: table %q row %d: missing field %q", t.name, t.written+1, field)
+			}
+			values[i] = value.Interface()
+		}
+		return t.writeValues(values)
+
+	case reflect.Slice, reflect.Array:
+		if rv.Len() != len(t.fields) {
+			return fmt.Errorf("toon: table %q row %d: expected %d values, got %d", t.name, t.written+1, len(t.fields), rv.Len())
+		}
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return t.writeValues(values)
+
+	default:
+		return fmt.Errorf("toon: table %q: unsupported row type %s", t.name, rv.Type())
+	}
+}
+
+// WriteStruct writes one row from a struct or pointer to struct. Its
+// exported fields, named as by Encode, must match the declared fields.
+func (t *TableWriter) WriteStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("toon: table %q: nil row", t.name)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("toon: table %q: WriteStruct of non-struct %s", t.name, rv.Type())
+	}
+	return t.writeStruct(rv)
+}
+
+func (t *TableWriter) writeStruct(rv reflect.Value) error {
+	info := types.CachedStruct(rv.Type())
+	values := make([]interface{}, len(t.fields))
+
+	for i := range info.Fields {
+		field := &info.Fields[i]
+		pos, ok := t.index[field.Name]
+		if !ok {
+			return fmt.Errorf("toon: table %q: struct field %q is not a declared field", t.name, field.Name)
+		}
+		if value := field.Value(rv); value.IsValid() {
+			values[pos] = value.Interface()
+		}
+	}
+
+	if len(info.Fields) != len(t.fields) {
+		return fmt.Errorf("toon: table %q row %d: struct %s has %d of %d declared fields", t.name, t.written+1, rv.Type(), len(info.Fields), len(t.fields))
+	}
+	return t.writeValues(values)
+}
+
+func (t *TableWriter) writeValues(values []interface{}) error {
+	if t.closed {
+		return fmt.Errorf("toon: table %q: write after Close", t.name)
+	}
+	if t.mode == tableKnownCount && t.written >= t.count {
+		return fmt.Errorf("toon: table %q: more than the declared %d rows", t.name, t.count)
+	}
+
+	t.line = append(t.line[:0], t.rowIndent...)
+	for i, value := range values {
+		rv := reflect.ValueOf(value)
+		if !isPrimitiveValue(rv) {
+			return fmt.Errorf("toon: table %q row %d: field %q is not a primitive value", t.name, t.written+1, t.fields[i])
+		}
+		if i > 0 {
+			t.line = append(t.line, t.enc.opts.delimiter())
+		}
+		t.line = appendPrimitive(t.line, rv)
+	}
+	t.line = append(t.line, '\n')
+
+	if _, err := t.rows.Write(t.line); err != nil {
+		return err
+	}
+	t.written++
+	return nil
+}
+
+// Close flushes buffered rows and finalises the header. For spooled writers
+// it also removes the temporary file.
+func (t *TableWriter) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	flushErr := t.rows.Flush()
+
+	switch t.mode {
+	case tableKnownCount:
+		if flushErr != nil {
+			return flushErr
+		}
+		if t.written != t.count {
+			return fmt.Errorf("toon: table %q: declared %d rows, wrote %d", t.name, t.count, t.written)
+		}
+		return nil
+
+	case tableSpooled:
+		defer os.Remove(t.spool.Name())
+		defer t.spool.Close()
+		if flushErr != nil {
+			return flushErr
+		}
+		if _, err := io.WriteString(t.out, t.header(t.written)); err != nil {
+			return err
+		}
+		if _, err := t.spool.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := io.Copy(t.out, t.spool)
+		return err
+
+	default:
+		if flushErr != nil {
+			return flushErr
+		}
+		if len(strconv.Itoa(t.written)) > 1+headerPadding {
+			return fmt.Errorf("toon: table %q: row count %d does not fit the reserved header", t.name, t.written)
+		}
+		if _, err := t.seeker.Seek(t.headerPos, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(t.seeker, t.paddedHeader(t.written)); err != nil {
+			return err
+		}
+		_, err := t.seeker.Seek(0, io.SeekEnd)
+		return err
+	}
+}
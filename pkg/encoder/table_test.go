@@ -0,0 +1,156 @@
+package encoder
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+type tableRow struct {
+	ID   int    `toon:"id"`
+	Name string `toon:"name"`
+}
+
+func decodeTable(t *testing.T, data string) []interface{} {
+	t.Helper()
+	result, err := decoder.NewParser(strings.NewReader(data)).Parse()
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", data, err)
+	}
+	return result.(map[string]interface{})["users"].([]interface{})
+}
+
+func TestTableWriterKnownCount(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewTableWriter(&buf, "users", []string{"id", "name"}, 2, nil)
+	if err != nil {
+		t.Fatalf("NewTableWriter() error = %v", err)
+	}
+	if err := tw.WriteStruct(tableRow{ID: 1, Name: "Ada"}); err != nil {
+		t.Fatalf("WriteStruct() error = %v", err)
+	}
+	if err := tw.WriteRow(map[string]interface{}{"id": 2, "name": "Linus"}); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := tw.WriteRow([]interface{}{3, "extra"}); err == nil {
+		t.Error("WriteRow() expected error past declared count")
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := "users[2]{id,name}:\n  1,\"Ada\"\n  2,\"Linus\"\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+	if rows := decodeTable(t, buf.String()); len(rows) != 2 {
+		t.Errorf("decoded %d rows, want 2", len(rows))
+	}
+}
+
+func TestTableWriterValidation(t *testing.T) {
+	tw, err := NewTableWriter(io.Discard, "users", []string{"id", "name"}, 1, nil)
+	if err != nil {
+		t.Fatalf("NewTableWriter() error = %v", err)
+	}
+	if err := tw.WriteRow(map[string]interface{}{"id": 1, "email": "x"}); err == nil {
+		t.Error("WriteRow() expected error for undeclared field")
+	}
+	if err := tw.WriteRow([]interface{}{1}); err == nil {
+		t.Error("WriteRow() expected error for short row")
+	}
+	if err := tw.WriteRow([]interface{}{1, []int{2}}); err == nil {
+		t.Error("WriteRow() expected error for nested value")
+	}
+	if err := tw.Close(); err == nil {
+		t.Error("Close() expected error for missing rows")
+	}
+
+}
+
+func TestTableWriterQuotesKeys(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewTableWriter(&buf, "user list", []string{"id", "full name", "a,b"}, 1, nil)
+	if err != nil {
+		t.Fatalf("NewTableWriter() error = %v", err)
+	}
+	if err := tw.WriteRow([]interface{}{1, "Ada", true}); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if want := "\"user list\"[1]{id,\"full name\",\"a,b\"}:\n  1,\"Ada\",true\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	result, err := decoder.NewParser(strings.NewReader(buf.String())).Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	row := result.(map[string]interface{})["user list"].([]interface{})[0].(map[string]interface{})
+	if row["full name"] != "Ada" || row["a,b"] != true {
+		t.Errorf("decoded row = %v", row)
+	}
+}
+
+func TestSpooledTableWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewSpooledTableWriter(&buf, "users", []string{"id", "name"}, nil)
+	if err != nil {
+		t.Fatalf("NewSpooledTableWriter() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := tw.WriteStruct(&tableRow{ID: i, Name: "n"}); err != nil {
+			t.Fatalf("WriteStruct() error = %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("output written before Close: %q", buf.String())
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "users[3]{id,name}:\n") {
+		t.Errorf("output = %q", buf.String())
+	}
+	if rows := decodeTable(t, buf.String()); len(rows) != 3 {
+		t.Errorf("decoded %d rows, want 3", len(rows))
+	}
+}
+
+func TestSeekingTableWriter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "table-*.toon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw, err := NewSeekingTableWriter(f, "users", []string{"id", "name"}, nil)
+	if err != nil {
+		t.Fatalf("NewSeekingTableWriter() error = %v", err)
+	}
+	for i := 0; i < 12; i++ {
+		if err := tw.WriteRow([]interface{}{i, "n"}); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "users[12]{id,name}:") {
+		t.Errorf("output = %q", data)
+	}
+	if rows := decodeTable(t, string(data)); len(rows) != 12 {
+		t.Errorf("decoded %d rows, want 12", len(rows))
+	}
+}
@@ -0,0 +1,143 @@
+package tokens
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BPE is a byte-level byte pair encoding tokenizer driven by a rank table,
+// the same model used by tiktoken. Lower ranks merge first and every rank is
+// also the token id. It is safe for concurrent use.
+type BPE struct {
+	name    string
+	ranks   map[string]int
+	decoder map[int]string
+}
+
+// NewBPE returns a tokenizer for the given token ranks. Every single byte
+// must have a rank so that any input can be encoded.
+func NewBPE(name string, ranks map[string]int) (*BPE, error) {
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("tokens: vocabulary %s has no rank for byte 0x%02x", name, b)
+		}
+	}
+
+	decoder := make(map[int]string, len(ranks))
+	for token, rank := range ranks {
+		decoder[rank] = token
+	}
+	return &BPE{name: name, ranks: ranks, decoder: decoder}, nil
+}
+
+// LoadTiktoken reads a vocabulary in the tiktoken rank file format: one
+// "<base64 token> <rank>" pair per line.
+func LoadTiktoken(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		encoded, rankStr, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("tokens: %s line %d: missing rank", name, lineNum)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("tokens: %s line %d: %v", name, lineNum, err)
+		}
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil {
+			return nil, fmt.Errorf("tokens: %s line %d: invalid rank: %v", name, lineNum, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewBPE(name, ranks)
+}
+
+// Name returns the vocabulary name.
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Encode returns the token ids of text.
+func (b *BPE) Encode(text string) []int {
+	var ids []int
+	b.encode(text, func(id int) {
+		ids = append(ids, id)
+	})
+	return ids
+}
+
+// Count returns the number of tokens in text.
+func (b *BPE) Count(text string) int {
+	n := 0
+	b.encode(text, func(int) {
+		n++
+	})
+	return n
+}
+
+// Decode returns the text of the given token ids. Unknown ids are skipped.
+func (b *BPE) Decode(ids []int) string {
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(b.decoder[id])
+	}
+	return sb.String()
+}
+
+func (b *BPE) encode(text string, emit func(int)) {
+	var bounds []int
+	for len(text) > 0 {
+		n := nextPiece(text)
+		piece := text[:n]
+		text = text[n:]
+
+		if rank, ok := b.ranks[piece]; ok {
+			emit(rank)
+			continue
+		}
+
+		bounds = b.merge(piece, bounds[:0])
+		for i := 0; i+1 < len(bounds); i++ {
+			emit(b.ranks[piece[bounds[i]:bounds[i+1]]])
+		}
+	}
+}
+
+// merge applies byte pair merges to piece and returns the token boundaries,
+// reusing bounds for storage. At each step the adjacent pair with the lowest
+// rank is joined until no joined pair is in the vocabulary.
+func (b *BPE) merge(piece string, bounds []int) []int {
+	for i := 0; i <= len(piece); i++ {
+		bounds = append(bounds, i)
+	}
+
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return bounds
+}
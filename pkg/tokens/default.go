@@ -0,0 +1,29 @@
+package tokens
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen_vocab.go
+
+//go:embed toon_base.tiktoken
+var toonBase string
+
+var defaultBPE = sync.OnceValue(func() *BPE {
+	bpe, err := LoadTiktoken("toon_base", strings.NewReader(toonBase))
+	if err != nil {
+		panic(err)
+	}
+	return bpe
+})
+
+// Default returns the tokenizer for the embedded toon_base vocabulary, a
+// byte-level BPE vocabulary of 1536 tokens trained by gen_vocab.go on
+// synthetic records rendered as JSON and TOON. It needs no files and its
+// counts are stable, but they are not those of any model's tokenizer; load
+// a model's rank file with LoadTiktoken for the counts it will bill.
+func Default() *BPE {
+	return defaultBPE()
+}
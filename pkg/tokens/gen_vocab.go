@@ -0,0 +1,227 @@
+//go:build ignore
+
+// gen_vocab trains the embedded toon_base vocabulary. It runs byte pair
+// encoding training over a corpus of records generated from a fixed seed
+// and rendered as compact JSON, indented JSON and TOON with every
+// delimiter, so that neither format is favoured by the vocabulary, with
+// sentences of the same words in between. It reads no files: rerunning it
+// reproduces toon_base.tiktoken until the generator or the output of the
+// encoder changes.
+//
+//	go run gen_vocab.go
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/tokens"
+)
+
+const vocabSize = 1536
+
+func main() {
+	corpus, err := buildCorpus()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ranks := train(corpus, vocabSize)
+	if len(ranks) != vocabSize {
+		log.Fatalf("the corpus only yields %d tokens, not %d", len(ranks), vocabSize)
+	}
+
+	tokens := make([]string, len(ranks))
+	for token, rank := range ranks {
+		tokens[rank] = token
+	}
+
+	var out strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&out, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	if err := os.WriteFile("toon_base.tiktoken", []byte(out.String()), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var (
+	firstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Frances", "Edsger", "Radia", "John", "Maria", "Wei", "Priya", "Carlos"}
+	lastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Allen", "Dijkstra", "Perlman", "Smith", "Silva", "Zhang", "Patel", "Garcia"}
+	roles      = []string{"admin", "user", "editor", "viewer", "owner", "guest"}
+	statuses   = []string{"active", "pending", "shipped", "delivered", "cancelled", "failed", "completed"}
+	words      = []string{"the", "order", "was", "processed", "successfully", "payment", "failed", "retry", "later", "customer", "requested", "refund", "item", "out", "of", "stock", "new", "account", "created", "for", "user", "login", "from", "device", "error", "timeout", "while", "connecting", "to", "database", "service", "started"}
+	domains    = []string{"example.com", "mail.com", "company.org", "test.io"}
+)
+
+func sentence(r *rand.Rand, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[r.Intn(len(words))]
+	}
+	return strings.Join(parts, " ")
+}
+
+func record(r *rand.Rand, i int) map[string]interface{} {
+	first := firstNames[r.Intn(len(firstNames))]
+	last := lastNames[r.Intn(len(lastNames))]
+	return map[string]interface{}{
+		"id":         i + 1,
+		"name":       first + " " + last,
+		"email":      strings.ToLower(first+"."+last) + "@" + domains[r.Intn(len(domains))],
+		"role":       roles[r.Intn(len(roles))],
+		"active":     r.Intn(2) == 0,
+		"score":      float64(r.Intn(10000)) / 100,
+		"created_at": fmt.Sprintf("2024-%02d-%02dT%02d:%02d:00Z", r.Intn(12)+1, r.Intn(28)+1, r.Intn(24), r.Intn(60)),
+	}
+}
+
+func documents(r *rand.Rand) []interface{} {
+	var docs []interface{}
+	for d := 0; d < 60; d++ {
+		users := make([]interface{}, 2+r.Intn(20))
+		for i := range users {
+			users[i] = record(r, i)
+		}
+
+		orders := make([]interface{}, 1+r.Intn(5))
+		for i := range orders {
+			items := make([]interface{}, 1+r.Intn(4))
+			for j := range items {
+				items[j] = map[string]interface{}{
+					"sku":   fmt.Sprintf("SKU-%04d", r.Intn(10000)),
+					"qty":   1 + r.Intn(9),
+					"price": float64(r.Intn(100000)) / 100,
+				}
+			}
+			orders[i] = map[string]interface{}{
+				"order_id": fmt.Sprintf("ORD-%06d", r.Intn(1000000)),
+				"status":   statuses[r.Intn(len(statuses))],
+				"customer": record(r, r.Intn(1000)),
+				"items":    items,
+				"tags":     []string{roles[r.Intn(len(roles))], statuses[r.Intn(len(statuses))]},
+				"note":     sentence(r, 3+r.Intn(10)),
+			}
+		}
+
+		docs = append(docs,
+			map[string]interface{}{"users": users, "total": len(users), "page": d + 1},
+			map[string]interface{}{"orders": orders},
+			users,
+		)
+	}
+	return docs
+}
+
+func buildCorpus() ([]string, error) {
+	var corpus []string
+	r := rand.New(rand.NewSource(1))
+	for _, doc := range documents(r) {
+		corpus = append(corpus, sentence(r, 20+r.Intn(20)))
+
+		compact, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		indented, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		corpus = append(corpus, string(compact), string(indented))
+
+		for _, delim := range []byte{encoder.Comma, encoder.Tab, encoder.Pipe} {
+			data, err := encoder.Append(nil, doc, &encoder.Options{Indent: "  ", TokenOptimized: true, Delimiter: delim})
+			if err != nil {
+				return nil, err
+			}
+			corpus = append(corpus, string(data))
+		}
+	}
+	return corpus, nil
+}
+
+type word struct {
+	symbols []string
+	count   int
+}
+
+type pair struct{ a, b string }
+
+// train learns size-256 merges on top of the 256 single bytes. Ties between
+// equally frequent pairs are broken lexicographically so the output is
+// deterministic.
+func train(corpus []string, size int) map[string]int {
+	counts := map[string]int{}
+	for _, text := range corpus {
+		for _, piece := range tokens.Split(text) {
+			counts[piece]++
+		}
+	}
+
+	pieces := make([]string, 0, len(counts))
+	for piece := range counts {
+		pieces = append(pieces, piece)
+	}
+	sort.Strings(pieces)
+
+	vocab := make([]word, len(pieces))
+	for i, piece := range pieces {
+		symbols := make([]string, len(piece))
+		for j := 0; j < len(piece); j++ {
+			symbols[j] = piece[j : j+1]
+		}
+		vocab[i] = word{symbols: symbols, count: counts[piece]}
+	}
+
+	ranks := make(map[string]int, size)
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+
+	for len(ranks) < size {
+		pairs := map[pair]int{}
+		for _, w := range vocab {
+			for i := 0; i+1 < len(w.symbols); i++ {
+				pairs[pair{w.symbols[i], w.symbols[i+1]}] += w.count
+			}
+		}
+
+		var best pair
+		bestCount := 0
+		for p, c := range pairs {
+			if c > bestCount || c == bestCount && p.a+"\x00"+p.b < best.a+"\x00"+best.b {
+				best, bestCount = p, c
+			}
+		}
+		if bestCount < 2 {
+			break
+		}
+
+		merged := best.a + best.b
+		if _, ok := ranks[merged]; !ok {
+			ranks[merged] = len(ranks)
+		}
+
+		for i := range vocab {
+			symbols := vocab[i].symbols
+			out := symbols[:0]
+			for j := 0; j < len(symbols); j++ {
+				if j+1 < len(symbols) && symbols[j] == best.a && symbols[j+1] == best.b {
+					out = append(out, merged)
+					j++
+					continue
+				}
+				out = append(out, symbols[j])
+			}
+			vocab[i].symbols = out
+		}
+	}
+	return ranks
+}
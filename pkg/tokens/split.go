@@ -0,0 +1,157 @@
+package tokens
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// nextPiece returns the length of the pre-token at the start of s. It
+// implements the cl100k_base split pattern by hand because Go's regexp
+// package has no lookahead:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// The alternatives are tried in order, like the regular expression.
+func nextPiece(s string) int {
+	r0, n0 := utf8.DecodeRuneInString(s)
+
+	// Contractions.
+	if r0 == '\'' && len(s) > 1 {
+		for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+			if len(s) > len(suffix) && equalFold(s[1:1+len(suffix)], suffix) {
+				return 1 + len(suffix)
+			}
+		}
+	}
+
+	// Letters, optionally preceded by one other non-newline character.
+	if isLetter(r0) {
+		return n0 + spanLetters(s[n0:])
+	}
+	if r0 != '\r' && r0 != '\n' && !isNumber(r0) && n0 < len(s) {
+		if r1, _ := utf8.DecodeRuneInString(s[n0:]); isLetter(r1) {
+			return n0 + spanLetters(s[n0:])
+		}
+	}
+
+	// Up to three digits.
+	if isNumber(r0) {
+		n, digits := 0, 0
+		for n < len(s) && digits < 3 {
+			r, size := utf8.DecodeRuneInString(s[n:])
+			if !isNumber(r) {
+				break
+			}
+			n += size
+			digits++
+		}
+		return n
+	}
+
+	// Punctuation, optionally preceded by a space and followed by newlines.
+	start := 0
+	if r0 == ' ' && n0 < len(s) {
+		start = n0
+	}
+	if n := spanPunct(s[start:]); n > 0 {
+		n += start
+		for n < len(s) && (s[n] == '\r' || s[n] == '\n') {
+			n++
+		}
+		return n
+	}
+
+	// Whitespace.
+	spaces, lastNewline := 0, -1
+	for spaces < len(s) {
+		r, size := utf8.DecodeRuneInString(s[spaces:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		if r == '\r' || r == '\n' {
+			lastNewline = spaces + size
+		}
+		spaces += size
+	}
+	if spaces > 0 {
+		if lastNewline > 0 {
+			return lastNewline
+		}
+		if spaces == len(s) {
+			return spaces
+		}
+		// Leave the last space to prefix the following word.
+		_, size := utf8.DecodeLastRuneInString(s[:spaces])
+		if spaces > size {
+			return spaces - size
+		}
+		return spaces
+	}
+
+	return n0
+}
+
+func spanLetters(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isLetter(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func spanPunct(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || isLetter(r) || isNumber(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isLetter(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+	}
+	return unicode.IsLetter(r)
+}
+
+func isNumber(r rune) bool {
+	if r < utf8.RuneSelf {
+		return '0' <= r && r <= '9'
+	}
+	return unicode.IsNumber(r)
+}
+
+// equalFold compares ASCII strings case-insensitively.
+func equalFold(s, lower string) bool {
+	for i := 0; i < len(lower); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Split returns the pre-tokens of text using the cl100k_base pattern. BPE
+// tokens never cross pre-token boundaries.
+func Split(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		n := nextPiece(text)
+		pieces = append(pieces, text[:n])
+		text = text[n:]
+	}
+	return pieces
+}
@@ -0,0 +1,24 @@
+// Package tokens counts LLM tokens offline so that TOON and JSON encodings
+// of the same value can be compared.
+//
+// Tokenizer is the extension point: any model tokenizer can be plugged in.
+// BPE implements byte-level byte pair encoding over tiktoken-format rank
+// files, so the published vocabularies of common models (for example
+// cl100k_base or o200k_base) can be loaded with LoadTiktoken. Default
+// returns a compact vocabulary embedded in this package, which works without
+// network access or extra files but is not any model's vocabulary: its
+// counts are for comparing encodings with each other, and may be higher or
+// lower than a model's. Load the model's own vocabulary when the counts
+// themselves matter.
+package tokens
+
+// Tokenizer splits text into model tokens.
+type Tokenizer interface {
+	// Name identifies the vocabulary, for example "cl100k_base".
+	Name() string
+	// Encode returns the token ids of text.
+	Encode(text string) []int
+	// Count returns the number of tokens in text, which is len(Encode(text))
+	// but may be computed without allocating the ids.
+	Count(text string) int
+}
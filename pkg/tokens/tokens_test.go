@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"it's 12345 ok", []string{"it", "'s", " ", "123", "45", " ok"}},
+		{`{"id":1}`, []string{`{"`, "id", `":`, "1", "}"}},
+		{"a  b\n\n  c", []string{"a", " ", " b", "\n\n", " ", " c"}},
+		{"x: \"y\"\n", []string{"x", ":", ` "`, "y", "\"\n"}},
+		{"end   ", []string{"end", "   "}},
+		{"héllo wörld", []string{"héllo", " wörld"}},
+	}
+
+	for _, tt := range tests {
+		if got := Split(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBPEMerges(t *testing.T) {
+	ranks := map[string]int{}
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	ranks["ab"] = 256
+	ranks["abc"] = 257
+	ranks["bc"] = 258
+
+	bpe, err := NewBPE("test", ranks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := bpe.Encode("abcd"); !reflect.DeepEqual(got, []int{257, 'd'}) {
+		t.Errorf("Encode(abcd) = %v", got)
+	}
+	if got := bpe.Encode("bcab"); !reflect.DeepEqual(got, []int{258, 256}) {
+		t.Errorf("Encode(bcab) = %v", got)
+	}
+	if got := bpe.Count("abc abc"); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+}
+
+func TestLoadTiktoken(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("aGk= 256\n")
+	if _, err := LoadTiktoken("partial", strings.NewReader(sb.String())); err == nil {
+		t.Error("expected error for vocabulary without single bytes")
+	}
+
+	if _, err := LoadTiktoken("bad", strings.NewReader("not-base64! 1\n")); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
+
+func TestDefaultRoundTrip(t *testing.T) {
+	bpe := Default()
+	inputs := []string{
+		"users[2]{id,name}:\n  1,\"Ada\"\n  2,\"Grace\"\n",
+		`{"users":[{"id":1,"name":"Ada"}]}`,
+		"Unicode: ñandú 日本語 🚀",
+		"",
+	}
+
+	for _, input := range inputs {
+		ids := bpe.Encode(input)
+		if got := bpe.Decode(ids); got != input {
+			t.Errorf("Decode(Encode(%q)) = %q", input, got)
+		}
+		if bpe.Count(input) != len(ids) {
+			t.Errorf("Count(%q) = %d, want %d", input, bpe.Count(input), len(ids))
+		}
+		if len(input) > 0 && len(ids) >= len(input) && strings.Contains(input, "users") {
+			t.Errorf("Encode(%q) did not merge any bytes: %v", input, ids)
+		}
+	}
+}
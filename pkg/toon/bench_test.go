@@ -0,0 +1,74 @@
+package toon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type benchEmployee struct {
+	ID     int     `toon:"id" json:"id"`
+	Name   string  `toon:"name" json:"name"`
+	Role   string  `toon:"role" json:"role"`
+	Salary float64 `toon:"salary" json:"salary"`
+	Active bool    `toon:"active" json:"active"`
+}
+
+type benchCompany struct {
+	Name      string            `toon:"name" json:"name"`
+	Founded   int               `toon:"founded" json:"founded"`
+	Tags      []string          `toon:"tags" json:"tags"`
+	Meta      map[string]string `toon:"meta" json:"meta"`
+	Employees []benchEmployee   `toon:"employees" json:"employees"`
+}
+
+func benchData() benchCompany {
+	company := benchCompany{
+		Name:    "Tech Corp",
+		Founded: 1999,
+		Tags:    []string{"software", "consulting", "ai"},
+		Meta:    map[string]string{"region": "emea", "tier": "gold", "owner": "ops"},
+	}
+	for i := 0; i < 100; i++ {
+		company.Employees = append(company.Employees, benchEmployee{
+			ID:     i,
+			Name:   "Employee Name",
+			Role:   "Developer",
+			Salary: 75000.5,
+			Active: i%2 == 0,
+		})
+	}
+	return company
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	data := benchData()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendMarshal(b *testing.B) {
+	data := benchData()
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		buf, err = AppendMarshal(buf[:0], data)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONMarshal(b *testing.B) {
+	data := benchData()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/tokens"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// ErrOverBudget is returned by MarshalBudget when even the most reduced
+// encoding is larger than the budget.
+var ErrOverBudget = errors.New("toon: value does not fit the token budget")
+
+// Strategy selects which rows MarshalBudget keeps when it truncates arrays.
+type Strategy int
+
+const (
+	// KeepHeadTail keeps rows from both ends of a truncated array, favouring
+	// the head when the number kept is odd.
+	KeepHeadTail Strategy = iota
+	// KeepHead keeps only the first rows.
+	KeepHead
+)
+
+// Budget limits the size of the encoding produced by MarshalBudget.
+type Budget struct {
+	MaxTokens int
+	// Tokenizer counts tokens; nil uses tokens.Default.
+	Tokenizer tokens.Tokenizer
+	Strategy  Strategy
+}
+
+// ElisionKind describes what MarshalBudget removed.
+type ElisionKind int
+
+const (
+	// ElidedField is a dropped field tagged toon:",priority=low".
+	ElidedField ElisionKind = iota
+	// ElidedRows is an array with rows removed.
+	ElidedRows
+	// ElidedChars is a string with its end removed.
+	ElidedChars
+)
+
+func (k ElisionKind) String() string {
+	switch k {
+	case ElidedField:
+		return "field"
+	case ElidedRows:
+		return "rows"
+	case ElidedChars:
+		return "chars"
+	default:
+		return "ElisionKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Elision records one reduction made to fit a budget.
+type Elision struct {
+	// Path locates the value, such as "orders[2].note"; it is empty for a
+	// root value.
+	Path string
+	Kind ElisionKind
+	// Removed is the number of rows or characters removed, or 1 for a field.
+	Removed int
+	// Kept is the number of rows or characters still present.
+	Kept int
+}
+
+// BudgetReport describes the encoding returned by MarshalBudget.
+type BudgetReport struct {
+	Tokens   int
+	Elisions []Elision
+}
+
+// ellipsis marks the end of a shortened string.
+const ellipsis = "…"
+
+// minStringChars is the length below which strings are not shortened.
+const minStringChars = 16
+
+// MarshalBudget encodes v like Marshal while keeping the output within
+// b.MaxTokens. When the full encoding is too large it reduces it in this
+// order until it fits: fields tagged toon:",priority=low" are dropped,
+// largest first; long arrays are truncated to the same maximum number of
+// rows, chosen according to b.Strategy; long strings are cut to the same
+// maximum length and end with "…".
+//
+// The result is always valid TOON whose array counts match the emitted
+// rows; the report lists every elision. If the value cannot be reduced
+// enough, the smallest encoding is returned together with ErrOverBudget.
+func MarshalBudget(v interface{}, b Budget) ([]byte, BudgetReport, error) {
+	if b.MaxTokens <= 0 {
+		return nil, BudgetReport{}, fmt.Errorf("toon: invalid token budget %d", b.MaxTokens)
+	}
+	tok := b.Tokenizer
+	if tok == nil {
+		tok = tokens.Default()
+	}
+
+	root := buildBudgetNode(reflect.ValueOf(v))
+	stats := root.stats()
+	lim := budgetLimits{
+		strategy: b.Strategy,
+		maxRows:  stats.maxRows,
+		maxChars: stats.maxChars,
+		dropped:  map[*budgetField]bool{},
+	}
+
+	encode := func() ([]byte, int, error) {
+		data, err := encoder.Append(nil, root.render(&lim, "", nil), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		return data, tok.Count(string(data)), nil
+	}
+	fits := func() (bool, error) {
+		_, n, err := encode()
+		return n <= b.MaxTokens, err
+	}
+
+	ok, err := fits()
+	if err != nil {
+		return nil, BudgetReport{}, err
+	}
+
+	if !ok {
+		low := root.lowFields()
+		sort.SliceStable(low, func(i, j int) bool { return low[i].size > low[j].size })
+		for _, field := range low {
+			lim.dropped[field] = true
+			if ok, err = fits(); ok || err != nil {
+				break
+			}
+		}
+	}
+	if !ok && err == nil && stats.maxRows > 1 {
+		lim.maxRows, ok, err = largestFitting(1, stats.maxRows-1, func(n int) (bool, error) {
+			lim.maxRows = n
+			return fits()
+		})
+	}
+	if !ok && err == nil && stats.maxChars > minStringChars {
+		lim.maxChars, ok, err = largestFitting(minStringChars, stats.maxChars-1, func(n int) (bool, error) {
+			lim.maxChars = n
+			return fits()
+		})
+	}
+	if err != nil {
+		return nil, BudgetReport{}, err
+	}
+
+	var report BudgetReport
+	data, err := encoder.Append(nil, root.render(&lim, "", &report.Elisions), nil)
+	if err != nil {
+		return nil, BudgetReport{}, err
+	}
+	report.Tokens = tok.Count(string(data))
+	if !ok {
+		return data, report, ErrOverBudget
+	}
+	return data, report, nil
+}
+
+// largestFitting binary searches [lo, hi] for the largest n for which fits
+// holds, assuming smaller values fit at least as well. It returns lo and
+// false when nothing fits.
+func largestFitting(lo, hi int, fits func(int) (bool, error)) (int, bool, error) {
+	best, found := lo, false
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			best, found = mid, true
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best, found, nil
+}
+
+type budgetKind int
+
+const (
+	budgetValue budgetKind = iota
+	budgetString
+	budgetObject
+	budgetArray
+)
+
+// budgetNode is a value prepared for repeated encoding under shrinking
+// limits.
+type budgetNode struct {
+	kind   budgetKind
+	value  interface{} // budgetValue: primitives and Marshalers, written as is
+	str    string
+	fields []budgetField
+	items  []*budgetNode
+}
+
+type budgetField struct {
+	key  string
+	node *budgetNode
+	low  bool
+	size int
+}
+
+type budgetLimits struct {
+	strategy Strategy
+	maxRows  int
+	maxChars int
+	dropped  map[*budgetField]bool
+}
+
+var marshalerType = reflect.TypeFor[Marshaler]()
+
+func buildBudgetNode(rv reflect.Value) *budgetNode {
+	for rv.IsValid() {
+		if rv.Type().Implements(marshalerType) && rv.CanInterface() {
+			return &budgetNode{value: rv.Interface()}
+		}
+		if rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Interface {
+			break
+		}
+		if rv.IsNil() {
+			return &budgetNode{}
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return &budgetNode{}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return &budgetNode{kind: budgetString, str: rv.String()}
+
+	case reflect.Map:
+		node := &budgetNode{kind: budgetObject}
+		iter := rv.MapRange()
+		for iter.Next() {
+			node.fields = append(node.fields, budgetField{key: fmt.Sprint(iter.Key().Interface()), node: buildBudgetNode(iter.Value())})
+		}
+		sort.Slice(node.fields, func(i, j int) bool { return node.fields[i].key < node.fields[j].key })
+		return node.sized()
+
+	case reflect.Struct:
+		node := &budgetNode{kind: budgetObject}
+		info := types.CachedStruct(rv.Type())
+		for i := range info.Fields {
+			field := &info.Fields[i]
+			value := field.Value(rv)
+			if !value.IsValid() || field.OmitEmpty && types.IsEmptyValue(value) {
+				continue
+			}
+			priority, _ := field.Option("priority")
+			node.fields = append(node.fields, budgetField{key: field.Name, node: buildBudgetNode(value), low: priority == "low"})
+		}
+		return node.sized()
+
+	case reflect.Slice, reflect.Array:
+		if rv.Type() == reflect.TypeFor[types.Object]() {
+			node := &budgetNode{kind: budgetObject}
+			for _, m := range rv.Interface().(types.Object) {
+				node.fields = append(node.fields, budgetField{key: m.Key, node: buildBudgetNode(reflect.ValueOf(m.Value))})
+			}
+			return node.sized()
+		}
+		node := &budgetNode{kind: budgetArray, items: make([]*budgetNode, rv.Len())}
+		for i := range node.items {
+			node.items[i] = buildBudgetNode(rv.Index(i))
+		}
+		return node
+
+	default:
+		if !rv.CanInterface() {
+			return &budgetNode{}
+		}
+		return &budgetNode{value: rv.Interface()}
+	}
+}
+
+// sized records the encoded size of low-priority fields, which decides the
+// order in which they are dropped.
+func (n *budgetNode) sized() *budgetNode {
+	lim := budgetLimits{maxRows: -1, maxChars: -1}
+	for i := range n.fields {
+		if f := &n.fields[i]; f.low {
+			data, _ := encoder.Append(nil, types.Object{{Key: f.key, Value: f.node.render(&lim, "", nil)}}, nil)
+			f.size = len(data)
+		}
+	}
+	return n
+}
+
+type budgetStats struct {
+	maxRows  int
+	maxChars int
+}
+
+func (n *budgetNode) stats() budgetStats {
+	var s budgetStats
+	n.walk(func(n *budgetNode) {
+		switch n.kind {
+		case budgetArray:
+			s.maxRows = max(s.maxRows, len(n.items))
+		case budgetString:
+			s.maxChars = max(s.maxChars, utf8.RuneCountInString(n.str))
+		}
+	})
+	return s
+}
+
+func (n *budgetNode) lowFields() []*budgetField {
+	var low []*budgetField
+	n.walk(func(n *budgetNode) {
+		for i := range n.fields {
+			if n.fields[i].low {
+				low = append(low, &n.fields[i])
+			}
+		}
+	})
+	return low
+}
+
+func (n *budgetNode) walk(fn func(*budgetNode)) {
+	fn(n)
+	for i := range n.fields {
+		n.fields[i].node.walk(fn)
+	}
+	for _, item := range n.items {
+		item.walk(fn)
+	}
+}
+
+// render returns the value to encode under lim. Negative limits disable
+// truncation. When elisions is not nil every reduction is appended to it.
+func (n *budgetNode) render(lim *budgetLimits, path string, elisions *[]Elision) interface{} {
+	switch n.kind {
+	case budgetString:
+		chars := utf8.RuneCountInString(n.str)
+		if lim.maxChars < 0 || chars <= lim.maxChars {
+			return n.str
+		}
+		cut := 0
+		for i := 0; i < lim.maxChars; i++ {
+			_, size := utf8.DecodeRuneInString(n.str[cut:])
+			cut += size
+		}
+		if elisions != nil {
+			*elisions = append(*elisions, Elision{Path: path, Kind: ElidedChars, Removed: chars - lim.maxChars, Kept: lim.maxChars})
+		}
+		return n.str[:cut] + ellipsis
+
+	case budgetObject:
+		obj := make(types.Object, 0, len(n.fields))
+		for i := range n.fields {
+			f := &n.fields[i]
+			fieldPath := joinBudgetPath(path, f.key)
+			if lim.dropped[f] {
+				if elisions != nil {
+					*elisions = append(*elisions, Elision{Path: fieldPath, Kind: ElidedField, Removed: 1})
+				}
+				continue
+			}
+			obj = append(obj, types.Member{Key: f.key, Value: f.node.render(lim, fieldPath, elisions)})
+		}
+		return obj
+
+	case budgetArray:
+		indexes := keptRows(len(n.items), lim)
+		if len(indexes) < len(n.items) && elisions != nil {
+			*elisions = append(*elisions, Elision{Path: path, Kind: ElidedRows, Removed: len(n.items) - len(indexes), Kept: len(indexes)})
+		}
+		items := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			items[i] = n.items[index].render(lim, path+"["+strconv.Itoa(index)+"]", elisions)
+		}
+		return items
+
+	default:
+		return n.value
+	}
+}
+
+// keptRows returns the indexes of the rows kept from an array of n rows.
+func keptRows(n int, lim *budgetLimits) []int {
+	keep := n
+	if lim.maxRows >= 0 && n > lim.maxRows {
+		keep = lim.maxRows
+	}
+
+	indexes := make([]int, 0, keep)
+	head := keep
+	if lim.strategy == KeepHeadTail {
+		head = (keep + 1) / 2
+	}
+	for i := 0; i < head; i++ {
+		indexes = append(indexes, i)
+	}
+	for i := n - (keep - head); i < n; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func joinBudgetPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,153 @@
+package toon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+type budgetRow struct {
+	ID   int    `toon:"id"`
+	Name string `toon:"name"`
+}
+
+type budgetDoc struct {
+	Title string      `toon:"title"`
+	Rows  []budgetRow `toon:"rows"`
+	Debug string      `toon:"debug,priority=low"`
+}
+
+func budgetFixture() budgetDoc {
+	doc := budgetDoc{
+		Title: "report",
+		Debug: strings.Repeat("trace ", 40),
+	}
+	for i := 0; i < 50; i++ {
+		doc.Rows = append(doc.Rows, budgetRow{ID: i, Name: fmt.Sprintf("row-%d", i)})
+	}
+	return doc
+}
+
+func TestMarshalBudgetFits(t *testing.T) {
+	doc := budgetFixture()
+	full, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, report, err := MarshalBudget(doc, Budget{MaxTokens: 1 << 20, Tokenizer: countingTokenizer{}})
+	if err != nil {
+		t.Fatalf("MarshalBudget() error = %v", err)
+	}
+	if string(data) != string(full) || len(report.Elisions) != 0 {
+		t.Errorf("expected the full encoding, got %d elisions", len(report.Elisions))
+	}
+}
+
+func TestMarshalBudgetDropsLowPriorityFirst(t *testing.T) {
+	doc := budgetFixture()
+	full, _ := Marshal(doc)
+
+	// Dropping the debug field alone is enough.
+	data, report, err := MarshalBudget(doc, Budget{MaxTokens: len(full) - 200, Tokenizer: countingTokenizer{}})
+	if err != nil {
+		t.Fatalf("MarshalBudget() error = %v", err)
+	}
+	if strings.Contains(string(data), "debug") {
+		t.Errorf("debug field was not dropped:\n%s", data)
+	}
+	if len(report.Elisions) != 1 || report.Elisions[0] != (Elision{Path: "debug", Kind: ElidedField, Removed: 1}) {
+		t.Errorf("Elisions = %+v", report.Elisions)
+	}
+	if report.Tokens != len(data) {
+		t.Errorf("Tokens = %d, want %d", report.Tokens, len(data))
+	}
+}
+
+func TestMarshalBudgetTruncatesRows(t *testing.T) {
+	doc := budgetFixture()
+
+	for _, strategy := range []Strategy{KeepHeadTail, KeepHead} {
+		data, report, err := MarshalBudget(doc, Budget{MaxTokens: 200, Tokenizer: countingTokenizer{}, Strategy: strategy})
+		if err != nil {
+			t.Fatalf("MarshalBudget() error = %v", err)
+		}
+		if len(data) > 200 {
+			t.Errorf("output has %d tokens, budget 200", len(data))
+		}
+
+		var out budgetDoc
+		if err := Unmarshal(data, &out); err != nil {
+			t.Fatalf("output is not valid TOON: %v\n%s", err, data)
+		}
+
+		var rows *Elision
+		for i := range report.Elisions {
+			if report.Elisions[i].Kind == ElidedRows {
+				rows = &report.Elisions[i]
+			}
+		}
+		if rows == nil || rows.Path != "rows" || rows.Kept != len(out.Rows) || rows.Kept+rows.Removed != 50 {
+			t.Fatalf("rows elision = %+v, decoded %d rows", rows, len(out.Rows))
+		}
+
+		last := out.Rows[len(out.Rows)-1].ID
+		if strategy == KeepHeadTail && last != 49 {
+			t.Errorf("KeepHeadTail: last row = %d, want 49", last)
+		}
+		if strategy == KeepHead && last != len(out.Rows)-1 {
+			t.Errorf("KeepHead: last row = %d, want %d", last, len(out.Rows)-1)
+		}
+	}
+}
+
+func TestMarshalBudgetShortensStrings(t *testing.T) {
+	v := map[string]interface{}{
+		"summary": strings.Repeat("abcdefghij", 30),
+		"tags":    []string{"x"},
+	}
+
+	data, report, err := MarshalBudget(v, Budget{MaxTokens: 100, Tokenizer: countingTokenizer{}})
+	if err != nil {
+		t.Fatalf("MarshalBudget() error = %v", err)
+	}
+
+	result, err := decoder.ParseBytes(data)
+	if err != nil {
+		t.Fatalf("output is not valid TOON: %v", err)
+	}
+	summary := result.(map[string]interface{})["summary"].(string)
+	if !strings.HasSuffix(summary, "…") || len(summary) >= 300 {
+		t.Errorf("summary not shortened: %q", summary)
+	}
+	if len(report.Elisions) != 1 || report.Elisions[0].Kind != ElidedChars || report.Elisions[0].Path != "summary" {
+		t.Errorf("Elisions = %+v", report.Elisions)
+	}
+}
+
+func TestMarshalBudgetOverBudget(t *testing.T) {
+	data, report, err := MarshalBudget(budgetFixture(), Budget{MaxTokens: 5, Tokenizer: countingTokenizer{}})
+	if !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("error = %v, want ErrOverBudget", err)
+	}
+	if len(data) == 0 || report.Tokens <= 5 {
+		t.Errorf("expected the smallest encoding, got %d tokens", report.Tokens)
+	}
+
+	if _, _, err := MarshalBudget(1, Budget{}); err == nil {
+		t.Error("expected error for zero budget")
+	}
+}
+
+func TestMarshalBudgetDefaultTokenizer(t *testing.T) {
+	_, report, err := MarshalBudget(budgetFixture(), Budget{MaxTokens: 150})
+	if err != nil {
+		t.Fatalf("MarshalBudget() error = %v", err)
+	}
+	if report.Tokens > 150 {
+		t.Errorf("Tokens = %d, budget 150", report.Tokens)
+	}
+}
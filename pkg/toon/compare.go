@@ -0,0 +1,123 @@
+package toon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/tokens"
+)
+
+// FormatStats is the size of one encoding of a value.
+type FormatStats struct {
+	// Format is one of "json", "json-indent", "toon", "toon-tab" or "toon-pipe".
+	Format string
+	Bytes  int
+	Tokens int
+}
+
+// TokenSavings returns the fraction of base's tokens that f saves. It is
+// negative when f is larger.
+func (f FormatStats) TokenSavings(base FormatStats) float64 {
+	if base.Tokens == 0 {
+		return 0
+	}
+	return 1 - float64(f.Tokens)/float64(base.Tokens)
+}
+
+// ByteSavings returns the fraction of base's bytes that f saves. It is
+// negative when f is larger.
+func (f FormatStats) ByteSavings(base FormatStats) float64 {
+	if base.Bytes == 0 {
+		return 0
+	}
+	return 1 - float64(f.Bytes)/float64(base.Bytes)
+}
+
+// Comparison reports the size of a value encoded as compact JSON, indented
+// JSON and TOON with each delimiter.
+type Comparison struct {
+	Tokenizer  string
+	JSON       FormatStats
+	JSONIndent FormatStats
+	// TOON holds the comma, tab and pipe delimited encodings, in that order.
+	TOON []FormatStats
+}
+
+// Best returns the TOON encoding with the fewest tokens.
+func (c Comparison) Best() FormatStats {
+	best := c.TOON[0]
+	for _, f := range c.TOON[1:] {
+		if f.Tokens < best.Tokens {
+			best = f
+		}
+	}
+	return best
+}
+
+// String formats the comparison as a table with the token savings of each
+// TOON encoding against both JSON encodings.
+func (c Comparison) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "tokenizer: %s\n", c.Tokenizer)
+
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "format\tbytes\ttokens\tvs json\tvs json-indent\t\n")
+	for _, f := range []FormatStats{c.JSON, c.JSONIndent} {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\t\t\n", f.Format, f.Bytes, f.Tokens)
+	}
+	for _, f := range c.TOON {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%.1f%%\t\n", f.Format, f.Bytes, f.Tokens,
+			100*f.TokenSavings(c.JSON), 100*f.TokenSavings(c.JSONIndent))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// Compare encodes v as JSON and TOON and counts the tokens of each encoding
+// with the embedded tokenizer, tokens.Default.
+func Compare(v interface{}) (Comparison, error) {
+	return CompareWith(v, tokens.Default())
+}
+
+// CompareWith is like Compare but counts tokens with tok.
+func CompareWith(v interface{}, tok tokens.Tokenizer) (Comparison, error) {
+	c := Comparison{Tokenizer: tok.Name()}
+
+	stats := func(format string, data []byte) FormatStats {
+		return FormatStats{Format: format, Bytes: len(data), Tokens: tok.Count(string(data))}
+	}
+
+	compact, err := json.Marshal(v)
+	if err != nil {
+		return Comparison{}, err
+	}
+	c.JSON = stats("json", compact)
+
+	indented, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return Comparison{}, err
+	}
+	c.JSONIndent = stats("json-indent", indented)
+
+	delimiters := []struct {
+		format string
+		delim  byte
+	}{
+		{"toon", encoder.Comma},
+		{"toon-tab", encoder.Tab},
+		{"toon-pipe", encoder.Pipe},
+	}
+	opts := encoder.DefaultOptions()
+	for _, d := range delimiters {
+		opts.Delimiter = d.delim
+		data, err := encoder.Append(nil, v, &opts)
+		if err != nil {
+			return Comparison{}, err
+		}
+		c.TOON = append(c.TOON, stats(d.format, data))
+	}
+	return c, nil
+}
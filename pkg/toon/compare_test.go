@@ -0,0 +1,75 @@
+package toon
+
+import (
+	"strings"
+	"testing"
+)
+
+type countingTokenizer struct{}
+
+func (countingTokenizer) Name() string { return "bytes" }
+
+func (countingTokenizer) Encode(text string) []int {
+	ids := make([]int, len(text))
+	for i := range text {
+		ids[i] = int(text[i])
+	}
+	return ids
+}
+
+func (countingTokenizer) Count(text string) int { return len(text) }
+
+func TestCompare(t *testing.T) {
+	type user struct {
+		ID     int    `json:"id" toon:"id"`
+		Name   string `json:"name" toon:"name"`
+		Active bool   `json:"active" toon:"active"`
+	}
+	users := map[string]interface{}{
+		"users": []user{{1, "Ada", true}, {2, "Grace", false}, {3, "Linus", true}},
+	}
+
+	c, err := Compare(users)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if c.Tokenizer != "toon_base" {
+		t.Errorf("Tokenizer = %q", c.Tokenizer)
+	}
+	if len(c.TOON) != 3 || c.TOON[0].Format != "toon" || c.TOON[1].Format != "toon-tab" || c.TOON[2].Format != "toon-pipe" {
+		t.Fatalf("unexpected TOON formats: %+v", c.TOON)
+	}
+	for _, f := range c.TOON {
+		if f.Tokens >= c.JSON.Tokens || f.Tokens >= c.JSONIndent.Tokens {
+			t.Errorf("%s uses %d tokens, JSON %d, indented JSON %d", f.Format, f.Tokens, c.JSON.Tokens, c.JSONIndent.Tokens)
+		}
+	}
+
+	report := c.String()
+	for _, want := range []string{"tokenizer: toon_base", "json-indent", "toon-pipe", "%"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestCompareWith(t *testing.T) {
+	c, err := CompareWith(map[string]int{"a": 1}, countingTokenizer{})
+	if err != nil {
+		t.Fatalf("CompareWith() error = %v", err)
+	}
+
+	// {"a":1} versus a: 1
+	if c.JSON.Bytes != 7 || c.JSON.Tokens != 7 {
+		t.Errorf("JSON = %+v", c.JSON)
+	}
+	if c.TOON[0].Bytes != 5 || c.TOON[0].Tokens != 5 {
+		t.Errorf("TOON = %+v", c.TOON[0])
+	}
+	if got := c.TOON[0].TokenSavings(c.JSON); got < 0.28 || got > 0.29 {
+		t.Errorf("TokenSavings() = %v, want 2/7", got)
+	}
+	if c.Best().Format != "toon" {
+		t.Errorf("Best() = %+v", c.Best())
+	}
+}
@@ -0,0 +1,235 @@
+package toon
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// CSVOptions controls FromCSV.
+type CSVOptions struct {
+	// Name is the key the array is written under. Empty writes a root
+	// array.
+	Name string
+	// Comma is the field separator of the CSV input. Zero means ','.
+	Comma rune
+	// Strings keeps every cell a string instead of inferring column types.
+	Strings bool
+	// Delimiter separates the cells of the TOON array: encoder.Comma,
+	// encoder.Tab or encoder.Pipe. Zero means a comma.
+	Delimiter byte
+}
+
+// FromCSV reads CSV whose first record names the columns and returns a
+// TOON document holding the records as one tabular array.
+//
+// Unless opts.Strings is set, each column gets the narrowest type that
+// fits all of its non-empty cells: integers, then numbers, then booleans
+// (true or false in any case), then strings. Numbers with leading zeros,
+// such as postal codes, and integers too large for an int64 stay strings.
+// Empty cells are null in typed columns and empty strings in string
+// columns.
+func FromCSV(r io.Reader, opts CSVOptions) ([]byte, error) {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("toon: FromCSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("toon: FromCSV: missing header record")
+	}
+	header, records := records[0], records[1:]
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("toon: FromCSV: column %d has no name", i+1)
+		case seen[name]:
+			return nil, fmt.Errorf("toon: FromCSV: duplicate column %q", name)
+		}
+		seen[name] = true
+	}
+
+	kinds := make([]csvKind, len(header))
+	if !opts.Strings {
+		for i := range header {
+			kinds[i] = inferColumn(records, i)
+		}
+	}
+
+	encOpts := encoder.DefaultOptions()
+	encOpts.ForceTabular = true
+	encOpts.Delimiter = opts.Delimiter
+	if len(records) == 0 {
+		// The encoder writes empty arrays without a header, which would
+		// lose the columns.
+		return emptyTable(opts.Name, header, &encOpts), nil
+	}
+
+	rows := make([]interface{}, len(records))
+	for r, record := range records {
+		row := make(types.Object, len(header))
+		for i, cell := range record {
+			row[i] = types.Member{Key: header[i], Value: kinds[i].value(cell)}
+		}
+		rows[r] = row
+	}
+	var v interface{} = rows
+	if opts.Name != "" {
+		v = types.Object{{Key: opts.Name, Value: rows}}
+	}
+	return encoder.Append(nil, v, &encOpts)
+}
+
+// emptyTable writes the header of a tabular array without rows.
+func emptyTable(name string, columns []string, opts *encoder.Options) []byte {
+	delim := opts.Delimiter
+	if delim == 0 {
+		delim = encoder.Comma
+	}
+	var out []byte
+	if name != "" {
+		out = encoder.AppendKey(out, name)
+	}
+	out = append(out, "[0"...)
+	if delim != encoder.Comma {
+		out = append(out, delim)
+	}
+	out = append(out, "]{"...)
+	for i, column := range columns {
+		if i > 0 {
+			out = append(out, delim)
+		}
+		out = encoder.AppendKey(out, column)
+	}
+	return append(out, "}:\n"...)
+}
+
+type csvKind int
+
+const (
+	csvString csvKind = iota
+	csvInt
+	csvFloat
+	csvBool
+)
+
+var (
+	csvIntPattern   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	csvFloatPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// inferColumn returns the narrowest kind of the non-empty cells of column.
+func inferColumn(records [][]string, column int) csvKind {
+	ints, floats, bools, cells := true, true, true, false
+	for _, record := range records {
+		cell := record[column]
+		if cell == "" {
+			continue
+		}
+		cells = true
+		isInt := csvIntPattern.MatchString(cell)
+		if isInt {
+			// Integers too large for an int64 are identifiers, not numbers.
+			_, err := strconv.ParseInt(cell, 10, 64)
+			isInt = err == nil
+			floats = floats && isInt
+		} else if floats && csvFloatPattern.MatchString(cell) {
+			// Numbers out of the range of a float64, such as 1e400, would
+			// be written as null.
+			_, err := strconv.ParseFloat(cell, 64)
+			floats = err == nil
+		} else {
+			floats = false
+		}
+		ints = ints && isInt
+		bools = bools && (strings.EqualFold(cell, "true") || strings.EqualFold(cell, "false"))
+	}
+	switch {
+	case !cells:
+		return csvString
+	case ints:
+		return csvInt
+	case floats:
+		return csvFloat
+	case bools:
+		return csvBool
+	}
+	return csvString
+}
+
+func (k csvKind) value(cell string) interface{} {
+	if k == csvString {
+		return cell
+	}
+	if cell == "" {
+		return nil
+	}
+	switch k {
+	case csvInt:
+		n, _ := strconv.ParseInt(cell, 10, 64)
+		return n
+	case csvFloat:
+		f, _ := strconv.ParseFloat(cell, 64)
+		return f
+	default:
+		return strings.EqualFold(cell, "true")
+	}
+}
+
+// ToCSV writes the tabular array at path in the TOON document data as
+// CSV, with a header record naming its columns in order. Paths are dotted,
+// as in "data.events", and the empty path selects a root array, as with
+// Rows. Nulls are written as empty cells.
+func ToCSV(w io.Writer, data []byte, path string) error {
+	rr, err := decoder.NewRowReader(bytes.NewReader(data), path)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(rr.Fields()); err != nil {
+		return err
+	}
+	record := make([]string, len(rr.Fields()))
+	for {
+		values, err := rr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			record[i] = csvCell(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return string(encoder.AppendFloat(nil, v, 64))
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,104 @@
+package toon
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+)
+
+func TestFromCSV(t *testing.T) {
+	input := `id,name,score,active,zip,joined
+1,Ada,9.5,TRUE,02139,2024-01-05
+2,"Lovelace, Ada",10,false,,
+3,,,true,94103,2024-03-01
+`
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want string
+	}{
+		{
+			name: "inferred types",
+			opts: CSVOptions{Name: "users"},
+			want: `users[3]{id,name,score,active,zip,joined}:
+  1,"Ada",9.5,true,"02139","2024-01-05"
+  2,"Lovelace, Ada",10,false,"",""
+  3,"",null,true,"94103","2024-03-01"
+`,
+		},
+		{
+			name: "strings at the root",
+			opts: CSVOptions{Strings: true, Delimiter: encoder.Pipe},
+			want: `[3|]{id|name|score|active|zip|joined}:
+  "1"|"Ada"|"9.5"|"TRUE"|"02139"|"2024-01-05"
+  "2"|"Lovelace, Ada"|"10"|"false"|""|""
+  "3"|""|""|"true"|"94103"|"2024-03-01"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromCSV(strings.NewReader(input), tt.opts)
+			if err != nil {
+				t.Fatalf("FromCSV failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFromCSVEdgeCases(t *testing.T) {
+	got, err := FromCSV(strings.NewReader("id;big\n1;99999999999999999999\n"), CSVOptions{Name: "rows", Comma: ';'})
+	if err != nil {
+		t.Fatalf("FromCSV failed: %v", err)
+	}
+	if want := "rows[1]{id,big}:\n  1,\"99999999999999999999\"\n"; string(got) != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	got, err = FromCSV(strings.NewReader("x\n1.5\n1e400\n"), CSVOptions{Name: "rows"})
+	if want := "rows[2]{x}:\n  \"1.5\"\n  \"1e400\"\n"; err != nil || string(got) != want {
+		t.Errorf("Expected a float64 overflow to keep the column as strings, got %q (%v)", got, err)
+	}
+
+	got, err = FromCSV(strings.NewReader("id,full name\n"), CSVOptions{Name: "rows"})
+	if err != nil || string(got) != "rows[0]{id,\"full name\"}:\n" {
+		t.Errorf("Expected an empty table keeping its header, got %q (%v)", got, err)
+	}
+
+	for input, want := range map[string]string{
+		"":             "missing header record",
+		"a,a\n1,2\n":   `duplicate column "a"`,
+		"a,\n1,2\n":    "column 2 has no name",
+		"a,b\n1,2,3\n": "wrong number of fields",
+	} {
+		if _, err := FromCSV(strings.NewReader(input), CSVOptions{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	input := "id,name,score,note\n1,Ada,9.5,\"says \"\"hi\"\"\"\n2,Linus,,\n"
+	doc, err := FromCSV(strings.NewReader(input), CSVOptions{Name: "data"})
+	if err != nil {
+		t.Fatalf("FromCSV failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := ToCSV(&out, append([]byte("meta:\n  source: \"x\"\n"), doc...), "data"); err != nil {
+		t.Fatalf("ToCSV failed: %v", err)
+	}
+	if out.String() != input {
+		t.Errorf("Expected:\n%s\nGot:\n%s", input, out.String())
+	}
+
+	if err := ToCSV(&out, doc, "missing"); err == nil {
+		t.Error("Expected an error for a missing array")
+	}
+}
@@ -0,0 +1,230 @@
+package toon
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// Describe returns a prompt fragment that tells a language model how to
+// answer with TOON that decodes into the type of v, which may also be a
+// reflect.Type. It lists every key with its type, whether it is required
+// and, for fields tagged toon:"status,enum=open|closed", the allowed values,
+// followed by an example document.
+//
+// Keys and options come from the same struct tags Unmarshal reads, and the
+// example is written by the encoder, so arrays of flat structs appear as
+// tabular arrays with their field header exactly as Marshal would write
+// them. Fields are required unless they are pointers or tagged omitempty.
+func Describe(v interface{}) (string, error) {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		if v == nil {
+			return "", fmt.Errorf("toon: Describe of nil")
+		}
+		t = reflect.TypeOf(v)
+	}
+
+	d := &describer{visiting: map[reflect.Type]bool{}}
+	d.schema.WriteString("Answer in TOON. Keys:\n")
+	sample, err := d.sample(t, 1)
+	if err != nil {
+		return "", fmt.Errorf("toon: Describe: %w", err)
+	}
+	if d.fields == 0 {
+		fmt.Fprintf(&d.schema, "  (%s value)\n", typeName(t))
+	}
+
+	example, err := encoder.Append(nil, sample, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	out.WriteString(d.schema.String())
+	out.WriteString("Example:\n```toon\n")
+	out.Write(example)
+	if len(example) > 0 && example[len(example)-1] != '\n' {
+		out.WriteByte('\n')
+	}
+	out.WriteString("```\n")
+	return out.String(), nil
+}
+
+type describer struct {
+	schema   strings.Builder
+	fields   int
+	visiting map[reflect.Type]bool
+}
+
+// sample writes the key lines of the fields of t at depth and returns an
+// example value of type t for the encoder. Arrays get two elements so that
+// the example shows the tabular layout the encoder picks for them.
+func (d *describer) sample(t reflect.Type, depth int) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return nil, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "text", nil
+	case reflect.Bool:
+		return false, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return 0, nil
+	case reflect.Float32, reflect.Float64:
+		return 0.5, nil
+	case reflect.Interface:
+		return nil, nil
+
+	case reflect.Slice, reflect.Array:
+		elem, err := d.sample(t.Elem(), depth)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{elem, elem}, nil
+
+	case reflect.Map:
+		if !isPrimitiveKind(t.Key().Kind()) {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		value, err := d.sample(t.Elem(), depth)
+		if err != nil {
+			return nil, err
+		}
+		return types.Object{{Key: "key", Value: value}}, nil
+
+	case reflect.Struct:
+		if d.visiting[t] {
+			return types.Object{}, nil
+		}
+		d.visiting[t] = true
+		defer delete(d.visiting, t)
+
+		info := types.CachedStruct(t)
+		obj := make(types.Object, 0, len(info.Fields))
+		for i := range info.Fields {
+			field := &info.Fields[i]
+			enum := fieldEnum(field)
+			d.writeField(field, enum, depth)
+
+			value, err := d.sample(field.Type, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			if len(enum) > 0 {
+				value = enumSample(field.Type, enum[0], value)
+			}
+			obj = append(obj, types.Member{Key: field.Name, Value: value})
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func (d *describer) writeField(field *types.Field, enum []string, depth int) {
+	d.fields++
+	d.schema.WriteString(strings.Repeat("  ", depth))
+	d.schema.Write(encoder.AppendKey(nil, field.Name))
+	d.schema.WriteString(": ")
+	d.schema.WriteString(typeName(field.Type))
+	if field.OmitEmpty || field.Type.Kind() == reflect.Ptr {
+		d.schema.WriteString(", optional")
+	} else {
+		d.schema.WriteString(", required")
+	}
+	if len(enum) > 0 {
+		d.schema.WriteString(", one of ")
+		d.schema.WriteString(strings.Join(enum, ", "))
+	}
+	d.schema.WriteByte('\n')
+}
+
+// fieldEnum returns the values of an enum=a|b|c tag option.
+func fieldEnum(field *types.Field) []string {
+	value, ok := field.Option("enum")
+	if !ok || value == "" {
+		return nil
+	}
+	return strings.Split(value, "|")
+}
+
+// enumSample converts the first enum value to the field's type for the
+// example, keeping the default sample when it does not parse.
+func enumSample(t reflect.Type, value string, fallback interface{}) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return value
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func isPrimitiveKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// typeName is the short type description used in key lines.
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return "value"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Struct:
+		return "object"
+	case reflect.Map:
+		return "object with " + typeName(t.Key()) + " keys and " + typeName(t.Elem()) + " values"
+	case reflect.Slice, reflect.Array:
+		elem := typeName(t.Elem())
+		switch {
+		case strings.HasPrefix(elem, "array"), strings.HasPrefix(elem, "object with"):
+			return "array of " + elem
+		default:
+			return "array of " + elem + "s"
+		}
+	default:
+		return "any value"
+	}
+}
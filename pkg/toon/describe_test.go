@@ -0,0 +1,91 @@
+package toon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type describeItem struct {
+	SKU   string  `toon:"sku"`
+	Qty   int     `toon:"qty"`
+	Price float64 `toon:"price"`
+}
+
+type describeOrder struct {
+	ID       int    `toon:"id"`
+	Status   string `toon:"status,enum=pending|shipped"`
+	Customer struct {
+		Name  string  `toon:"name"`
+		Email *string `toon:"email"`
+	} `toon:"customer"`
+	Items []describeItem `toon:"items"`
+	Tags  []string       `toon:"tags,omitempty"`
+}
+
+func TestDescribe(t *testing.T) {
+	got, err := Describe(describeOrder{})
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+
+	want := "Answer in TOON. Keys:\n" +
+		"  id: integer, required\n" +
+		"  status: string, required, one of pending, shipped\n" +
+		"  customer: object, required\n" +
+		"    name: string, required\n" +
+		"    email: string, optional\n" +
+		"  items: array of objects, required\n" +
+		"    sku: string, required\n" +
+		"    qty: integer, required\n" +
+		"    price: number, required\n" +
+		"  tags: array of strings, optional\n" +
+		"Example:\n" +
+		"```toon\n" +
+		"id: 0\n" +
+		"status: \"pending\"\n" +
+		"customer:\n" +
+		"  name: \"text\"\n" +
+		"  email: \"text\"\n" +
+		"items[2]{sku,qty,price}:\n" +
+		"  \"text\",0,0.5\n" +
+		"  \"text\",0,0.5\n" +
+		"tags[2]: \"text\",\"text\"\n" +
+		"```\n"
+	if got != want {
+		t.Errorf("Describe() mismatch\n got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The example must decode into the described type.
+	blocks := ExtractBlocks(got)
+	if len(blocks) != 1 {
+		t.Fatalf("expected one example block, got %+v", blocks)
+	}
+	var order describeOrder
+	if err := Unmarshal([]byte(got[blocks[0].Start:blocks[0].End]), &order); err != nil {
+		t.Fatalf("Unmarshal(example) error = %v", err)
+	}
+	if order.Status != "pending" || len(order.Items) != 2 {
+		t.Errorf("unexpected example value %+v", order)
+	}
+
+	// A reflect.Type describes the same shape.
+	byType, err := Describe(reflect.TypeFor[describeOrder]())
+	if err != nil || byType != got {
+		t.Errorf("Describe(reflect.Type) = %q, %v", byType, err)
+	}
+}
+
+func TestDescribeErrors(t *testing.T) {
+	if _, err := Describe(nil); err == nil {
+		t.Error("expected an error for nil")
+	}
+	_, err := Describe(struct {
+		Items []struct {
+			Done chan bool
+		}
+	}{})
+	if err == nil || !strings.Contains(err.Error(), "field Items: field Done: unsupported type chan bool") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package toon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// ChangeKind says how a value differs between two documents.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// Change is one difference found by Diff. Path locates the value in the
+// syntax of ParsePath, except that rows of arrays matched by
+// DiffOptions.Key are written as users[id=3]. Old is nil for added values
+// and New for removed ones.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// String formats the change on one line, with values as compact JSON.
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, compactJSON(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, compactJSON(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, compactJSON(c.Old), compactJSON(c.New))
+	}
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// DiffOptions controls DiffWithOptions.
+type DiffOptions struct {
+	// Key names the field identifying the elements of arrays of objects,
+	// such as "id", so that rows are matched whatever their order. It
+	// applies to the arrays whose elements all have the field with
+	// distinct primitive values; other arrays, and all arrays when Key is
+	// empty, are compared by position after aligning their equal elements.
+	Key string
+}
+
+// Diff decodes two TOON documents and returns how b differs from a: the
+// fields added, removed and changed, and the elements added to or removed
+// from arrays. Arrays are compared element by element after aligning the
+// elements they have in common, so inserting a row reports one added row
+// rather than a change to every row after it. Removed and changed
+// elements are numbered as in a, added elements as in b. Changes are
+// listed in document order; objects compare their fields in sorted order.
+func Diff(a, b []byte) ([]Change, error) {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffWithOptions is like Diff but compares with opts, for example to
+// match rows by their id.
+func DiffWithOptions(a, b []byte, opts DiffOptions) ([]Change, error) {
+	va, err := decoder.NewParser(bytes.NewReader(a)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	vb, err := decoder.NewParser(bytes.NewReader(b)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return DiffValues(va, vb, opts), nil
+}
+
+// DiffValues compares two decoded values as Diff does. Objects may be
+// maps or types.Object values, whose fields are compared in their order.
+func DiffValues(a, b interface{}, opts DiffOptions) []Change {
+	d := &differ{opts: opts}
+	d.value("", a, b)
+	return d.changes
+}
+
+type differ struct {
+	opts    DiffOptions
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path string, old, new interface{}) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Old: old, New: new})
+}
+
+func (d *differ) value(path string, a, b interface{}) {
+	oa, aObject := diffFields(a)
+	ob, bObject := diffFields(b)
+	if aObject && bObject {
+		d.object(path, oa, ob)
+		return
+	}
+	la, aArray := a.([]interface{})
+	lb, bArray := b.([]interface{})
+	if aArray && bArray {
+		if ka, kb, ok := d.keys(la, lb); ok {
+			d.keyed(path, la, lb, ka, kb)
+		} else {
+			d.array(path, la, lb)
+		}
+		return
+	}
+	if !equalValues(a, b) {
+		d.add(Changed, path, a, b)
+	}
+}
+
+func (d *differ) object(path string, a, b types.Object) {
+	for _, m := range a {
+		if value, ok := b.Get(m.Key); ok {
+			d.value(fieldPath(path, m.Key), m.Value, value)
+		} else {
+			d.add(Removed, fieldPath(path, m.Key), m.Value, nil)
+		}
+	}
+	for _, m := range b {
+		if _, ok := a.Get(m.Key); !ok {
+			d.add(Added, fieldPath(path, m.Key), nil, m.Value)
+		}
+	}
+}
+
+// array compares a and b by position, after aligning the longest common
+// subsequence of equal elements. The elements between two aligned ones are
+// compared in pairs, and the rest are added or removed.
+func (d *differ) array(path string, a, b []interface{}) {
+	i, j := 0, 0
+	for _, m := range commonElements(a, b) {
+		d.unaligned(path, a, b, i, m[0], j, m[1])
+		i, j = m[0]+1, m[1]+1
+	}
+	d.unaligned(path, a, b, i, len(a), j, len(b))
+}
+
+func (d *differ) unaligned(path string, a, b []interface{}, i, iEnd, j, jEnd int) {
+	for ; i < iEnd && j < jEnd; i, j = i+1, j+1 {
+		d.value(indexPath(path, i), a[i], b[j])
+	}
+	for ; i < iEnd; i++ {
+		d.add(Removed, indexPath(path, i), a[i], nil)
+	}
+	for ; j < jEnd; j++ {
+		d.add(Added, indexPath(path, j), nil, b[j])
+	}
+}
+
+// commonElements returns the index pairs of a longest common subsequence
+// of equal elements of a and b.
+func commonElements(a, b []interface{}) [][2]int {
+	// Equal leading and trailing elements are aligned without the table.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && equalValues(a[prefix], b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && equalValues(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	var pairs [][2]int
+	for k := 0; k < prefix; k++ {
+		pairs = append(pairs, [2]int{k, k})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	lengths := make([][]int, len(ma)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if equalValues(ma[i], mb[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(ma) && j < len(mb); {
+		switch {
+		case equalValues(ma[i], mb[j]):
+			pairs = append(pairs, [2]int{prefix + i, prefix + j})
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	for k := suffix; k > 0; k-- {
+		pairs = append(pairs, [2]int{len(a) - k, len(b) - k})
+	}
+	return pairs
+}
+
+// keys returns the values of the Key field of every element of a and b,
+// as the text identifying them, when both arrays can be matched by it.
+func (d *differ) keys(a, b []interface{}) (ka, kb []string, ok bool) {
+	if d.opts.Key == "" || len(a) == 0 && len(b) == 0 {
+		return nil, nil, false
+	}
+	if ka, ok = d.keyValues(a); !ok {
+		return nil, nil, false
+	}
+	if kb, ok = d.keyValues(b); !ok {
+		return nil, nil, false
+	}
+	return ka, kb, true
+}
+
+func (d *differ) keyValues(items []interface{}) ([]string, bool) {
+	keys := make([]string, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		fields, ok := diffFields(item)
+		if !ok {
+			return nil, false
+		}
+		value, ok := fields.Get(d.opts.Key)
+		if !ok {
+			return nil, false
+		}
+		switch value.(type) {
+		case map[string]interface{}, types.Object, []interface{}:
+			return nil, false
+		}
+		keys[i] = compactJSON(value)
+		if seen[keys[i]] {
+			return nil, false
+		}
+		seen[keys[i]] = true
+	}
+	return keys, true
+}
+
+// keyed compares the elements of a and b with the same key, then lists the
+// elements only in b.
+func (d *differ) keyed(path string, a, b []interface{}, ka, kb []string) {
+	index := make(map[string]int, len(kb))
+	for j, key := range kb {
+		index[key] = j
+	}
+	inA := make(map[string]bool, len(ka))
+	for i, key := range ka {
+		inA[key] = true
+		rowPath := path + "[" + d.opts.Key + "=" + key + "]"
+		if j, ok := index[key]; ok {
+			d.value(rowPath, a[i], b[j])
+		} else {
+			d.add(Removed, rowPath, a[i], nil)
+		}
+	}
+	for j, key := range kb {
+		if !inA[key] {
+			d.add(Added, path+"["+d.opts.Key+"="+key+"]", nil, b[j])
+		}
+	}
+}
+
+// diffFields returns the fields of an object, those of maps in sorted
+// order.
+func diffFields(v interface{}) (types.Object, bool) {
+	switch v := v.(type) {
+	case types.Object:
+		return v, true
+	case map[string]interface{}:
+		obj := make(types.Object, 0, len(v))
+		for key, value := range v {
+			obj = append(obj, types.Member{Key: key, Value: value})
+		}
+		sort.Slice(obj, func(i, j int) bool { return obj[i].Key < obj[j].Key })
+		return obj, true
+	}
+	return nil, false
+}
+
+// equalValues reports whether two decoded values are equal, comparing
+// numbers by value and objects whatever the order of their fields.
+func equalValues(a, b interface{}) bool {
+	if na, ok := a.(json.Number); ok {
+		if nb, ok := b.(json.Number); ok {
+			return equalNumbers(na, nb)
+		}
+	}
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	oa, aObject := diffFields(a)
+	ob, bObject := diffFields(b)
+	if aObject || bObject {
+		if !aObject || !bObject || len(oa) != len(ob) {
+			return false
+		}
+		for _, m := range oa {
+			value, ok := ob.Get(m.Key)
+			if !ok || !equalValues(m.Value, value) {
+				return false
+			}
+		}
+		return true
+	}
+	la, aArray := a.([]interface{})
+	lb, bArray := b.([]interface{})
+	if aArray || bArray {
+		if !aArray || !bArray || len(la) != len(lb) {
+			return false
+		}
+		for i := range la {
+			if !equalValues(la[i], lb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// equalNumbers compares numbers read from JSON with more precision than a
+// float64 has, so that long integers differing in their last digits are
+// told apart.
+func equalNumbers(a, b json.Number) bool {
+	fa, _, errA := big.ParseFloat(string(a), 10, 256, big.ToNearestEven)
+	fb, _, errB := big.ParseFloat(string(b), 10, 256, big.ToNearestEven)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return fa.Cmp(fb) == 0
+}
+
+// fieldPath appends a field to path, in brackets when ParsePath would not
+// read the name back as a bare name.
+func fieldPath(path, key string) string {
+	bare := key != "" && !strings.ContainsAny(key[:1], "$*@")
+	for i := 0; bare && i < len(key); i++ {
+		bare = isNameByte(key[i])
+	}
+	switch {
+	case !bare:
+		return path + "[" + strconv.Quote(key) + "]"
+	case path == "":
+		return key
+	default:
+		return path + "." + key
+	}
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
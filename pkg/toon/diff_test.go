@@ -0,0 +1,112 @@
+package toon
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+const diffOld = `name: "team"
+users[3]{id,name,email}:
+  1,"Ada","ada@example.com"
+  2,"Linus","linus@example.com"
+  3,"Grace","grace@example.com"
+tags[2]: "a","b"
+`
+
+const diffNew = `name: "team"
+users[3]{id,name,email}:
+  3,"Grace","grace@example.org"
+  1,"Ada","ada@example.com"
+  4,"Alan","alan@example.com"
+tags[3]: "a","x","b"
+owner:
+  "full name": "Margaret"
+`
+
+func TestDiffKeyed(t *testing.T) {
+	changes, err := DiffWithOptions([]byte(diffOld), []byte(diffNew), DiffOptions{Key: "id"})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	want := []Change{
+		{Kind: Added, Path: "tags[1]", New: "x"},
+		{Kind: Removed, Path: "users[id=2]", Old: map[string]interface{}{"id": int64(2), "name": "Linus", "email": "linus@example.com"}},
+		{Kind: Changed, Path: "users[id=3].email", Old: "grace@example.com", New: "grace@example.org"},
+		{Kind: Added, Path: "users[id=4]", New: map[string]interface{}{"id": int64(4), "name": "Alan", "email": "alan@example.com"}},
+		{Kind: Added, Path: "owner", New: map[string]interface{}{"full name": "Margaret"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected:\n%v\nGot:\n%v", want, changes)
+	}
+}
+
+func TestDiffPositional(t *testing.T) {
+	changes, err := Diff([]byte("items[4]: 1,2,3,4\nid: 7\n"), []byte("items[4]: 0,1,3,5\nid: 7.0\n"))
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	want := []string{
+		"+ items[0]: 0",
+		"- items[1]: 2",
+		"~ items[3]: 4 -> 5",
+	}
+	got := make([]string, len(changes))
+	for i, c := range changes {
+		got[i] = c.String()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if changes, _ := Diff([]byte(diffOld), []byte(diffOld)); len(changes) != 0 {
+		t.Errorf("Expected no changes between equal documents, got %v", changes)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	a := types.Object{{Key: "a.b", Value: int64(1)}, {Key: "rows", Value: []interface{}{
+		types.Object{{Key: "id", Value: "x"}, {Key: "n", Value: int64(1)}},
+	}}}
+	b := map[string]interface{}{"a.b": int64(2), "rows": []interface{}{
+		map[string]interface{}{"n": int64(2), "id": "x"},
+	}}
+
+	changes := DiffValues(a, b, DiffOptions{Key: "id"})
+	want := []Change{
+		{Kind: Changed, Path: `["a.b"]`, Old: int64(1), New: int64(2)},
+		{Kind: Changed, Path: `rows[id="x"].n`, Old: int64(1), New: int64(2)},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected %v, got %v", want, changes)
+	}
+
+	// Paths of changes select the old values.
+	p, err := ParsePath(changes[0].Path)
+	if err != nil || !reflect.DeepEqual(p.Select(a), []interface{}{int64(1)}) {
+		t.Errorf("Expected %s to select the old value, got %v (%v)", changes[0].Path, p.Select(a), err)
+	}
+}
+
+func TestDiffJSONNumbers(t *testing.T) {
+	a, err := DecodeJSON([]byte(`{"id": 12345678901234567890, "price": 1.50, "qty": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeJSON([]byte(`{"id": 12345678901234567891, "price": 1.5, "qty": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := DiffValues(a, b, DiffOptions{})
+	want := []Change{{Kind: Changed, Path: "id", Old: json.Number("12345678901234567890"), New: json.Number("12345678901234567891")}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected %v, got %v", want, changes)
+	}
+
+	if changes := DiffValues(a, map[string]interface{}{"id": json.Number("12345678901234567890"), "price": 1.5, "qty": int64(2)}, DiffOptions{}); len(changes) != 0 {
+		t.Errorf("Expected numbers to compare by value, got %v", changes)
+	}
+}
@@ -0,0 +1,17 @@
+package toon
+
+import (
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+// Block is a TOON document found inside a larger text.
+type Block = decoder.Block
+
+// ExtractBlocks finds the TOON documents in a language model response or
+// Markdown text: code fences tagged toon, untagged code fences whose content
+// parses as a TOON object or array, and a bare document at the end of the
+// text. Each block carries its byte range and decoded value; use
+// UnmarshalValue to convert the value into a Go type.
+func ExtractBlocks(text string) []Block {
+	return decoder.ExtractBlocks(text)
+}
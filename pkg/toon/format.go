@@ -0,0 +1,20 @@
+package toon
+
+import (
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+// Format rewrites a TOON document in canonical form, keeping its comments:
+// two-space indentation, keys quoted only when needed, strings always
+// quoted, numbers written as Marshal writes them and array counts
+// recomputed from the items present. Invalid documents are reported as a
+// *SyntaxError.
+func Format(data []byte) ([]byte, error) {
+	return decoder.Format(data, decoder.FormatOptions{})
+}
+
+// FormatWithOptions is like Format but formats with opts, for example to
+// sort keys.
+func FormatWithOptions(data []byte, opts decoder.FormatOptions) ([]byte, error) {
+	return decoder.Format(data, opts)
+}
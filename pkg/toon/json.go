@@ -0,0 +1,138 @@
+package toon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// FromJSON converts a JSON document to TOON without decoding it into Go
+// maps: objects keep the order of their keys and numbers the text they
+// were written with, so 1.50 stays 1.50 and large integers keep every
+// digit. Arrays of objects with the same primitive fields become tabular
+// arrays, as with Marshal. Syntax errors wrap a *SyntaxError.
+func FromJSON(data []byte) ([]byte, error) {
+	return FromJSONWithOptions(data, nil)
+}
+
+// FromJSONWithOptions is like FromJSON but encodes with opts.
+func FromJSONWithOptions(data []byte, opts *encoder.Options) ([]byte, error) {
+	v, err := DecodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("toon: FromJSON: %w", err)
+	}
+	return encoder.Append(nil, v, opts)
+}
+
+// ToJSON converts a TOON document to compact JSON, keeping the order of
+// keys and the text of numbers. See decoder.ToJSON.
+func ToJSON(data []byte) ([]byte, error) {
+	return decoder.ToJSON(data)
+}
+
+// ToJSONWithOptions is like ToJSON but expands folded keys and drops empty
+// tabular cells as opts asks. See decoder.ToJSONWithOptions.
+func ToJSONWithOptions(data []byte, opts decoder.Options) ([]byte, error) {
+	return decoder.ToJSONWithOptions(data, opts)
+}
+
+// DecodeJSON decodes a JSON document into the values FromJSON encodes:
+// objects become types.Object values, which keep the order of their keys,
+// and numbers json.Number values, which keep their text. Syntax errors are
+// reported as a *SyntaxError at the offending byte.
+func DecodeJSON(data []byte) (interface{}, error) {
+	v, offset, err := decodeJSON(data)
+	if err != nil {
+		line, column := position(data, offset)
+		return nil, &SyntaxError{Line: line, Column: column, Msg: err.Error()}
+	}
+	return v, nil
+}
+
+// decodeJSON decodes one JSON value token by token. On error it also
+// returns the offset of the offending byte.
+func decodeJSON(data []byte) (interface{}, int64, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	offset := dec.InputOffset()
+	if err == nil {
+		if _, err = dec.Token(); err == io.EOF {
+			return v, 0, nil
+		}
+		if err == nil {
+			err = errors.New("invalid character after top-level value")
+			offset += int64(len(data[offset:]) - len(bytes.TrimLeft(data[offset:], " \t\r\n")))
+		}
+	}
+	var serr *json.SyntaxError
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		err = errors.New("unexpected end of JSON input")
+		offset = int64(len(data))
+	case errors.As(err, &serr) && serr.Offset >= int64(len(data)) && strings.Contains(serr.Error(), "end of JSON input"):
+		offset = int64(len(data))
+	case errors.As(err, &serr) && serr.Offset > 0:
+		// The offending byte is the last one read.
+		offset = serr.Offset - 1
+	}
+	return nil, offset, err
+}
+
+// position returns the 1-based line and column of the byte at offset.
+func position(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	if delim == '[' {
+		items := []interface{}{}
+		for dec.More() {
+			item, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+
+	obj := types.Object{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		// Later duplicates win, as with encoding/json.
+		obj.Set(key.(string), value)
+	}
+	_, err = dec.Token()
+	return obj, err
+}
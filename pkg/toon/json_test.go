@@ -0,0 +1,53 @@
+package toon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromJSON(t *testing.T) {
+	input := `{
+  "name": "catalog",
+  "total": 12345678901234567890,
+  "items": [
+    {"sku": "b-2", "price": 1.50, "qty": 1e2},
+    {"sku": "a-1", "price": 10, "qty": -3}
+  ],
+  "meta": {"z": [1, 2.0], "a": {}}
+}`
+	want := `name: "catalog"
+total: 12345678901234567890
+items[2]{sku,price,qty}:
+  "b-2",1.50,1e2
+  "a-1",10,-3
+meta:
+  z[2]: 1,2.0
+  a:
+`
+	got, err := FromJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+
+	back, err := ToJSON(got)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	compact := `{"name":"catalog","total":12345678901234567890,"items":[{"sku":"b-2","price":1.50,"qty":1e2},{"sku":"a-1","price":10,"qty":-3}],"meta":{"z":[1,2.0],"a":{}}}`
+	if string(back) != compact {
+		t.Errorf("Expected the round trip to give:\n%s\nGot:\n%s", compact, back)
+	}
+
+	for input, want := range map[string]string{
+		`{"a": 1`:        "line 1, column 8: unexpected end of JSON input",
+		"{\"a\": 1}\n{}": "line 2, column 1: invalid character after top-level value",
+		`{"a" 1}`:        "line 1, column 6: invalid character '1'",
+	} {
+		if _, err := FromJSON([]byte(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", input, want, err)
+		}
+	}
+}
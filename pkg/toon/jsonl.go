@@ -0,0 +1,308 @@
+package toon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// JSONLOptions controls FromJSONL.
+type JSONLOptions struct {
+	// Name is the key the array is written under. Empty writes a root
+	// array.
+	Name string
+	// Delimiter separates the cells of a tabular array and the values of
+	// inline arrays: encoder.Comma, encoder.Tab or encoder.Pipe. Zero means
+	// a comma.
+	Delimiter byte
+	// TabularThreshold writes records with differing keys as one table
+	// over the union of their keys, with null in the missing cells, when
+	// at least this fraction of the cells hold a value, as with
+	// encoder.Options.TabularThreshold. Zero requires every record to have
+	// the same keys.
+	TabularThreshold float64
+}
+
+// FromJSONL reads a JSON Lines stream, one JSON value per line, and writes
+// the values to w as a single TOON array. Blank lines are skipped.
+//
+// When every record is an object of primitives with the same keys, the
+// array is tabular, with the columns in the order the keys first appear;
+// otherwise it is a list. Since the header declares the row count and the
+// columns, the input is read twice: a reader that can seek, such as a file,
+// is rewound, and any other reader is spooled to a temporary file, so
+// memory use does not grow with the number of records.
+func FromJSONL(w io.Writer, r io.Reader, opts JSONLOptions) error {
+	src, cleanup, err := rewindable(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var scan jsonlScan
+	if err := readJSONL(src.first, scan.add); err != nil {
+		return err
+	}
+	second, err := src.second()
+	if err != nil {
+		return err
+	}
+
+	encOpts := encoder.DefaultOptions()
+	encOpts.Delimiter = opts.Delimiter
+	bw := bufio.NewWriter(w)
+	if scan.tabular(opts.TabularThreshold) {
+		err = scan.writeTable(bw, second, opts.Name, &encOpts)
+	} else {
+		err = scan.writeList(bw, second, opts.Name, &encOpts)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// jsonlSource reads the input of FromJSONL twice.
+type jsonlSource struct {
+	first  io.Reader
+	second func() (io.Reader, error)
+}
+
+// rewindable returns a source rereading r by seeking back when r supports
+// it, and otherwise by copying what the first pass reads to a temporary
+// file.
+func rewindable(r io.Reader) (jsonlSource, func(), error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		// Pipes and terminals implement Seek but fail when called.
+		if start, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			return jsonlSource{
+				first: rs,
+				second: func() (io.Reader, error) {
+					_, err := rs.Seek(start, io.SeekStart)
+					return rs, err
+				},
+			}, func() {}, nil
+		}
+	}
+
+	spool, err := os.CreateTemp("", "toon-jsonl-*")
+	if err != nil {
+		return jsonlSource{}, nil, err
+	}
+	cleanup := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	buf := bufio.NewWriter(spool)
+	return jsonlSource{
+		first: io.TeeReader(r, buf),
+		second: func() (io.Reader, error) {
+			if err := buf.Flush(); err != nil {
+				return nil, err
+			}
+			_, err := spool.Seek(0, io.SeekStart)
+			return spool, err
+		},
+	}, cleanup, nil
+}
+
+// jsonlScan holds what the first pass of FromJSONL learns about the
+// records.
+type jsonlScan struct {
+	count   int
+	columns []string
+	seen    map[string]bool
+	cells   int
+	// flat is false once a record is not an object of primitives.
+	flat bool
+}
+
+// readJSONL calls fn with each record of r and its line number.
+func readJSONL(r io.Reader, fn func(line int, v interface{}) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if record := bytes.TrimSpace(text); len(record) > 0 {
+			v, _, jerr := decodeJSON(record)
+			if jerr != nil {
+				return fmt.Errorf("toon: FromJSONL: line %d: %v", line, jerr)
+			}
+			if ferr := fn(line, v); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func (s *jsonlScan) add(line int, v interface{}) error {
+	if s.count == 0 {
+		s.flat = true
+		s.seen = map[string]bool{}
+	}
+	s.count++
+	obj, ok := v.(types.Object)
+	if !ok {
+		s.flat = false
+		return nil
+	}
+	for _, m := range obj {
+		if !types.IsPrimitiveType(m.Value) {
+			s.flat = false
+		}
+		if !s.seen[m.Key] {
+			s.seen[m.Key] = true
+			s.columns = append(s.columns, m.Key)
+		}
+	}
+	s.cells += len(obj)
+	return nil
+}
+
+// tabular reports whether the records can be written as one table.
+func (s *jsonlScan) tabular(threshold float64) bool {
+	if !s.flat || len(s.columns) == 0 {
+		return false
+	}
+	total := s.count * len(s.columns)
+	if threshold == 0 {
+		return s.cells == total
+	}
+	return float64(s.cells) >= threshold*float64(total)
+}
+
+func (s *jsonlScan) writeTable(w io.Writer, r io.Reader, name string, opts *encoder.Options) error {
+	tw, err := encoder.NewTableWriter(w, name, s.columns, s.count, opts)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int, len(s.columns))
+	for i, column := range s.columns {
+		index[column] = i
+	}
+	row := make([]interface{}, len(s.columns))
+	err = readJSONL(r, func(line int, v interface{}) error {
+		obj, _ := v.(types.Object)
+		clear(row)
+		for _, m := range obj {
+			i, ok := index[m.Key]
+			if !ok {
+				return fmt.Errorf("toon: FromJSONL: line %d: input changed between passes", line)
+			}
+			row[i] = m.Value
+		}
+		if err := tw.WriteRow(row); err != nil {
+			return fmt.Errorf("toon: FromJSONL: line %d: %v", line, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func (s *jsonlScan) writeList(w io.Writer, r io.Reader, name string, opts *encoder.Options) error {
+	var line []byte
+	if name != "" {
+		line = encoder.AppendKey(line, name)
+	}
+	line = append(line, '[')
+	line = strconv.AppendInt(line, int64(s.count), 10)
+	if opts.Delimiter != 0 && opts.Delimiter != encoder.Comma {
+		line = append(line, opts.Delimiter)
+	}
+	line = append(line, "]:\n"...)
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+
+	written := 0
+	err := readJSONL(r, func(n int, v interface{}) error {
+		var err error
+		if line, err = encoder.AppendListItem(line[:0], v, 1, opts); err != nil {
+			return fmt.Errorf("toon: FromJSONL: line %d: %v", n, err)
+		}
+		written++
+		_, err = w.Write(line)
+		return err
+	})
+	if err == nil && written != s.count {
+		err = fmt.Errorf("toon: FromJSONL: input changed between passes: read %d records, then %d", s.count, written)
+	}
+	return err
+}
+
+// ToJSONL writes the elements of the array at path in the TOON document
+// data to w as JSON Lines, one compact JSON value per line. Paths are
+// dotted, as in "data.events", and the empty path selects a root array.
+//
+// Tabular arrays are streamed row by row and keep the order of their
+// columns. Other arrays are decoded whole, and the keys of their objects
+// are written in sorted order.
+func ToJSONL(w io.Writer, data []byte, path string) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	if rr, err := decoder.NewRowReader(bytes.NewReader(data), path); err == nil {
+		fields := rr.Fields()
+		for {
+			values, err := rr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			row := make(types.Object, len(fields))
+			for i, field := range fields {
+				row[i] = types.Member{Key: field, Value: values[i]}
+			}
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	}
+
+	v, err := decoder.NewParser(bytes.NewReader(data)).Parse()
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("toon: ToJSONL: no array at %q", path)
+			}
+			if v, ok = obj[key]; !ok {
+				return fmt.Errorf("toon: ToJSONL: no array at %q", path)
+			}
+		}
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("toon: ToJSONL: no array at %q", path)
+	}
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
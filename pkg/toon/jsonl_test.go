@@ -0,0 +1,141 @@
+package toon
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/encoder"
+)
+
+func TestFromJSONL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  JSONLOptions
+		want  string
+	}{
+		{
+			name:  "uniform records",
+			input: "{\"id\": 1, \"msg\": \"start\", \"ok\": true}\n\n{\"msg\": \"a, b\", \"id\": 2, \"ok\": null}\n",
+			opts:  JSONLOptions{Name: "events"},
+			want:  "events[2]{id,msg,ok}:\n  1,\"start\",true\n  2,\"a, b\",null\n",
+		},
+		{
+			name:  "quoted columns at the root",
+			input: "{\"full name\": \"Ada\", \"n\": 1.5}\r\n{\"full name\": \"Linus\", \"n\": 2}",
+			opts:  JSONLOptions{Delimiter: encoder.Pipe},
+			want:  "[2|]{\"full name\"|n}:\n  \"Ada\"|1.5\n  \"Linus\"|2\n",
+		},
+		{
+			name:  "sparse records",
+			input: "{\"id\": 1, \"tag\": \"x\"}\n{\"id\": 2}\n{\"id\": 3, \"tag\": \"y\"}\n",
+			opts:  JSONLOptions{Name: "rows", TabularThreshold: 0.5},
+			want:  "rows[3]{id,tag}:\n  1,\"x\"\n  2,null\n  3,\"y\"\n",
+		},
+		{
+			name:  "mixed records",
+			input: "{\"id\": 1, \"tags\": [\"a\", \"b\"]}\n{\"id\": 2}\n7\n",
+			opts:  JSONLOptions{Name: "rows"},
+			want:  "rows[3]:\n  - id: 1\n    tags[2]: \"a\",\"b\"\n  - id: 2\n  - 7\n",
+		},
+		{
+			name:  "empty",
+			input: "\n",
+			opts:  JSONLOptions{Name: "rows"},
+			want:  "rows[0]:\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A reader that cannot seek is spooled to disk.
+			readers := map[string]io.Reader{
+				"seeker":  strings.NewReader(tt.input),
+				"spooled": io.MultiReader(strings.NewReader(tt.input)),
+			}
+			for kind, r := range readers {
+				var out bytes.Buffer
+				if err := FromJSONL(&out, r, tt.opts); err != nil {
+					t.Fatalf("%s: FromJSONL failed: %v", kind, err)
+				}
+				if out.String() != tt.want {
+					t.Errorf("%s: expected:\n%s\nGot:\n%s", kind, tt.want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestFromJSONLFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(name, []byte("{\"id\": 1}\n{\"id\": 2}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	if err := FromJSONL(&out, f, JSONLOptions{Name: "events"}); err != nil {
+		t.Fatalf("FromJSONL failed: %v", err)
+	}
+	if want := "events[2]{id}:\n  1\n  2\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestFromJSONLErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		"{\"id\": 1}\n{\"id\": \n":  "line 2: unexpected end of JSON input",
+		"{\"id\": 1} {\"id\": 2}\n": "line 1: invalid character after top-level value",
+	} {
+		err := FromJSONL(io.Discard, strings.NewReader(input), JSONLOptions{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestToJSONL(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		path string
+		want string
+	}{
+		{
+			name: "tabular",
+			data: "meta:\n  page: 1\ndata:\n  events[2]{ts,kind}:\n    5,\"<start>\"\n    6,null\n",
+			path: "data.events",
+			want: "{\"ts\":5,\"kind\":\"<start>\"}\n{\"ts\":6,\"kind\":null}\n",
+		},
+		{
+			name: "list",
+			data: "rows[2]:\n  - id: 1\n    tags[1]: \"a\"\n  - \"x\"\n",
+			path: "rows",
+			want: "{\"id\":1,\"tags\":[\"a\"]}\n\"x\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := ToJSONL(&out, []byte(tt.data), tt.path); err != nil {
+				t.Fatalf("ToJSONL failed: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.want, out.String())
+			}
+		})
+	}
+
+	if err := ToJSONL(io.Discard, []byte("a: 1\n"), "a"); err == nil || !strings.Contains(err.Error(), `no array at "a"`) {
+		t.Errorf("Expected a missing array error, got %v", err)
+	}
+}
@@ -0,0 +1,651 @@
+package toon
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// Query decodes data and returns the values selected by the path
+// expression expr, in document order. Objects are returned as
+// types.Object values and primitives as Parser decodes them. See ParsePath
+// for the syntax.
+func Query(data []byte, expr string) ([]interface{}, error) {
+	p, err := ParsePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	// The JSON of the document keeps the order of its fields, which maps
+	// would lose.
+	out, err := ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	v, err := DecodeJSON(out)
+	if err != nil {
+		return nil, err
+	}
+	return p.Select(parsedNumbers(v)), nil
+}
+
+// parsedNumbers replaces the json.Number values in v, in place, with the
+// int64 or float64 Parser decodes from the same text.
+func parsedNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return v
+		}
+		if float64(int64(f)) == f {
+			return int64(f)
+		}
+		return f
+	case types.Object:
+		for i := range v {
+			v[i].Value = parsedNumbers(v[i].Value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = parsedNumbers(v[i])
+		}
+	}
+	return v
+}
+
+// Path is a compiled path expression.
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// ParsePath compiles a path expression selecting values inside a decoded
+// document. A path is a sequence of steps, optionally preceded by "$" for
+// the root:
+//
+//	name, .name       the field name of an object
+//	["full name"]     a field whose name needs quoting
+//	[2], [-1]         an element of an array, counting from the end when negative
+//	[*], .*           every element of an array or field of an object
+//	[1:3], [::2]      a slice of an array, as start:end:step
+//	[?expr]           the elements or fields for which expr holds
+//	..step            step applied to the value and all its descendants
+//
+// Filter expressions compare a path relative to the element, written as
+// name or @.name, with a literal using ==, !=, <, <=, > or >=, as in
+// [?age>=18] or [?role=="admin"]; "@" alone is the element itself. A path
+// without a comparison holds when the value exists and is neither false
+// nor null. Conditions combine with &&, ||, a leading ! and parentheses.
+// Literals are numbers, true, false, null and strings in double or single
+// quotes.
+//
+// The fields of maps are visited in sorted key order, and those of
+// types.Object values in their own order.
+func ParsePath(expr string) (*Path, error) {
+	p := &pathParser{expr: expr}
+	if strings.HasPrefix(expr, "$") {
+		p.pos++
+	}
+	segments, err := p.segments(false)
+	if err != nil {
+		return nil, err
+	}
+	return &Path{expr: expr, segments: segments}, nil
+}
+
+// String returns the expression the path was compiled from.
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Definite reports whether the path selects at most one value: it has no
+// wildcards, slices, filters or recursive descent.
+func (p *Path) Definite() bool {
+	for _, s := range p.segments {
+		if s.descend {
+			return false
+		}
+		switch s.sel.(type) {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the values of v selected by the path, in document order.
+// v is a decoded value: a map[string]interface{} or types.Object, a
+// []interface{} or a primitive.
+func (p *Path) Select(v interface{}) []interface{} {
+	nodes := []interface{}{v}
+	for _, s := range p.segments {
+		var next []interface{}
+		emit := func(v interface{}) { next = append(next, v) }
+		for _, n := range nodes {
+			if !s.descend {
+				s.sel.selectFrom(n, emit)
+				continue
+			}
+			descendants(n, func(d interface{}) { s.sel.selectFrom(d, emit) })
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+type segment struct {
+	// descend applies sel to every descendant as well, for "..".
+	descend bool
+	sel     selector
+}
+
+type selector interface {
+	selectFrom(v interface{}, emit func(interface{}))
+}
+
+type nameSelector string
+
+func (s nameSelector) selectFrom(v interface{}, emit func(interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if value, ok := v[string(s)]; ok {
+			emit(value)
+		}
+	case types.Object:
+		if value, ok := v.Get(string(s)); ok {
+			emit(value)
+		}
+	}
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) selectFrom(v interface{}, emit func(interface{})) {
+	children(v, emit)
+}
+
+type indexSelector int
+
+func (s indexSelector) selectFrom(v interface{}, emit func(interface{})) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+	i := int(s)
+	if i < 0 {
+		i += len(items)
+	}
+	if i >= 0 && i < len(items) {
+		emit(items[i])
+	}
+}
+
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) selectFrom(v interface{}, emit func(interface{})) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+	bound := func(i *int, def int) int {
+		if i == nil {
+			return def
+		}
+		n := *i
+		if n < 0 {
+			n += len(items)
+		}
+		return min(max(n, 0), len(items))
+	}
+	for i := bound(s.start, 0); i < bound(s.end, len(items)); i += s.step {
+		emit(items[i])
+	}
+}
+
+type filterSelector struct {
+	cond condition
+}
+
+func (s filterSelector) selectFrom(v interface{}, emit func(interface{})) {
+	children(v, func(child interface{}) {
+		if s.cond.holds(child) {
+			emit(child)
+		}
+	})
+}
+
+// children calls emit with the elements of an array or the fields of an
+// object.
+func children(v interface{}, emit func(interface{})) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			emit(item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			emit(v[key])
+		}
+	case types.Object:
+		for _, m := range v {
+			emit(m.Value)
+		}
+	}
+}
+
+// descendants calls emit with v and every value nested in it, parents
+// before their children.
+func descendants(v interface{}, emit func(interface{})) {
+	emit(v)
+	children(v, func(child interface{}) { descendants(child, emit) })
+}
+
+type condition interface {
+	holds(v interface{}) bool
+}
+
+type andCondition struct{ left, right condition }
+
+func (c andCondition) holds(v interface{}) bool { return c.left.holds(v) && c.right.holds(v) }
+
+type orCondition struct{ left, right condition }
+
+func (c orCondition) holds(v interface{}) bool { return c.left.holds(v) || c.right.holds(v) }
+
+type notCondition struct{ cond condition }
+
+func (c notCondition) holds(v interface{}) bool { return !c.cond.holds(v) }
+
+// comparison compares the first value selected by path with value, or
+// tests that it exists when op is empty.
+type comparison struct {
+	path  *Path
+	op    string
+	value interface{}
+}
+
+func (c comparison) holds(v interface{}) bool {
+	selected := c.path.Select(v)
+	if len(selected) == 0 {
+		return false
+	}
+	got := selected[0]
+	if c.op == "" {
+		return got != nil && got != false
+	}
+
+	if a, ok := toFloat(got); ok {
+		if b, ok := toFloat(c.value); ok {
+			return compareOrdered(a, b, c.op)
+		}
+	}
+	if a, ok := got.(string); ok {
+		if b, ok := c.value.(string); ok {
+			return compareOrdered(a, b, c.op)
+		}
+	}
+	switch c.op {
+	case "==":
+		return got == c.value
+	case "!=":
+		return got != c.value
+	}
+	return false
+}
+
+func compareOrdered[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+type pathParser struct {
+	expr string
+	pos  int
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toon: path %q: offset %d: %s", p.expr, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *pathParser) more() bool {
+	return p.pos < len(p.expr)
+}
+
+func (p *pathParser) peek() byte {
+	if !p.more() {
+		return 0
+	}
+	return p.expr[p.pos]
+}
+
+func (p *pathParser) skipSpace() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+// segments reads steps up to the end of the expression or, in a filter,
+// up to the first character that cannot continue a path.
+func (p *pathParser) segments(inFilter bool) ([]segment, error) {
+	var segments []segment
+	for p.more() {
+		var s segment
+		var err error
+		switch c := p.peek(); {
+		case strings.HasPrefix(p.expr[p.pos:], ".."):
+			p.pos += 2
+			s.descend = true
+			if p.peek() == '[' {
+				s.sel, err = p.bracket()
+			} else {
+				s.sel, err = p.dotted()
+			}
+		case c == '.':
+			p.pos++
+			s.sel, err = p.dotted()
+		case c == '[':
+			s.sel, err = p.bracket()
+		case len(segments) == 0 && isNameByte(c):
+			s.sel, err = p.dotted()
+		case inFilter:
+			return segments, nil
+		default:
+			return nil, p.errorf("unexpected %q", string(c))
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, nil
+}
+
+// isNameByte reports whether c may appear in an unquoted field name.
+func isNameByte(c byte) bool {
+	return !strings.ContainsRune(".[]()=!<>&|'\" ", rune(c))
+}
+
+// dotted reads a name or "*" after a dot.
+func (p *pathParser) dotted() (selector, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return wildcardSelector{}, nil
+	}
+	start := p.pos
+	for p.more() && isNameByte(p.peek()) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a field name")
+	}
+	return nameSelector(p.expr[start:p.pos]), nil
+}
+
+// bracket reads a step in brackets.
+func (p *pathParser) bracket() (selector, error) {
+	p.pos++
+	var sel selector
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		sel = wildcardSelector{}
+	case c == '?':
+		p.pos++
+		cond, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		sel = filterSelector{cond}
+	case c == '"' || c == '\'':
+		name, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		sel = nameSelector(name)
+	default:
+		var err error
+		if sel, err = p.indexOrSlice(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek() != ']' {
+		return nil, p.errorf("expected \"]\"")
+	}
+	p.pos++
+	return sel, nil
+}
+
+// quoted reads a string in single or double quotes, with backslash
+// escapes.
+func (p *pathParser) quoted() (string, error) {
+	quote := p.peek()
+	var sb strings.Builder
+	for p.pos++; p.more(); p.pos++ {
+		c := p.peek()
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\\' && p.pos+1 < len(p.expr):
+			p.pos++
+			sb.WriteByte(p.peek())
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// integer reads an optionally negative integer, returning nil when there
+// is none.
+func (p *pathParser) integer() (*int, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, nil
+	}
+	text := p.expr[start:p.pos]
+	if text == "-" {
+		p.pos = start
+		return nil, p.errorf("expected digits after \"-\"")
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid index %q", text)
+	}
+	return &n, nil
+}
+
+func (p *pathParser) indexOrSlice() (selector, error) {
+	start, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ':' {
+		if start == nil {
+			return nil, p.errorf("expected an index, slice, \"*\", filter or quoted name")
+		}
+		return indexSelector(*start), nil
+	}
+
+	s := sliceSelector{start: start, step: 1}
+	p.pos++
+	if s.end, err = p.integer(); err != nil {
+		return nil, err
+	}
+	if p.peek() == ':' {
+		p.pos++
+		step, err := p.integer()
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			if *step <= 0 {
+				return nil, p.errorf("slice step must be positive")
+			}
+			s.step = *step
+		}
+	}
+	return s, nil
+}
+
+func (p *pathParser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpace(); strings.HasPrefix(p.expr[p.pos:], "||"); p.skipSpace() {
+		p.pos += 2
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *pathParser) and() (condition, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpace(); strings.HasPrefix(p.expr[p.pos:], "&&"); p.skipSpace() {
+		p.pos += 2
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left, right}
+	}
+	return left, nil
+}
+
+var comparisonOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// term reads a negation, a parenthesized condition or a comparison.
+func (p *pathParser) term() (condition, error) {
+	p.skipSpace()
+	switch p.peek() {
+	case '!':
+		p.pos++
+		cond, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{cond}, nil
+	case '(':
+		p.pos++
+		cond, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace(); p.peek() != ')' {
+			return nil, p.errorf("expected \")\"")
+		}
+		p.pos++
+		return cond, nil
+	}
+
+	start := p.pos
+	if p.peek() == '@' {
+		p.pos++
+	}
+	segments, err := p.segments(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a path in filter")
+	}
+	c := comparison{path: &Path{expr: p.expr[start:p.pos], segments: segments}}
+
+	p.skipSpace()
+	for _, op := range comparisonOps {
+		if strings.HasPrefix(p.expr[p.pos:], op) {
+			p.pos += len(op)
+			c.op = op
+			p.skipSpace()
+			if c.value, err = p.literal(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return c, nil
+}
+
+// literal reads the value a filter compares with.
+func (p *pathParser) literal() (interface{}, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.quoted()
+	}
+	start := p.pos
+	for p.more() && !strings.ContainsRune(" ])&|", rune(p.peek())) {
+		p.pos++
+	}
+	text := p.expr[start:p.pos]
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	p.pos = start
+	return nil, p.errorf("expected a literal")
+}
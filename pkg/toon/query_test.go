@@ -0,0 +1,99 @@
+package toon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+const queryDocument = `team: "core"
+users[3]{id,name,email,active,age}:
+  1,"Ada","ada@example.com",true,36
+  2,"Linus","linus@example.com",false,28
+  3,"Grace","grace@example.com",true,45
+meta:
+  owner:
+    name: "Margaret"
+  "full name": "Core team"
+`
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []interface{}
+	}{
+		{"team", []interface{}{"core"}},
+		{"$.users[0].name", []interface{}{"Ada"}},
+		{"users[-1].id", []interface{}{int64(3)}},
+		{"users[*].email", []interface{}{"ada@example.com", "linus@example.com", "grace@example.com"}},
+		{"users.*.id", []interface{}{int64(1), int64(2), int64(3)}},
+		{"users[?active==true].id", []interface{}{int64(1), int64(3)}},
+		{"users[?active].id", []interface{}{int64(1), int64(3)}},
+		{"users[?!active].name", []interface{}{"Linus"}},
+		{"users[?@.age >= 30 && name != 'Ada'].name", []interface{}{"Grace"}},
+		{`users[?name == "Linus" || (age > 40 && active)].id`, []interface{}{int64(2), int64(3)}},
+		{"users[1:].id", []interface{}{int64(2), int64(3)}},
+		{"users[:-1].id", []interface{}{int64(1), int64(2)}},
+		{"users[::2].id", []interface{}{int64(1), int64(3)}},
+		{"..name", []interface{}{"Ada", "Linus", "Grace", "Margaret"}},
+		{"..owner", []interface{}{types.Object{{Key: "name", Value: "Margaret"}}}},
+		{`meta["full name"]`, []interface{}{"Core team"}},
+		{"meta.owner.missing", nil},
+		{"users[7]", nil},
+		{"team[0]", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Query([]byte(queryDocument), tt.expr)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPathSelectObject(t *testing.T) {
+	v := types.Object{
+		{Key: "b", Value: []interface{}{"x", "y"}},
+		{Key: "a", Value: types.Object{{Key: "tags", Value: []interface{}{"y"}}}},
+	}
+	p, err := ParsePath(`..[?@=="y"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Select(v); !reflect.DeepEqual(got, []interface{}{"y", "y"}) {
+		t.Errorf("Expected both matches in document order, got %v", got)
+	}
+	if p.Definite() {
+		t.Error("Expected a filter to be indefinite")
+	}
+	if p, _ := ParsePath("a.tags[0]"); !p.Definite() {
+		t.Error("Expected names and indexes to be definite")
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	tests := map[string]string{
+		"users[":         `offset 6: expected an index, slice, "*", filter or quoted name`,
+		"users[0":        `offset 7: expected "]"`,
+		"users.":         "offset 6: expected a field name",
+		`a["b`:           "offset 4: unterminated string",
+		"a[::0]":         "offset 5: slice step must be positive",
+		"a[?b==]":        "offset 6: expected a literal",
+		"a[?(b]":         `offset 5: expected ")"`,
+		"users]":         `offset 5: unexpected "]"`,
+		"a[?b == maybe]": "offset 8: expected a literal",
+	}
+	for expr, want := range tests {
+		_, err := ParsePath(expr)
+		if err == nil || !strings.HasSuffix(err.Error(), want) {
+			t.Errorf("%s: expected an error ending in %q, got %v", expr, want, err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package toon
+
+import (
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+// Fix describes one change made by Repair.
+type Fix = decoder.Fix
+
+// Repair fixes TOON written by language models so that it decodes: it
+// removes code fences and surrounding prose, normalizes indentation,
+// corrects array counts and quotes tabular values that contain the
+// delimiter. Each change is reported as a Fix. When the repaired document
+// still does not parse it is returned together with the parse error.
+//
+// To repair and decode in one step, use UnmarshalWithOptions with
+// decoder.Options{Lenient: true}.
+func Repair(data []byte) ([]byte, []Fix, error) {
+	return decoder.Repair(data)
+}
@@ -0,0 +1,148 @@
+package toon
+
+import (
+	"io"
+	"iter"
+	"reflect"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/types"
+)
+
+// Row is a single decoded row of a tabular array.
+type Row struct {
+	Index  int
+	Fields []string
+	Values []interface{}
+}
+
+// Get returns the value of the named column, or nil if the row has no such column.
+func (r Row) Get(field string) interface{} {
+	for i, name := range r.Fields {
+		if name == field {
+			return r.Values[i]
+		}
+	}
+	return nil
+}
+
+// Map returns the row as an object keyed by column name.
+func (r Row) Map() map[string]interface{} {
+	obj := make(map[string]interface{}, len(r.Fields))
+	for i, name := range r.Fields {
+		obj[name] = r.Values[i]
+	}
+	return obj
+}
+
+// Decode stores the row in the value pointed to by v, following the same
+// rules as Unmarshal.
+func (r Row) Decode(v interface{}) error {
+	return convertToValue(r.Map(), v)
+}
+
+// Rows locates the tabular array at path name in r and returns an iterator
+// that decodes one row at a time. The header is read before Rows returns so
+// a missing or malformed array is reported immediately; row errors are
+// yielded by the iterator, which stops after the first one.
+//
+// The iterator consumes r and can only be ranged over once.
+func Rows(r io.Reader, name string) (iter.Seq2[Row, error], error) {
+	rr, err := decoder.NewRowReader(r, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Row, error) bool) {
+		for i := 0; ; i++ {
+			values, err := rr.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(Row{}, err)
+				return
+			}
+			if !yield(Row{Index: i, Fields: rr.Fields(), Values: values}, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// RowsOf is like Rows but decodes every row directly into a T. When T is a
+// struct (or pointer to struct) the column to field mapping is resolved once
+// from the header instead of building an intermediate map per row.
+func RowsOf[T any](r io.Reader, name string) (iter.Seq2[T, error], error) {
+	rr, err := decoder.NewRowReader(r, name)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := structColumns(reflect.TypeFor[T](), rr.Fields())
+
+	return func(yield func(T, error) bool) {
+		for {
+			var item T
+			values, err := rr.Next()
+			if err == io.EOF {
+				return
+			}
+			if err == nil {
+				if columns != nil {
+					err = setStructFromRow(reflect.ValueOf(&item).Elem(), columns, values)
+				} else {
+					row := Row{Fields: rr.Fields(), Values: values}
+					err = row.Decode(&item)
+				}
+			}
+			if err != nil {
+				yield(item, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// structColumns maps each header field to the struct field it decodes into,
+// or nil for unknown columns. It returns nil when t is not a struct or
+// pointer to struct.
+func structColumns(t reflect.Type, fields []string) []*types.Field {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	info := types.CachedStruct(t)
+	columns := make([]*types.Field, len(fields))
+	for i, name := range fields {
+		columns[i], _ = info.FieldByName(name)
+	}
+	return columns
+}
+
+func setStructFromRow(dst reflect.Value, columns []*types.Field, values []interface{}) error {
+	if dst.Kind() == reflect.Ptr {
+		dst.Set(reflect.New(dst.Type().Elem()))
+		dst = dst.Elem()
+	}
+
+	for i, field := range columns {
+		if field == nil || values[i] == nil {
+			continue
+		}
+		dstField := field.SettableValue(dst)
+		if !dstField.IsValid() {
+			continue
+		}
+		if err := setStructField(field, dstField, reflect.ValueOf(values[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
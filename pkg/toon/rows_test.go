@@ -0,0 +1,86 @@
+package toon
+
+import (
+	"strings"
+	"testing"
+)
+
+const eventsDoc = `source: "api"
+data:
+  events[3]{id,kind,ok}:
+    1,"login",true
+    2,"click",true
+    3,"logout",false
+  total: 3`
+
+func TestRows(t *testing.T) {
+	rows, err := Rows(strings.NewReader(eventsDoc), "data.events")
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+
+	var kinds []string
+	for row, err := range rows {
+		if err != nil {
+			t.Fatalf("row error = %v", err)
+		}
+		kinds = append(kinds, row.Get("kind").(string))
+	}
+
+	if strings.Join(kinds, ",") != "login,click,logout" {
+		t.Errorf("Rows() kinds = %v", kinds)
+	}
+}
+
+func TestRowsOfStruct(t *testing.T) {
+	type Event struct {
+		ID   int    `toon:"id"`
+		Kind string `toon:"kind"`
+		OK   bool   `toon:"ok"`
+	}
+
+	rows, err := RowsOf[Event](strings.NewReader(eventsDoc), "data.events")
+	if err != nil {
+		t.Fatalf("RowsOf() error = %v", err)
+	}
+
+	var events []Event
+	for event, err := range rows {
+		if err != nil {
+			t.Fatalf("row error = %v", err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("RowsOf() got %d rows, want 3", len(events))
+	}
+	if events[2] != (Event{ID: 3, Kind: "logout", OK: false}) {
+		t.Errorf("RowsOf() last row = %+v", events[2])
+	}
+}
+
+func TestRowsErrors(t *testing.T) {
+	if _, err := Rows(strings.NewReader(eventsDoc), "events"); err == nil {
+		t.Error("Rows() expected error for unknown path")
+	}
+
+	short := "events[3]{id}:\n  1\n  2\n"
+	rows, err := Rows(strings.NewReader(short), "events")
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+
+	var got int
+	var lastErr error
+	for _, err := range rows {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		got++
+	}
+	if got != 2 || lastErr == nil {
+		t.Errorf("Rows() got %d rows, err %v; want 2 rows and a count mismatch", got, lastErr)
+	}
+}
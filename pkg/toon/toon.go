@@ -7,9 +7,11 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/devalexandre/toon-go/pkg/decoder"
 	"github.com/devalexandre/toon-go/pkg/encoder"
+	"github.com/devalexandre/toon-go/pkg/types"
 )
 
 type Marshaler interface {
@@ -20,18 +22,35 @@ type Unmarshaler interface {
 	UnmarshalTOON(data []byte) error
 }
 
+// ValueUnmarshaler is implemented by types that can decode themselves from
+// an already parsed value. Nested values are handed to it directly instead
+// of being re-encoded for UnmarshalTOON.
+type ValueUnmarshaler interface {
+	UnmarshalTOONValue(v interface{}) error
+}
+
+var (
+	unmarshalerType      = reflect.TypeFor[Unmarshaler]()
+	valueUnmarshalerType = reflect.TypeFor[ValueUnmarshaler]()
+
+	unmarshalerTypes sync.Map // map[reflect.Type]bool
+)
+
 func Marshal(v interface{}) ([]byte, error) {
-	var buf []byte
-	writer := &byteWriter{buf: &buf}
-	enc := encoder.NewEncoder(writer, nil)
-	err := enc.Encode(v)
-	if err != nil {
-		return nil, err
-	}
-	return *writer.buf, nil
+	return AppendMarshal(nil, v)
+}
+
+// AppendMarshal appends the TOON encoding of v to dst and returns the
+// extended buffer. Reusing dst across calls avoids allocating the output.
+func AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	return encoder.Append(dst, v, nil)
 }
 
 func Unmarshal(data []byte, v interface{}) error {
+	if u, ok := v.(Unmarshaler); ok {
+		return u.UnmarshalTOON(data)
+	}
+
 	reader := strings.NewReader(string(data))
 	dec := decoder.NewParser(reader)
 
@@ -43,22 +62,27 @@ func Unmarshal(data []byte, v interface{}) error {
 	return convertToValue(result, v)
 }
 
-func MarshalIndent(v interface{}, indent string) ([]byte, error) {
-	opts := &encoder.Options{
-		Indent: indent,
-	}
-	var buf []byte
-	writer := &byteWriter{buf: &buf}
-	enc := encoder.NewEncoder(writer, opts)
-	err := enc.Encode(v)
+// MarshalWithOptions is like Marshal but encodes with opts, for example
+// to pick a delimiter or enable AutoLayout.
+func MarshalWithOptions(v interface{}, opts *encoder.Options) ([]byte, error) {
+	return encoder.Append(nil, v, opts)
+}
+
+// UnmarshalWithOptions is like Unmarshal but decodes with opts, for example
+// to expand folded keys.
+func UnmarshalWithOptions(data []byte, v interface{}, opts decoder.Options) error {
+	result, err := decoder.NewParserWithOptions(bytes.NewReader(data), opts).Parse()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return *writer.buf, nil
+	return convertToValue(result, v)
 }
 
-type byteWriter struct {
-	buf *[]byte
+func MarshalIndent(v interface{}, indent string) ([]byte, error) {
+	opts := &encoder.Options{
+		Indent: indent,
+	}
+	return encoder.Append(nil, v, opts)
 }
 
 // Encode serializa um objeto Go para o formato TOON como string
@@ -142,16 +166,17 @@ func joinComma(arr []string) string {
 	return strings.Join(arr, ",")
 }
 
+// UnmarshalValue stores a value already produced by the decoder, such as a
+// map[string]interface{} from a parsed document, in the value pointed to by v.
+func UnmarshalValue(src interface{}, v interface{}) error {
+	return convertToValue(src, v)
+}
+
 // Decode desserializa uma string TOON para um objeto Go
 func Decode(data string, v interface{}) error {
 	return Unmarshal([]byte(data), v)
 }
 
-func (w *byteWriter) Write(p []byte) (n int, err error) {
-	*w.buf = append(*w.buf, p...)
-	return len(p), nil
-}
-
 type byteReader struct {
 	data []byte
 	pos  int
@@ -187,19 +212,34 @@ func setFieldValue(dst, src reflect.Value) error {
 		return nil
 	}
 
+	if handled, err := callUnmarshaler(dst, src); handled {
+		return err
+	}
+
+	if src.Kind() == reflect.Interface && dst.Kind() != reflect.Interface {
+		src = src.Elem()
+	}
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
 	switch dst.Kind() {
 	case reflect.Interface:
 		dst.Set(src)
 
 	case reflect.Ptr:
-		if src.IsNil() {
-			dst.Set(reflect.Zero(dst.Type()))
-			return nil
+		if src.Kind() == reflect.Ptr {
+			if src.IsNil() {
+				dst.Set(reflect.Zero(dst.Type()))
+				return nil
+			}
+			src = src.Elem()
 		}
 		if dst.IsNil() {
 			dst.Set(reflect.New(dst.Type().Elem()))
 		}
-		return setFieldValue(dst.Elem(), src.Elem())
+		return setFieldValue(dst.Elem(), src)
 
 	case reflect.Struct:
 		if src.Kind() == reflect.Map {
@@ -225,7 +265,7 @@ func setFieldValue(dst, src reflect.Value) error {
 		if src.Kind() == reflect.String {
 			dst.SetString(src.String())
 		} else {
-			dst.SetString(formatValue(src.Interface()))
+			dst.SetString(types.FormatScalar(src.Interface()))
 		}
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -289,31 +329,85 @@ func setFieldValue(dst, src reflect.Value) error {
 	return nil
 }
 
-func setStructFromMap(dst, src reflect.Value) error {
-	dstType := dst.Type()
+// callUnmarshaler hands src to dst's ValueUnmarshaler or Unmarshaler
+// implementation, if it has one.
+func callUnmarshaler(dst, src reflect.Value) (bool, error) {
+	if !dst.CanAddr() || dst.Kind() == reflect.Interface {
+		return false, nil
+	}
 
-	for i := 0; i < dstType.NumField(); i++ {
-		field := dstType.Field(i)
-		dstField := dst.Field(i)
+	ptrType := reflect.PointerTo(dst.Type())
+	if !ptrType.Implements(valueUnmarshalerType) && !ptrType.Implements(unmarshalerType) {
+		return false, nil
+	}
 
-		if !dstField.CanSet() {
-			continue
-		}
+	var value interface{}
+	if src.IsValid() && src.CanInterface() {
+		value = src.Interface()
+	}
+
+	if u, ok := dst.Addr().Interface().(ValueUnmarshaler); ok {
+		return true, u.UnmarshalTOONValue(value)
+	}
 
-		fieldName := field.Tag.Get("toon")
-		if fieldName == "" {
-			fieldName = field.Tag.Get("json")
+	data, err := encoder.Append(nil, value, nil)
+	if err != nil {
+		return true, err
+	}
+	return true, dst.Addr().Interface().(Unmarshaler).UnmarshalTOON(data)
+}
+
+// implementsUnmarshaler reports whether pointers to t, or to a type it
+// points to, implement Unmarshaler or ValueUnmarshaler.
+func implementsUnmarshaler(t reflect.Type) bool {
+	if ok, found := unmarshalerTypes.Load(t); found {
+		return ok.(bool)
+	}
+	ok := false
+	for u := t; ; u = u.Elem() {
+		if p := reflect.PointerTo(u); p.Implements(valueUnmarshalerType) || p.Implements(unmarshalerType) {
+			ok = true
+			break
 		}
-		if fieldName == "" {
-			fieldName = field.Name // Use actual field name, not lowercase
+		if u.Kind() != reflect.Ptr {
+			break
 		}
+	}
+	unmarshalerTypes.Store(t, ok)
+	return ok
+}
+
+// setStructField sets dst, the value of field in a struct, to src with the
+// field's compiled Set func, unless the field needs setFieldValue.
+func setStructField(field *types.Field, dst, src reflect.Value) error {
+	if field.Set == nil || implementsUnmarshaler(field.Type) {
+		return setFieldValue(dst, src)
+	}
+	var value interface{}
+	if src.IsValid() {
+		value = src.Interface()
+	}
+	field.Set(dst, value)
+	return nil
+}
+
+func setStructFromMap(dst, src reflect.Value) error {
+	info := types.CachedStruct(dst.Type())
 
-		srcValue := src.MapIndex(reflect.ValueOf(fieldName))
+	for i := range info.Fields {
+		field := &info.Fields[i]
+
+		srcValue := src.MapIndex(reflect.ValueOf(field.Name))
 		if !srcValue.IsValid() {
 			continue
 		}
 
-		if err := setFieldValue(dstField, srcValue); err != nil {
+		dstField := field.SettableValue(dst)
+		if !dstField.IsValid() {
+			continue
+		}
+
+		if err := setStructField(field, dstField, srcValue); err != nil {
 			return err
 		}
 	}
@@ -362,30 +456,6 @@ func setSliceFromSlice(dst, src reflect.Value) error {
 	return nil
 }
 
-func formatValue(v interface{}) string {
-	if v == nil {
-		return "null"
-	}
-
-	switch val := v.(type) {
-	case string:
-		return val
-	case int, int8, int16, int32, int64:
-		return reflect.ValueOf(val).String()
-	case uint, uint8, uint16, uint32, uint64:
-		return reflect.ValueOf(val).String()
-	case float32, float64:
-		return reflect.ValueOf(val).String()
-	case bool:
-		if val {
-			return "true"
-		}
-		return "false"
-	default:
-		return ""
-	}
-}
-
 type InvalidUnmarshalError struct {
 	Type reflect.Type
 }
@@ -399,3 +469,7 @@ func (e *InvalidUnmarshalError) Error() string {
 	}
 	return "toon: Unmarshal(nil " + e.Type.String() + ")"
 }
+
+// SyntaxError is returned by Unmarshal for invalid TOON, with the line and
+// column where it was found.
+type SyntaxError = decoder.SyntaxError
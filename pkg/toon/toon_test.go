@@ -1,8 +1,13 @@
 package toon
 
 import (
+	"bytes"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/devalexandre/toon-go/pkg/decoder"
+	"github.com/devalexandre/toon-go/pkg/encoder"
 )
 
 func TestMarshalSimpleTypes(t *testing.T) {
@@ -126,3 +131,232 @@ active: true`)
 		t.Errorf("Unmarshal() active = %v, want %v", result["active"], true)
 	}
 }
+
+func TestAutoLayoutRoundTrip(t *testing.T) {
+	type row struct {
+		ID    int     `toon:"id"`
+		Label string  `toon:"label"`
+		Score float64 `toon:"score"`
+	}
+	type doc struct {
+		Settings struct {
+			Display struct {
+				Theme string `toon:"theme"`
+			} `toon:"display"`
+		} `toon:"settings"`
+		Rows   []row             `toon:"rows"`
+		Tags   []string          `toon:"tags"`
+		Dotted map[string]string `toon:"dotted"`
+	}
+
+	var in doc
+	in.Settings.Display.Theme = "dark"
+	in.Rows = []row{{1, "a|b", 0.5}, {2, "c,d", 1.5}}
+	in.Tags = []string{"x", "y"}
+	in.Dotted = map[string]string{"k.v": "literal", "other": "z"}
+
+	opts := encoder.DefaultOptions()
+	opts.AutoLayout = true
+	data, err := MarshalWithOptions(in, &opts)
+	if err != nil {
+		t.Fatalf("MarshalWithOptions() error = %v", err)
+	}
+	if !strings.Contains(string(data), "settings.display.theme:") {
+		t.Errorf("expected folded keys in:\n%s", data)
+	}
+
+	var out doc
+	if err := UnmarshalWithOptions(data, &out, decoder.Options{ExpandPaths: true}); err != nil {
+		t.Fatalf("UnmarshalWithOptions() error = %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v\n%s", out, in, data)
+	}
+}
+
+func TestUnmarshalLenient(t *testing.T) {
+	type user struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	var out struct {
+		Users []user `toon:"users"`
+	}
+
+	response := []byte("Sure! Here are the users:\n```toon\nusers[3]{id,name}:\n  1,Smith, John\n  2,Ada\n```")
+	if err := Unmarshal(response, &out); err == nil {
+		t.Fatal("expected strict Unmarshal to fail")
+	}
+	if err := UnmarshalWithOptions(response, &out, decoder.Options{Lenient: true}); err != nil {
+		t.Fatalf("UnmarshalWithOptions() error = %v", err)
+	}
+	want := []user{{1, "Smith, John"}, {2, "Ada"}}
+	if !reflect.DeepEqual(out.Users, want) {
+		t.Errorf("got %+v, want %+v", out.Users, want)
+	}
+
+	_, fixes, err := Repair(response)
+	if err != nil || len(fixes) != 4 {
+		t.Errorf("Repair() = %v, %v; want 4 fixes", fixes, err)
+	}
+}
+
+func TestExtractBlocksUnmarshalValue(t *testing.T) {
+	type user struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	response := "Found them:\n```toon\nusers[2]{id,name}:\n  1,Ada\n  2,Linus\n```\nAnything else?"
+
+	blocks := ExtractBlocks(response)
+	if len(blocks) != 1 {
+		t.Fatalf("ExtractBlocks() = %+v, want 1 block", blocks)
+	}
+	var out struct {
+		Users []user `toon:"users"`
+	}
+	if err := UnmarshalValue(blocks[0].Value, &out); err != nil {
+		t.Fatalf("UnmarshalValue() error = %v", err)
+	}
+	if want := []user{{1, "Ada"}, {2, "Linus"}}; !reflect.DeepEqual(out.Users, want) {
+		t.Errorf("got %+v, want %+v", out.Users, want)
+	}
+}
+
+func TestKeyDictionaryRoundTrip(t *testing.T) {
+	type metric struct {
+		Duration int    `toon:"requestDurationMilliseconds"`
+		Bytes    int    `toon:"responseSizeBytes"`
+		Route    string `toon:"route"`
+	}
+	type doc struct {
+		Metrics map[string]metric `toon:"metrics"`
+		Tags    []string          `toon:"tags"`
+	}
+	in := doc{Metrics: map[string]metric{}, Tags: []string{"a", "b"}}
+	for _, name := range []string{"api", "auth", "billing", "search"} {
+		in.Metrics[name] = metric{Duration: len(name), Bytes: 100, Route: "/" + name}
+	}
+
+	opts := encoder.DefaultOptions()
+	opts.KeyDictionary = true
+	data, err := MarshalWithOptions(in, &opts)
+	if err != nil {
+		t.Fatalf("MarshalWithOptions() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), "%keys\n") {
+		t.Fatalf("expected a key dictionary in:\n%s", data)
+	}
+
+	var out doc
+	if err := Unmarshal(data, &out); err == nil {
+		t.Error("expected Unmarshal without KeyDictionary to fail")
+	}
+	if err := UnmarshalWithOptions(data, &out, decoder.Options{KeyDictionary: true}); err != nil {
+		t.Fatalf("UnmarshalWithOptions() error = %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+}
+
+func TestDottedColumnsRoundTrip(t *testing.T) {
+	type customer struct {
+		ID   int    `toon:"id"`
+		Name string `toon:"name"`
+	}
+	type order struct {
+		ID       int      `toon:"id"`
+		Customer customer `toon:"customer"`
+		Total    float64  `toon:"total"`
+	}
+	in := struct {
+		Orders []order `toon:"orders"`
+	}{Orders: []order{{1, customer{7, "Ada"}, 9.5}, {2, customer{8, "Linus"}, 12}}}
+
+	for _, auto := range []bool{false, true} {
+		opts := encoder.DefaultOptions()
+		opts.DottedColumns = true
+		opts.AutoLayout = auto
+		data, err := MarshalWithOptions(in, &opts)
+		if err != nil {
+			t.Fatalf("MarshalWithOptions() error = %v", err)
+		}
+		if !strings.Contains(string(data), "customer.name") {
+			t.Errorf("expected dotted columns in:\n%s", data)
+		}
+
+		out := in
+		out.Orders = nil
+		if err := UnmarshalWithOptions(data, &out, decoder.Options{ExpandPaths: true}); err != nil {
+			t.Fatalf("UnmarshalWithOptions() error = %v\n%s", err, data)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("round trip mismatch:\n got %+v\nwant %+v\n%s", out, in, data)
+		}
+	}
+}
+
+func TestMarkAbsentRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"id": int64(1), "name": "Ada", "role": "admin"},
+			map[string]interface{}{"id": int64(2), "name": "Linus"},
+			map[string]interface{}{"id": int64(3), "name": "Grace", "role": ""},
+		},
+	}
+
+	for _, auto := range []bool{false, true} {
+		opts := encoder.DefaultOptions()
+		opts.TabularThreshold = 0.8
+		opts.MarkAbsent = true
+		opts.AutoLayout = auto
+		data, err := MarshalWithOptions(in, &opts)
+		if err != nil {
+			t.Fatalf("MarshalWithOptions() error = %v", err)
+		}
+		if !strings.Contains(string(data), "{id,name,role}:") {
+			t.Errorf("expected a tabular array in:\n%s", data)
+		}
+
+		var out map[string]interface{}
+		if err := UnmarshalWithOptions(data, &out, decoder.Options{DropAbsent: true}); err != nil {
+			t.Fatalf("UnmarshalWithOptions() error = %v\n%s", err, data)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("round trip mismatch:\n got %v\nwant %v\n%s", out, in, data)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"name":  "Ada",
+		"score": 9.5,
+		"tags":  []interface{}{"a", "b"},
+		"users": []interface{}{
+			map[string]interface{}{"id": int64(1), "name": "x"},
+			map[string]interface{}{"id": int64(2), "name": "y"},
+		},
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	formatted, err := Format(data)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if !bytes.Equal(formatted, data) {
+		t.Errorf("expected Marshal output to be canonical:\n%s\ngot:\n%s", data, formatted)
+	}
+
+	var out map[string]interface{}
+	if err := Unmarshal(append([]byte("# fixture\n"), formatted...), &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %v\nwant %v", out, in)
+	}
+}
@@ -0,0 +1,27 @@
+package toon
+
+import (
+	"github.com/devalexandre/toon-go/pkg/decoder"
+)
+
+// Schema constrains the values of a document checked by Validate. See
+// decoder.Schema for the JSON Schema keywords it supports.
+type Schema = decoder.Schema
+
+// SchemaError reports a value that does not satisfy a Schema, with the
+// line and column where it was found and its path in the document.
+type SchemaError = decoder.SchemaError
+
+// ParseSchema reads a schema written as JSON or TOON.
+func ParseSchema(data []byte) (*Schema, error) {
+	return decoder.ParseSchema(data)
+}
+
+// Validate checks a document strictly and returns every *SyntaxError
+// found, ordered by line, rather than stopping at the first as Unmarshal
+// does. When schema is not nil and the document is well formed, the values
+// it rejects are returned as *SchemaError. The result is nil for a valid
+// document.
+func Validate(data []byte, schema *Schema) []error {
+	return decoder.Validate(data, schema)
+}
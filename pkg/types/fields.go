@@ -0,0 +1,464 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldKind classifies a struct field by how it is laid out in TOON.
+type FieldKind int
+
+const (
+	// KindPrimitive fields hold strings, numbers, booleans or pointers to them.
+	KindPrimitive FieldKind = iota
+	// KindArray fields hold slices or arrays.
+	KindArray
+	// KindObject fields hold maps or structs.
+	KindObject
+	// KindDynamic fields hold interfaces whose layout is only known per value.
+	KindDynamic
+)
+
+// Field is the precompiled plan for one encoded struct field.
+type Field struct {
+	// Name is the TOON key, taken from the toon tag, the json tag or the Go name.
+	Name string
+	// Index is the reflect index path, which is longer than one for fields
+	// promoted from embedded structs.
+	Index []int
+	Type  reflect.Type
+	Kind  FieldKind
+	// Tagged reports whether Name came from a struct tag.
+	Tagged    bool
+	OmitEmpty bool
+	// Options holds every tag option after the name, including ones this
+	// package does not interpret, such as "priority=low".
+	Options []string
+
+	// Append and Set are compiled for Type when the plan is built, and are
+	// nil unless Kind is KindPrimitive. They ignore Marshaler and
+	// Unmarshaler implementations, which callers check for first.
+	//
+	// Append appends the value returned by Value as a TOON primitive,
+	// writing null for nil pointers and the zero Value.
+	Append func(dst []byte, v reflect.Value) []byte
+	// Set assigns a decoded primitive to the value returned by
+	// SettableValue, converting between numeric kinds and formatting
+	// scalars for string fields as setting a value of the same type does
+	// in the toon package. Values of other kinds leave v unchanged.
+	Set func(v reflect.Value, src interface{})
+}
+
+// Option returns the value of a "key=value" tag option and whether it is set.
+// Flag options without a value return an empty string.
+func (f *Field) Option(key string) (string, bool) {
+	for _, opt := range f.Options {
+		name, value, _ := strings.Cut(opt, "=")
+		if name == key {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Value returns the field of the struct v, or the zero Value when the path
+// runs through a nil embedded pointer.
+func (f *Field) Value(v reflect.Value) reflect.Value {
+	for i, index := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return v
+}
+
+// SettableValue returns the field of the struct v for assignment,
+// allocating nil embedded pointers along the way. It returns the zero Value
+// when a nil embedded pointer cannot be set because it is unexported.
+func (f *Field) SettableValue(v reflect.Value) reflect.Value {
+	for i, index := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return v
+}
+
+// StructInfo is the cached field plan for a struct type.
+type StructInfo struct {
+	Type   reflect.Type
+	Fields []Field
+	// Primitive reports whether no field is statically an array or object,
+	// which makes slices of the type candidates for tabular encoding.
+	// KindDynamic fields still have to be checked per value.
+	Primitive bool
+
+	byName map[string]int
+}
+
+// FieldByName returns the field encoded under name.
+func (s *StructInfo) FieldByName(name string) (*Field, bool) {
+	i, ok := s.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return &s.Fields[i], true
+}
+
+// Names returns the encoded field names in declaration order.
+func (s *StructInfo) Names() []string {
+	names := make([]string, len(s.Fields))
+	for i := range s.Fields {
+		names[i] = s.Fields[i].Name
+	}
+	return names
+}
+
+var structCache sync.Map // map[reflect.Type]*StructInfo
+
+// CachedStruct returns the field plan for the struct type t, computing it on
+// first use. It is safe for concurrent use.
+func CachedStruct(t reflect.Type) *StructInfo {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*StructInfo)
+	}
+	info, _ := structCache.LoadOrStore(t, buildStructInfo(t))
+	return info.(*StructInfo)
+}
+
+// ParseTag splits a toon or json struct tag into its name and options.
+func ParseTag(tag string) (string, []string) {
+	name, rest, found := strings.Cut(tag, ",")
+	if !found {
+		return name, nil
+	}
+	return name, strings.Split(rest, ",")
+}
+
+func fieldTag(sf reflect.StructField) (string, bool) {
+	if tag, ok := sf.Tag.Lookup("toon"); ok {
+		return tag, true
+	}
+	return sf.Tag.Lookup("json")
+}
+
+// buildStructInfo walks t breadth first like encoding/json: fields of
+// untagged embedded structs are promoted, shallower fields hide deeper ones
+// with the same name, and ambiguous names at the same depth are dropped
+// unless exactly one of them is tagged.
+func buildStructInfo(t reflect.Type) *StructInfo {
+	type candidate struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []Field
+	current := []candidate{}
+	next := []candidate{{typ: t}}
+	visited := map[reflect.Type]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+
+		for _, c := range current {
+			if visited[c.typ] {
+				continue
+			}
+			visited[c.typ] = true
+
+			for i := 0; i < c.typ.NumField(); i++ {
+				sf := c.typ.Field(i)
+
+				tag, hasTag := fieldTag(sf)
+				name, opts := ParseTag(tag)
+				if tag == "-" {
+					continue
+				}
+
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+					if ft.Kind() == reflect.Struct && name == "" {
+						index := append(append([]int(nil), c.index...), i)
+						next = append(next, candidate{typ: ft, index: index})
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				field := Field{
+					Name:    name,
+					Index:   append(append([]int(nil), c.index...), i),
+					Type:    sf.Type,
+					Kind:    kindOf(sf.Type),
+					Tagged:  hasTag && name != "",
+					Options: opts,
+				}
+				if field.Name == "" {
+					field.Name = sf.Name
+				}
+				if field.Kind == KindPrimitive {
+					field.Append = compileAppend(sf.Type)
+					field.Set = compileSet(sf.Type)
+				}
+				for _, opt := range opts {
+					if opt == "omitempty" {
+						field.OmitEmpty = true
+					}
+				}
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	fields = dominantFields(fields)
+
+	info := &StructInfo{
+		Type:      t,
+		Fields:    fields,
+		Primitive: len(fields) > 0,
+		byName:    make(map[string]int, len(fields)),
+	}
+	for i := range fields {
+		info.byName[fields[i].Name] = i
+		if fields[i].Kind == KindArray || fields[i].Kind == KindObject {
+			info.Primitive = false
+		}
+	}
+	return info
+}
+
+// dominantFields resolves name conflicts and restores declaration order.
+func dominantFields(fields []Field) []Field {
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].Name != fields[j].Name {
+			return fields[i].Name < fields[j].Name
+		}
+		if len(fields[i].Index) != len(fields[j].Index) {
+			return len(fields[i].Index) < len(fields[j].Index)
+		}
+		return fields[i].Tagged && !fields[j].Tagged
+	})
+
+	out := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].Name == fields[i].Name {
+			j++
+		}
+		group := fields[i:j]
+		if len(group) == 1 || len(group[1].Index) > len(group[0].Index) || group[0].Tagged && !group[1].Tagged {
+			out = append(out, group[0])
+		}
+		i = j
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Index, out[j].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return out
+}
+
+func kindOf(t reflect.Type) FieldKind {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return KindArray
+	case reflect.Map, reflect.Struct:
+		return KindObject
+	case reflect.Interface:
+		return KindDynamic
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return KindPrimitive
+	default:
+		return KindDynamic
+	}
+}
+
+var numberType = reflect.TypeFor[json.Number]()
+
+// compileAppend returns the Append func of a primitive field of type t.
+func compileAppend(t reflect.Type) func([]byte, reflect.Value) []byte {
+	appendValue := appendFunc(t)
+	return func(dst []byte, v reflect.Value) []byte {
+		if !v.IsValid() {
+			return append(dst, "null"...)
+		}
+		return appendValue(dst, v)
+	}
+}
+
+func appendFunc(t reflect.Type) func([]byte, reflect.Value) []byte {
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := appendFunc(t.Elem())
+		return func(dst []byte, v reflect.Value) []byte {
+			if v.IsNil() {
+				return append(dst, "null"...)
+			}
+			return elem(dst, v.Elem())
+		}
+	case reflect.Bool:
+		return func(dst []byte, v reflect.Value) []byte {
+			return strconv.AppendBool(dst, v.Bool())
+		}
+	case reflect.String:
+		if t == numberType {
+			return func(dst []byte, v reflect.Value) []byte {
+				// A json.Number keeps the exact text of a decoded number.
+				if s := v.String(); IsNumber(s) {
+					return append(dst, s...)
+				}
+				return AppendString(dst, v.String())
+			}
+		}
+		return func(dst []byte, v reflect.Value) []byte {
+			return AppendString(dst, v.String())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(dst []byte, v reflect.Value) []byte {
+			return strconv.AppendInt(dst, v.Int(), 10)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(dst []byte, v reflect.Value) []byte {
+			return strconv.AppendUint(dst, v.Uint(), 10)
+		}
+	case reflect.Float32:
+		return func(dst []byte, v reflect.Value) []byte {
+			return AppendFloat(dst, v.Float(), 32)
+		}
+	default:
+		return func(dst []byte, v reflect.Value) []byte {
+			return AppendFloat(dst, v.Float(), 64)
+		}
+	}
+}
+
+// compileSet returns the Set func of a primitive field of type t.
+func compileSet(t reflect.Type) func(reflect.Value, interface{}) {
+	set := setFunc(t)
+	return func(v reflect.Value, src interface{}) {
+		if !v.CanSet() {
+			return
+		}
+		if src == nil {
+			v.SetZero()
+			return
+		}
+		set(v, reflect.ValueOf(src))
+	}
+}
+
+func setFunc(t reflect.Type) func(reflect.Value, reflect.Value) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := setFunc(t.Elem())
+		return func(v, src reflect.Value) {
+			if src.Kind() == reflect.Ptr {
+				if src.IsNil() {
+					v.SetZero()
+					return
+				}
+				src = src.Elem()
+			}
+			if v.IsNil() {
+				v.Set(reflect.New(t.Elem()))
+			}
+			elem(v.Elem(), src)
+		}
+	case reflect.Bool:
+		return func(v, src reflect.Value) {
+			if src.Kind() == reflect.Bool {
+				v.SetBool(src.Bool())
+			}
+		}
+	case reflect.String:
+		return func(v, src reflect.Value) {
+			if src.Kind() == reflect.String {
+				v.SetString(src.String())
+			} else {
+				v.SetString(FormatScalar(src.Interface()))
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v, src reflect.Value) {
+			if src.CanInt() {
+				v.SetInt(src.Int())
+			} else if src.Kind() == reflect.Float64 {
+				if f := src.Float(); float64(int64(f)) == f {
+					v.SetInt(int64(f))
+				}
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(v, src reflect.Value) {
+			if src.CanUint() {
+				v.SetUint(src.Uint())
+			} else if src.Kind() == reflect.Float64 {
+				v.SetUint(uint64(src.Float()))
+			}
+		}
+	default:
+		return func(v, src reflect.Value) {
+			if src.CanFloat() {
+				v.SetFloat(src.Float())
+			} else if src.CanInt() {
+				v.SetFloat(float64(src.Int()))
+			}
+		}
+	}
+}
+
+// IsEmptyValue reports whether v is the zero value for omitempty purposes,
+// using the same rules as encoding/json.
+func IsEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Invalid:
+		return true
+	}
+	return false
+}
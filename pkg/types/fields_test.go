@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type Base struct {
+	ID      int    `toon:"id"`
+	Created string `toon:"created"`
+}
+
+type Audit struct {
+	By   string `toon:"by"`
+	Name string `toon:"name"`
+}
+
+type Record struct {
+	Base
+	*Audit
+	Name    string `toon:"name,omitempty"`
+	Note    string `json:"note"`
+	Secret  string `toon:"-"`
+	Score   int    `toon:"score,priority=low"`
+	private int
+}
+
+func TestCachedStructFields(t *testing.T) {
+	info := CachedStruct(reflect.TypeOf(Record{}))
+
+	names := info.Names()
+	want := []string{"id", "created", "by", "name", "note", "score"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Names() = %v, want %v", names, want)
+	}
+
+	id, ok := info.FieldByName("id")
+	if !ok || !reflect.DeepEqual(id.Index, []int{0, 0}) {
+		t.Errorf("id field = %+v, want promoted from Base", id)
+	}
+
+	name, _ := info.FieldByName("name")
+	if !name.OmitEmpty || len(name.Index) != 1 {
+		t.Errorf("name field = %+v, want Record.Name hiding Audit.Name", name)
+	}
+
+	score, _ := info.FieldByName("score")
+	if priority, ok := score.Option("priority"); !ok || priority != "low" {
+		t.Errorf("score priority option = %q, %v", priority, ok)
+	}
+
+	if !info.Primitive {
+		t.Error("Record should be primitive-only")
+	}
+}
+
+func TestFieldValueThroughEmbeddedPointer(t *testing.T) {
+	info := CachedStruct(reflect.TypeOf(Record{}))
+	by, _ := info.FieldByName("by")
+
+	var record Record
+	v := reflect.ValueOf(&record).Elem()
+	if by.Value(v).IsValid() {
+		t.Error("Value() through nil embedded pointer should be invalid")
+	}
+
+	by.SettableValue(v).SetString("ops")
+	if record.Audit == nil || record.By != "ops" {
+		t.Errorf("SettableValue() did not allocate embedded pointer: %+v", record)
+	}
+}
+
+func TestFieldFuncs(t *testing.T) {
+	type row struct {
+		N     int8
+		U     *uint
+		F     float32
+		S     string
+		Zip   string
+		B     bool
+		Num   json.Number
+		Tags  []string
+		Value interface{}
+	}
+
+	info := CachedStruct(reflect.TypeOf(row{}))
+	var r row
+	v := reflect.ValueOf(&r).Elem()
+	for name, src := range map[string]interface{}{
+		"N": int64(-3), "U": float64(7), "F": int64(2), "S": "a\"b", "Zip": int64(2139), "B": true, "Num": "1.50",
+	} {
+		field, _ := info.FieldByName(name)
+		field.Set(field.SettableValue(v), src)
+	}
+
+	var out []byte
+	for i := range info.Fields {
+		field := &info.Fields[i]
+		if field.Append == nil {
+			if field.Kind == KindPrimitive {
+				t.Errorf("%s: expected an Append func", field.Name)
+			}
+			continue
+		}
+		out = field.Append(out, field.Value(v))
+		out = append(out, ' ')
+	}
+	if want := `-3 7 2 "a\"b" "2139" true 1.50 `; string(out) != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	u, _ := info.FieldByName("U")
+	u.Set(u.SettableValue(v), nil)
+	if r.U != nil || string(u.Append(nil, u.Value(v))) != "null" {
+		t.Errorf("Expected nil to clear the pointer and append null, got %v", r.U)
+	}
+}
+
+func TestCachedStructConcurrent(t *testing.T) {
+	type local struct {
+		A int
+		B []string
+	}
+
+	var wg sync.WaitGroup
+	infos := make([]*StructInfo, 8)
+	for i := range infos {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			infos[i] = CachedStruct(reflect.TypeOf(local{}))
+		}(i)
+	}
+	wg.Wait()
+
+	for _, info := range infos[1:] {
+		if info != infos[0] {
+			t.Fatal("CachedStruct returned different plans for the same type")
+		}
+	}
+	if infos[0].Primitive {
+		t.Error("struct with slice field should not be primitive-only")
+	}
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Member is one key and value of an Object.
+type Member struct {
+	Key   string
+	Value interface{}
+}
+
+// Object is an object that keeps its keys in the order they were added,
+// unlike a map, which the encoder writes with sorted keys. Values may be any
+// value the encoder accepts, including nested Objects.
+type Object []Member
+
+// Get returns the value stored under key.
+func (o Object) Get(key string) (interface{}, bool) {
+	for _, m := range o {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value stored under key, or appends it when key is new.
+func (o *Object) Set(key string, value interface{}) {
+	for i := range *o {
+		if (*o)[i].Key == key {
+			(*o)[i].Value = value
+			return
+		}
+	}
+	*o = append(*o, Member{Key: key, Value: value})
+}
+
+// Keys returns the keys in order.
+func (o Object) Keys() []string {
+	keys := make([]string, len(o))
+	for i, m := range o {
+		keys[i] = m.Key
+	}
+	return keys
+}
+
+// MarshalJSON writes the object as a JSON object with its keys in order.
+// Strings are not HTML-escaped, so that an Encoder with SetEscapeHTML(false)
+// writes them as they are; json.Marshal escapes the result itself.
+func (o Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Encode ends each value with a newline, which is dropped.
+		if err := enc.Encode(m.Key); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(m.Value); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
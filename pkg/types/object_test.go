@@ -0,0 +1,30 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestObjectMarshalJSON(t *testing.T) {
+	obj := Object{
+		{Key: "name", Value: "Ada"},
+		{Key: "id", Value: int64(7)},
+		{Key: "tags", Value: []interface{}{Object{{Key: "z", Value: true}, {Key: "a", Value: nil}}}},
+	}
+
+	got, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `{"name":"Ada","id":7,"tags":[{"z":true,"a":null}]}`; string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	if got, _ := json.Marshal(Object{}); string(got) != "{}" {
+		t.Errorf("Expected an empty object, got %s", got)
+	}
+
+	if got, _ := (Object{{Key: "a<b", Value: "<i>"}}).MarshalJSON(); string(got) != `{"a<b":"<i>"}` {
+		t.Errorf("Expected strings without HTML escaping, got %s", got)
+	}
+}
@@ -0,0 +1,120 @@
+package types
+
+import (
+	"math"
+	"strconv"
+)
+
+// The functions in this file write scalars the way the encoder does. They
+// live here so that the decoder can write canonical text without
+// depending on the encoder.
+
+// AppendFloat formats like encoding/json: plain decimal notation except for
+// very large or very small magnitudes. NaN and infinities become null.
+func AppendFloat(dst []byte, f float64, bits int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return append(dst, "null"...)
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	return strconv.AppendFloat(dst, f, format, -1, bits)
+}
+
+// FormatScalar returns the text of a decoded scalar: strings as they are,
+// numbers in decimal, booleans as true or false and nil as null. Other
+// values give an empty string.
+func FormatScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return string(AppendFloat(nil, float64(v), 32))
+	case float64:
+		return string(AppendFloat(nil, v, 64))
+	default:
+		return ""
+	}
+}
+
+// AppendString appends s as a quoted string. Strings are always quoted so the
+// decoder never mistakes them for numbers, booleans or null.
+func AppendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		var esc byte
+		switch s[i] {
+		case '"':
+			esc = '"'
+		case '\\':
+			esc = '\\'
+		case '\n':
+			esc = 'n'
+		case '\r':
+			esc = 'r'
+		case '\t':
+			esc = 't'
+		default:
+			continue
+		}
+		dst = append(dst, s[start:i]...)
+		dst = append(dst, '\\', esc)
+		start = i + 1
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// AppendKey appends an object key, quoting it when it contains characters
+// that would be ambiguous in a key position.
+func AppendKey(dst []byte, key string) []byte {
+	if IsBareKey(key) {
+		return append(dst, key...)
+	}
+	return AppendString(dst, key)
+}
+
+// IsBareKey reports whether key can be written without quotes.
+func IsBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '.':
+		case c == '-' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
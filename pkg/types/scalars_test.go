@@ -0,0 +1,28 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAppendScalars(t *testing.T) {
+	tests := []struct {
+		got  []byte
+		want string
+	}{
+		{AppendString(nil, "a \"b\"\n\\"), `"a \"b\"\n\\"`},
+		{AppendKey(nil, "user_id.v2"), `user_id.v2`},
+		{AppendKey(nil, "full name"), `"full name"`},
+		{AppendKey(nil, "-x"), `"-x"`},
+		{AppendKey(nil, ""), `""`},
+		{AppendFloat(nil, 1.5, 64), `1.5`},
+		{AppendFloat(nil, 1e21, 64), `1e+21`},
+		{AppendFloat(nil, 0.1, 32), `0.1`},
+		{AppendFloat(nil, math.Inf(1), 64), `null`},
+	}
+	for _, tt := range tests {
+		if string(tt.got) != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, tt.got)
+		}
+	}
+}
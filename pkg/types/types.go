@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 )
@@ -57,13 +58,54 @@ func ParsePrimitive(value string) TOONValue {
 
 func IsPrimitiveType(value TOONValue) bool {
 	switch value.(type) {
-	case string, int64, float64, bool, nil:
+	case string, int64, float64, bool, nil, json.Number:
 		return true
 	default:
 		return false
 	}
 }
 
+// IsNumber reports whether s is a number in JSON syntax.
+func IsNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	digits := func() int {
+		n := 0
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		return n
+	}
+	n := digits()
+	if n == 0 || n > 1 && s[0] == '0' {
+		return false
+	}
+	s = s[n:]
+	if strings.HasPrefix(s, ".") {
+		s = s[1:]
+		if n = digits(); n == 0 {
+			return false
+		}
+		s = s[n:]
+	}
+	if len(s) > 0 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if n = digits(); n == 0 {
+			return false
+		}
+		s = s[n:]
+	}
+	return s == ""
+}
+
+// ShouldUseTabularFormat reports whether every object in slice has the same
+// number of keys and only primitive values. It predates the encoder's
+// tabular rules and ignores their thresholds.
+//
+// Deprecated: the encoder decides with Options.TabularThreshold and
+// Options.ForceTabular; this function is not consulted and may disagree.
 func ShouldUseTabularFormat(slice []TOONValue) bool {
 	if len(slice) < 2 {
 		return false
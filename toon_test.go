@@ -127,3 +127,37 @@ address:
 		t.Errorf("Expected %+v, got %+v", expected, result)
 	}
 }
+
+func TestEmbeddedStructRoundTrip(t *testing.T) {
+	type Base struct {
+		ID int `toon:"id"`
+	}
+	type Item struct {
+		Base
+		Name  string `toon:"name"`
+		Token string `toon:"-"`
+		Note  string `toon:"note,omitempty"`
+	}
+
+	item := Item{Base: Base{ID: 7}, Name: "widget", Token: "secret"}
+
+	data, err := Marshal(item)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	expected := "id: 7\nname: \"widget\"\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+
+	var unmarshaled Item
+	if err := Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+
+	item.Token = ""
+	if !reflect.DeepEqual(item, unmarshaled) {
+		t.Errorf("Expected %+v, got %+v", item, unmarshaled)
+	}
+}